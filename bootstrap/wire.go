@@ -1,16 +1,268 @@
 package bootstrap
 
 import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sse/internal/adapters/history"
 	"sse/internal/adapters/hub"
+	"sse/internal/adapters/tenant"
+	"sse/internal/adapters/webhook"
+	"sse/internal/app/publish"
+	"sse/internal/app/schedule"
 	"sse/internal/ports"
+	"sse/pkg/config"
+	"sse/pkg/health"
+	"sse/pkg/heartbeat"
+	"sse/pkg/logger"
+	"sse/pkg/topic"
+	"time"
 )
 
 type Container struct {
-	ShardedHub ports.Hub
+	// 按租户隔离的 Hub 注册表
+	Tenants ports.Tenants
+	// 租户解析
+	Resolver *tenant.Resolver
+	// 结构化日志
+	Logger *slog.Logger
+	// 依赖组件状态，供 /healthz 与 /readyz 使用
+	Health *health.Health
+	// 心跳
+	Heartbeat *heartbeat.Heartbeat
+	// 需要持久化的主题
+	PersistTopics *topic.Filter
+	// topic 消息历史，未启用持久化时为 nil
+	History ports.History
+	// persistent 用户消息的收件箱，未启用持久化时为 nil
+	Inbox ports.Inbox
+	// 离线消息的 webhook 回调，未启用时为 nil；需要 Start 与 Close
+	Webhooks *webhook.Dispatcher
+	// 定时发布调度器，未启用时为 nil；需要 Start 与 Close
+	Scheduler *schedule.Scheduler
+	// 发布幂等键记录，未启用时为 nil
+	Idempotency ports.Idempotency
 }
 
-func NewContainer() *Container {
-	return &Container{
-		ShardedHub: hub.NewShardedHub(),
+// NewContainer 按配置创建各组件，配置需要的存储不可用时返回错误
+func NewContainer() (*Container, error) {
+	cfg := config.Config
+	log := logger.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+
+	quotas := make(map[string]ports.Quota, len(cfg.Tenant.Tenants))
+	for _, t := range cfg.Tenant.Tenants {
+		quotas[t.Name] = toQuota(t.Quota)
+	}
+	persistTopics := topic.NewFilter(cfg.Persistence.Topics.Include, cfg.Persistence.Topics.Exclude)
+	store := newHistory(log)
+	inbox := newInbox()
+	hooks := newWebhooks(log)
+
+	settings := &hub.Settings{
+		SampleEvery:    cfg.Log.SampleEvery,
+		AckTimeout:     time.Duration(cfg.Hub.Ack.TimeoutSec) * time.Second,
+		AckMaxAttempts: cfg.Hub.Ack.MaxAttempts,
+		AckWindow:      cfg.Hub.Ack.Window,
+		StatusMax:      cfg.Hub.Ack.StatusMax,
+		SnapshotMax:    cfg.Hub.SnapshotMax,
+		Lanes: hub.Lanes{
+			High:   toLane(cfg.Hub.Lanes.High),
+			Normal: toLane(cfg.Hub.Lanes.Normal),
+			Low:    toLane(cfg.Hub.Lanes.Low),
+		},
+		PresenceTopics:   topic.NewFilter(cfg.Hub.Presence.Topics, nil),
+		PresenceDebounce: time.Duration(cfg.Hub.Presence.DebounceMs) * time.Millisecond,
+	}
+	settings.DropSlowClient.Store(cfg.Hub.DropSlowClient)
+	settings.SetConflate(toConflateRules(cfg.Hub.Conflate))
+	newHub := func(name string, quota ports.Quota) ports.Hub {
+		h := ports.Hub(hub.NewShardedHub(quota, settings, log.With("tenant", name)))
+		if hooks != nil {
+			h = webhook.Offline(h, name, hooks)
+		}
+		if store != nil {
+			h = history.Recording(h, name, store, persistTopics)
+		}
+		if inbox != nil {
+			h = history.Inboxed(h, name, inbox)
+		}
+		return h
+	}
+	registry := tenant.NewRegistry(newHub, toQuota(cfg.Tenant.Quota), quotas, cfg.Tenant.Strict, cfg.Tenant.MaxUndeclared, cfg.Publish.RateLimitQps)
+
+	c := &Container{
+		Tenants: registry,
+		Resolver: &tenant.Resolver{
+			Mode:   cfg.Tenant.Resolver,
+			Header: cfg.Tenant.Header,
+			Claim:  cfg.Tenant.TokenClaim,
+			Secret: []byte(cfg.Tenant.TokenSecret),
+		},
+		Logger:        log,
+		Health:        health.New(),
+		Heartbeat:     heartbeat.NewHeartbeat(cfg.Sse.HeartbeatSec, registry),
+		PersistTopics: persistTopics,
+		Webhooks:      hooks,
+	}
+	if store != nil {
+		c.History = store
+	}
+	if inbox != nil {
+		c.Inbox = inbox
+	}
+	c.Scheduler = newScheduler(registry, c.Inbox, log)
+	idem, err := newIdempotency(log)
+	if err != nil {
+		return nil, err
+	}
+	c.Idempotency = idem
+
+	// 热更新：配置文件变化后把可热更新的值推送给各组件
+	config.Subscribe(func() {
+		cur := config.Current()
+		logger.Level.Set(logger.ParseLevel(cur.Log.Level))
+		c.Heartbeat.SetInterval(cur.Sse.HeartbeatSec)
+		registry.SetPublishQps(cur.Publish.RateLimitQps)
+		settings.DropSlowClient.Store(cur.Hub.DropSlowClient)
+		settings.SetConflate(toConflateRules(cur.Hub.Conflate))
+		settings.PresenceTopics.Set(cur.Hub.Presence.Topics, nil)
+		c.PersistTopics.Set(cur.Persistence.Topics.Include, cur.Persistence.Topics.Exclude)
+	})
+	return c, nil
+}
+
+// newHistory 按 persistence 配置创建消息历史，未启用时返回 nil
+func newHistory(log *slog.Logger) *history.Memory {
+	cfg := config.Config
+	if !cfg.Persistence.Enabled {
+		return nil
+	}
+	if cfg.Persistence.Kind != "memory" {
+		log.Warn("当前构建未包含数据库驱动，消息历史保存在内存中", "kind", cfg.Persistence.Kind)
+	}
+	retention := time.Duration(cfg.Persistence.Retention.Days) * 24 * time.Hour
+	return history.NewMemory(cfg.Publish.DefaultMaxlen, retention)
+}
+
+// newInbox 与消息历史使用同一持久化配置，未启用时返回 nil
+func newInbox() *history.MemoryInbox {
+	cfg := config.Config.Persistence
+	if !cfg.Enabled {
+		return nil
+	}
+	return history.NewMemoryInbox(cfg.Inbox.MaxItems, time.Duration(cfg.Inbox.MaxAgeHours)*time.Hour)
+}
+
+// newIdempotency 按 publish.idempotency 配置创建幂等键记录，未启用时返回 nil；
+// 启用了 mysql / postgres 持久化且构建时注册了对应的 database/sql 驱动时保存在 persistence.dsn 指向的数据库中，
+// 多实例共享（不受 maxKeys 限制）；未注册驱动时与消息历史一样保存在内存中。数据库不可用时返回错误
+func newIdempotency(log *slog.Logger) (ports.Idempotency, error) {
+	cfg := config.Config
+	if !cfg.Publish.Idempotency.Enabled {
+		return nil, nil
+	}
+	window := time.Duration(cfg.Publish.Idempotency.WindowSec) * time.Second
+	memory := func() ports.Idempotency {
+		return history.NewMemoryIdempotency(window, cfg.Publish.Idempotency.MaxKeys)
+	}
+	if !cfg.Persistence.Enabled || cfg.Persistence.Kind == "memory" {
+		return memory(), nil
+	}
+	if !slices.Contains(sql.Drivers(), cfg.Persistence.Kind) {
+		log.Warn("当前构建未包含数据库驱动，幂等键保存在内存中，多实例之间不共享", "kind", cfg.Persistence.Kind)
+		return memory(), nil
+	}
+	db, err := sql.Open(cfg.Persistence.Kind, cfg.Persistence.Dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开幂等键存储失败: %w", err)
+	}
+	idem, err := history.NewSQLIdempotency(db, cfg.Persistence.Kind, window, log.With("component", "idempotency"))
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return idem, nil
+}
+
+// newWebhooks 按 webhook 配置创建回调分发器并恢复上次的队列，未启用时返回 nil
+func newWebhooks(log *slog.Logger) *webhook.Dispatcher {
+	cfg := config.Config.Webhook
+	if !cfg.Enabled {
+		return nil
+	}
+	subs := make([]webhook.Subscription, 0, len(cfg.Subscriptions))
+	for _, s := range cfg.Subscriptions {
+		sub := webhook.Subscription{URL: s.Url, Secret: s.Secret, Tenants: s.Tenants, ClientTypes: s.ClientTypes, Users: s.Users}
+		if len(s.Topics) > 0 {
+			sub.Topics = topic.NewFilter(s.Topics, nil)
+		}
+		subs = append(subs, sub)
+	}
+	d := webhook.NewDispatcher(subs, webhook.Options{
+		Secret:         cfg.Secret,
+		Timeout:        time.Duration(cfg.TimeoutSec) * time.Second,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.MaxBackoffSec) * time.Second,
+		Workers:        cfg.Workers,
+		QueueMax:       cfg.QueueMax,
+		DeadLetterMax:  cfg.DeadLetterMax,
+		QueueFile:      cfg.QueueFile,
+	}, log.With("component", "webhook"))
+	if err := d.Load(); err != nil {
+		log.Warn("恢复 webhook 队列失败，从空队列开始", "file", cfg.QueueFile, "err", err)
+	}
+	return d
+}
+
+// newScheduler 按 schedule 配置创建定时发布调度器并恢复上次的队列，未启用时返回 nil。
+// 到期的消息按原请求重新走一次发布流程
+func newScheduler(tenants ports.Tenants, inbox ports.Inbox, log *slog.Logger) *schedule.Scheduler {
+	cfg := config.Config.Schedule
+	if !cfg.Enabled {
+		return nil
+	}
+	fire := func(s publish.Scheduled) error {
+		h, err := tenants.Hub(s.Tenant)
+		if err != nil {
+			return err
+		}
+		_, err = publish.Dispatch(publish.Target{Tenant: s.Tenant, Hub: h, Inbox: inbox}, s.Request)
+		return err
+	}
+	sc := schedule.New(schedule.Options{
+		MaxPending: cfg.MaxPending,
+		MaxDelay:   time.Duration(cfg.MaxDelayHours) * time.Hour,
+		File:       cfg.File,
+	}, fire, nil, log.With("component", "schedule"))
+	if err := sc.Load(); err != nil {
+		log.Warn("恢复定时消息失败，从空队列开始", "file", cfg.File, "err", err)
+	}
+	return sc
+}
+
+func toConflateRules(rules []config.ConflateRule) []hub.ConflateRule {
+	out := make([]hub.ConflateRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, hub.ConflateRule{Topic: r.Topic, Key: r.Key})
+	}
+	return out
+}
+
+func toLane(l config.Lane) hub.Lane {
+	return hub.Lane{Size: l.Size, Weight: l.Weight, OnFull: l.OnFull}
+}
+
+func toQuota(q config.Quota) ports.Quota {
+	return ports.Quota{
+		MaxConns:            q.MaxConns,
+		MaxTopicsPerClient:  q.MaxTopicsPerClient,
+		PublishQps:          q.PublishQps,
+		MaxConnsPerUser:     q.MaxConnsPerUser,
+		MaxConnsPerUserType: q.MaxConnsPerUserType,
+		OnUserLimit:         q.OnUserLimit,
+		SingleSession:       q.SingleSession,
 	}
 }
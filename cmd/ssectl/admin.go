@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	pb "sse/pkg/ssepb"
+)
+
+// clientRow stats 表格的一行
+type clientRow struct {
+	ClientID   string
+	UserID     int64
+	ClientType string
+	Transport  string
+}
+
+func runStats(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	var ep endpoint
+	ep.register(fs)
+	asJSON := fs.Bool("json", false, "输出 JSON 而不是表格")
+	_ = fs.Parse(args)
+
+	var (
+		rows []clientRow
+		err  error
+	)
+	if ep.grpcAddr != "" {
+		rows, err = statsGRPC(ctx, &ep)
+	} else {
+		rows, err = statsHTTP(ctx, &ep)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].UserID != rows[j].UserID {
+			return rows[i].UserID < rows[j].UserID
+		}
+		return rows[i].ClientID < rows[j].ClientID
+	})
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLIENT ID\tUSER ID\tTYPE\tTRANSPORT")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", r.ClientID, r.UserID, r.ClientType, r.Transport)
+	}
+	fmt.Fprintf(tw, "\n共 %d 个连接\n", len(rows))
+	return tw.Flush()
+}
+
+func statsHTTP(ctx context.Context, ep *endpoint) ([]clientRow, error) {
+	var stats []struct {
+		ClientID  int64
+		UserID    int64
+		Type      string
+		Transport string
+	}
+	if err := getJSON(ctx, ep.base()+"/status", ep.header(), &stats); err != nil {
+		return nil, err
+	}
+	rows := make([]clientRow, 0, len(stats))
+	for _, s := range stats {
+		rows = append(rows, clientRow{ClientID: fmt.Sprint(s.ClientID), UserID: s.UserID, ClientType: s.Type, Transport: s.Transport})
+	}
+	return rows, nil
+}
+
+func statsGRPC(ctx context.Context, ep *endpoint) ([]clientRow, error) {
+	conn, err := ep.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewMessageServiceClient(conn).Status(ep.outgoing(ctx), &pb.StatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]clientRow, 0, len(resp.Stats))
+	for _, s := range resp.Stats {
+		rows = append(rows, clientRow{ClientID: s.ClientId, UserID: s.UserId, ClientType: s.ClientType, Transport: s.Transport})
+	}
+	return rows, nil
+}
+
+func runKick(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("kick", flag.ExitOnError)
+	var ep endpoint
+	ep.register(fs)
+	clientId := fs.String("client", "", "断开该客户端 ID")
+	user := fs.String("user", "", "断开该用户的全部连接")
+	_ = fs.Parse(args)
+
+	q := ep.adminQuery()
+	switch {
+	case *clientId != "":
+		q.Set("clientId", *clientId)
+	case *user != "":
+		q.Set("userId", *user)
+	default:
+		return errors.New("需要 --client 或 --user")
+	}
+
+	var result struct {
+		Kicked []int64 `json:"kicked"`
+	}
+	if err := ep.admin(ctx, http.MethodPost, "/admin/kick", q, &result); err != nil {
+		return err
+	}
+	fmt.Printf("已断开 %d 个连接 %v\n", len(result.Kicked), result.Kicked)
+	return nil
+}
+
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	var ep endpoint
+	ep.register(fs)
+	topic := fs.String("topic", "", "回放的 topic（必填）")
+	after := fs.Uint64("after", 0, "从该记录 ID 之后开始")
+	limit := fs.Int("limit", 0, "最多输出的条数，0 表示全部")
+	raw := fs.Bool("raw", false, "只输出原始数据，每行一条")
+	_ = fs.Parse(args)
+
+	if *topic == "" {
+		return errors.New("需要 --topic")
+	}
+
+	// 分页读取直到取完或达到 limit
+	const page = 500
+	printed := 0
+	for *limit <= 0 || printed < *limit {
+		n := page
+		if *limit > 0 {
+			n = min(n, *limit-printed)
+		}
+		q := ep.adminQuery()
+		q.Set("topic", *topic)
+		q.Set("after", fmt.Sprint(*after))
+		q.Set("limit", fmt.Sprint(n))
+
+		var records []struct {
+			ID      uint64    `json:"id"`
+			Message string    `json:"message"`
+			Time    time.Time `json:"time"`
+		}
+		if err := ep.admin(ctx, http.MethodGet, "/admin/history", q, &records); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if *raw {
+				fmt.Println(r.Message)
+			} else {
+				fmt.Printf("%d\t%s\t%s\n", r.ID, r.Time.Format(time.RFC3339Nano), r.Message)
+			}
+			*after = r.ID
+		}
+		printed += len(records)
+		if len(records) < n {
+			break
+		}
+	}
+	return nil
+}
+
+// adminQuery /admin/* 通过查询参数指定租户
+func (e *endpoint) adminQuery() url.Values {
+	q := url.Values{}
+	if e.tenant != "" {
+		q.Set("tenant", e.tenant)
+	}
+	return q
+}
+
+// admin 调用 /admin/* 接口并解码 JSON 响应；管理接口挂在根路径，不带路径租户前缀
+func (e *endpoint) admin(ctx context.Context, method, path string, q url.Values, v any) error {
+	u, err := url.Parse(e.base())
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawQuery = path, q.Encode()
+
+	h := http.Header{}
+	if e.adminToken != "" {
+		h.Set("Authorization", "Bearer "+e.adminToken)
+	}
+	return doJSON(ctx, method, u.String(), h, v)
+}
+
+func getJSON(ctx context.Context, url string, h http.Header, v any) error {
+	return doJSON(ctx, http.MethodGet, url, h, v)
+}
+
+func doJSON(ctx context.Context, method, url string, h http.Header, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = h
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s %s", method, url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,146 @@
+// ssectl 是 SSE 服务的调试命令行：发布消息、订阅并打印事件、查看连接、踢出客户端与回放历史。
+//
+//	ssectl publish --topic orders -m '{"id":1}'
+//	ssectl tail --topics orders,notice --grep id
+//	ssectl stats
+//	ssectl kick --user 42
+//	ssectl replay --topic orders --after 100
+//
+// 默认走 HTTP（--addr），指定 --grpc 后 publish 与 stats 走 gRPC；kick 与 replay 使用 /admin/* 接口。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sse/pkg/publisher"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"publish", "发布消息，未指定 -m 时逐行读取标准输入", runPublish},
+	{"tail", "订阅并打印事件", runTail},
+	{"stats", "以表格列出当前连接", runStats},
+	{"kick", "断开指定客户端或用户的连接", runKick},
+	{"replay", "读取 topic 的持久化历史", runReplay},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(ctx, os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "ssectl:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "ssectl: 未知的子命令 %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: ssectl <子命令> [参数]")
+	fmt.Fprintln(os.Stderr)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "各子命令的参数见 ssectl <子命令> -h")
+}
+
+// endpoint 各子命令共用的连接参数，默认值可用 SSECTL_* 环境变量设置
+type endpoint struct {
+	addr         string
+	grpcAddr     string
+	tenant       string
+	tenantHeader string
+	token        string
+	adminToken   string
+}
+
+func (e *endpoint) register(fs *flag.FlagSet) {
+	fs.StringVar(&e.addr, "addr", env("SSECTL_ADDR", "http://localhost:8080"), "HTTP 地址，路径模式的租户可写成 http://host:8080/t/<tenant>")
+	fs.StringVar(&e.grpcAddr, "grpc", os.Getenv("SSECTL_GRPC"), "gRPC 地址，如 localhost:50051；设置后 publish/stats 走 gRPC")
+	fs.StringVar(&e.tenant, "tenant", os.Getenv("SSECTL_TENANT"), "租户名")
+	fs.StringVar(&e.tenantHeader, "tenant-header", "X-Tenant", "租户请求头")
+	fs.StringVar(&e.token, "token", os.Getenv("SSECTL_TOKEN"), "Bearer token（token 租户模式）")
+	fs.StringVar(&e.adminToken, "admin-token", os.Getenv("SSECTL_ADMIN_TOKEN"), "/admin/* 接口的 token")
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// base HTTP 基础地址，不带结尾的 /
+func (e *endpoint) base() string {
+	return strings.TrimRight(e.addr, "/")
+}
+
+// header 租户与认证请求头
+func (e *endpoint) header() http.Header {
+	h := http.Header{}
+	if e.tenant != "" {
+		h.Set(e.tenantHeader, e.tenant)
+	}
+	if e.token != "" {
+		h.Set("Authorization", "Bearer "+e.token)
+	}
+	return h
+}
+
+// dial 建立 gRPC 连接，调用方负责关闭
+func (e *endpoint) dial() (*grpc.ClientConn, error) {
+	return grpc.NewClient(e.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+// outgoing 附加租户与认证 metadata
+func (e *endpoint) outgoing(ctx context.Context) context.Context {
+	var kv []string
+	if e.tenant != "" {
+		kv = append(kv, strings.ToLower(e.tenantHeader), e.tenant)
+	}
+	if e.token != "" {
+		kv = append(kv, "authorization", "Bearer "+e.token)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// publisher 按是否指定 --grpc 创建发布客户端，close 关闭底层连接
+func (e *endpoint) publisher() (p *publisher.Publisher, close func(), err error) {
+	opts := publisher.Options{Tenant: e.tenant, TenantHeader: e.tenantHeader, Token: e.token}
+	if e.grpcAddr == "" {
+		return publisher.NewHTTP(e.base(), opts), func() {}, nil
+	}
+	conn, err := e.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	return publisher.NewGRPC(conn, opts), func() { _ = conn.Close() }, nil
+}
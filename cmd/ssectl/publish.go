@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sse/pkg/publisher"
+	"strconv"
+	"sync"
+)
+
+// ndjsonLine 与服务端批量接口的条目一致；kind 为空时使用命令行指定的目标
+type ndjsonLine struct {
+	Kind       string `json:"kind"`
+	Topic      string `json:"topic"`
+	UserId     int64  `json:"userId"`
+	ClientType string `json:"clientType"`
+	Message    string `json:"message"`
+	Persistent bool   `json:"persistent"`
+}
+
+func runPublish(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	var ep endpoint
+	ep.register(fs)
+	topic := fs.String("topic", "", "发布到 topic")
+	user := fs.String("user", "", "发布到用户，与 --type 同时指定时发布到该用户的某类客户端")
+	clientType := fs.String("type", "", "发布到客户端类型")
+	message := fs.String("m", "", "消息内容；为空时逐行读取标准输入，每行一条")
+	ndjson := fs.Bool("ndjson", false, "标准输入为 NDJSON，每行 {kind,topic,userId,clientType,message,persistent}")
+	batch := fs.Int("batch", 100, "读取标准输入时每批最多发送的条数")
+	persistent := fs.Bool("persistent", false, "存入用户收件箱，用户确认前每次连接都会补发（仅 --user）")
+	_ = fs.Parse(args)
+
+	target, targetErr := parseTarget(*topic, *user, *clientType)
+
+	p, closeConn, err := ep.publisher()
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	if *message != "" {
+		if targetErr != nil {
+			return targetErr
+		}
+		return p.Publish(ctx, target, publisher.Event{Message: *message, Persistent: *persistent})
+	}
+	if !*ndjson && targetErr != nil {
+		return targetErr
+	}
+
+	// 逐行读取标准输入，后台批次与同步批次的失败都汇总到 firstErr
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	record := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	b := p.NewBatcher(publisher.BatchOptions{
+		MaxItems: *batch,
+		OnError:  func(_ []publisher.Item, err error) { record(err) },
+	})
+
+	total, err := readLines(os.Stdin, func(line []byte) error {
+		t, msg, keep := target, string(line), *persistent
+		if *ndjson {
+			var l ndjsonLine
+			if err := json.Unmarshal(line, &l); err != nil {
+				return fmt.Errorf("无效的 NDJSON: %w", err)
+			}
+			if l.Kind != "" {
+				t = publisher.Target{Kind: l.Kind, Topic: l.Topic, UserId: l.UserId, ClientType: l.ClientType}
+			} else if targetErr != nil {
+				return targetErr
+			}
+			msg, keep = l.Message, keep || l.Persistent
+		}
+		if err := b.Add(ctx, t, publisher.Event{Message: msg, Persistent: keep}); err != nil {
+			record(err)
+		}
+		return nil
+	})
+	if cerr := b.Close(ctx); cerr != nil {
+		record(cerr)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "已读取 %d 条\n", total)
+	return firstErr
+}
+
+// parseTarget 由命令行参数确定发布目标
+func parseTarget(topic, user, clientType string) (publisher.Target, error) {
+	if topic != "" {
+		return publisher.Topic(topic), nil
+	}
+	if user == "" {
+		if clientType == "" {
+			return publisher.Target{}, errors.New("需要 --topic、--user 或 --type 之一")
+		}
+		return publisher.ClientType(clientType), nil
+	}
+
+	userId, err := strconv.ParseInt(user, 10, 64)
+	if err != nil {
+		return publisher.Target{}, fmt.Errorf("无效的 --user: %w", err)
+	}
+	if clientType != "" {
+		return publisher.Client(clientType, userId), nil
+	}
+	return publisher.User(userId), nil
+}
+
+// readLines 逐行回调非空行，返回处理的行数
+func readLines(r io.Reader, fn func(line []byte) error) (int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		n++
+		if err := fn(sc.Bytes()); err != nil {
+			return n, fmt.Errorf("第 %d 行: %w", n, err)
+		}
+	}
+	return n, sc.Err()
+}
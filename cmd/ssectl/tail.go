@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sse/pkg/client"
+	"strings"
+	"time"
+)
+
+// 心跳以数据帧形式下发
+const heartbeatData = "event: ping"
+
+func runTail(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	var ep endpoint
+	ep.register(fs)
+	topics := fs.String("topics", "", "订阅的 topic，逗号分隔（必填）")
+	user := fs.String("user", "0", "以该用户身份订阅")
+	clientType := fs.String("type", "ssectl", "以该客户端类型订阅")
+	filter := fs.String("filter", "", `服务端内容过滤表达式，如 'region == "eu" && amount > 100'`)
+	grep := fs.String("grep", "", "只打印数据中包含该字符串的事件")
+	event := fs.String("event", "", "只打印该事件类型")
+	raw := fs.Bool("raw", false, "只输出原始数据，每行一条，便于管道处理")
+	pings := fs.Bool("pings", false, "同时打印心跳")
+	lastID := fs.String("last-event-id", "", "从该事件 ID 之后恢复")
+	_ = fs.Parse(args)
+
+	if *topics == "" {
+		return errors.New("需要 --topics")
+	}
+	q := url.Values{}
+	q.Set("topics", *topics)
+	q.Set("userId", *user)
+	q.Set("clientType", *clientType)
+	if *filter != "" {
+		q.Set("filter", *filter)
+	}
+
+	events, err := client.Subscribe(ctx, ep.base()+"/sse?"+q.Encode(), client.Options{
+		Header:      ep.header(),
+		LastEventID: *lastID,
+		OnStateChange: func(state client.State, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[%s] %v\n", state, err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "[%s]\n", state)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for ev := range events {
+		if ev.Data == heartbeatData && !*pings {
+			continue
+		}
+		if *event != "" && ev.Event != *event {
+			continue
+		}
+		if *grep != "" && !strings.Contains(ev.Data, *grep) {
+			continue
+		}
+		if *raw {
+			fmt.Println(ev.Data)
+			continue
+		}
+		printEvent(ev)
+	}
+	return nil
+}
+
+// printEvent 打印时间、事件类型与 ID，JSON 数据缩进输出
+func printEvent(ev client.Event) {
+	head := time.Now().Format("15:04:05.000")
+	if ev.Event != "" && ev.Event != "message" {
+		head += " " + ev.Event
+	}
+	if ev.ID != "" {
+		head += " id=" + ev.ID
+	}
+
+	data := ev.Data
+	var buf bytes.Buffer
+	if json.Valid([]byte(data)) && json.Indent(&buf, []byte(data), "  ", "  ") == nil {
+		data = buf.String()
+	}
+	fmt.Printf("%s\n  %s\n", head, data)
+}
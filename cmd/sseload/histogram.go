@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// 每个 2 的幂区间再细分的桶数，相对误差约 1/subBuckets
+const subBuckets = 32
+
+// histogram 以微秒为单位的对数线性直方图，可并发写入
+type histogram struct {
+	counts [64 * subBuckets]atomic.Int64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{}
+}
+
+func bucketOf(us int64) int {
+	if us < subBuckets {
+		return int(us)
+	}
+	// 最高位决定区间，其后 log2(subBuckets) 位决定区间内的桶
+	exp := bits.Len64(uint64(us)) - 1
+	shift := exp - bits.Len(subBuckets-1)
+	return (shift+1)*subBuckets + int(us>>shift) - subBuckets
+}
+
+// lowerBound 桶的下界（微秒）
+func lowerBound(b int) int64 {
+	if b < subBuckets {
+		return int64(b)
+	}
+	shift := b/subBuckets - 1
+	return int64(b%subBuckets+subBuckets) << shift
+}
+
+func (h *histogram) observe(d time.Duration) {
+	us := max(d.Microseconds(), 0)
+	h.counts[bucketOf(us)].Add(1)
+	h.sum.Add(us)
+	for {
+		cur := h.max.Load()
+		if us <= cur || h.max.CompareAndSwap(cur, us) {
+			return
+		}
+	}
+}
+
+type latencyStats struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p999"`
+	Max   float64 `json:"max"`
+}
+
+// stats 汇总为毫秒
+func (h *histogram) stats() latencyStats {
+	var total int64
+	for i := range h.counts {
+		total += h.counts[i].Load()
+	}
+	if total == 0 {
+		return latencyStats{}
+	}
+
+	quantile := func(q float64) float64 {
+		rank := int64(math.Ceil(q * float64(total)))
+		var seen int64
+		for i := range h.counts {
+			seen += h.counts[i].Load()
+			if seen >= rank {
+				return float64(lowerBound(i)) / 1000
+			}
+		}
+		return float64(h.max.Load()) / 1000
+	}
+	return latencyStats{
+		Count: total,
+		Mean:  float64(h.sum.Load()) / float64(total) / 1000,
+		P50:   quantile(0.5),
+		P90:   quantile(0.9),
+		P99:   quantile(0.99),
+		P999:  quantile(0.999),
+		Max:   float64(h.max.Load()) / 1000,
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// connSpec 单个连接的订阅参数
+type connSpec struct {
+	userId     int64
+	clientType string
+	topics     []string
+}
+
+// layout 连接分布，以及按 topic / 用户 / 类型统计的连接数，用于计算期望投递数
+type layout struct {
+	conns  []connSpec
+	topics []string
+	types  []string
+
+	byTopic map[string]int64
+	byUser  map[int64]int64
+	byType  map[string]int64
+
+	topicDist string
+	userDist  string
+	users     int
+}
+
+func newLayout(o options) *layout {
+	l := &layout{
+		topics:    make([]string, o.Topics),
+		types:     o.Types,
+		byTopic:   make(map[string]int64),
+		byUser:    make(map[int64]int64),
+		byType:    make(map[string]int64),
+		topicDist: o.TopicDist,
+		userDist:  o.UserDist,
+		users:     o.Users,
+	}
+	for i := range l.topics {
+		l.topics[i] = "load." + strconv.Itoa(i)
+	}
+
+	r := rand.New(rand.NewPCG(o.Seed, 0))
+	pickTopic, pickUser := l.topicPicker(r), l.userPicker(r)
+	perConn := min(max(o.TopicsPerConn, 1), o.Topics)
+	for i := 0; i < o.Conns; i++ {
+		spec := connSpec{
+			userId:     int64(pickUser()),
+			clientType: o.Types[i%len(o.Types)],
+		}
+		// 不重复地选取 topic；zipf 下冷门 topic 很难抽中，多次未命中后按顺序补齐
+		seen := make(map[int]bool, perConn)
+		for tries := 0; len(spec.topics) < perConn; tries++ {
+			t := pickTopic()
+			if tries >= 32*perConn {
+				for t = 0; seen[t]; t++ {
+				}
+			}
+			if !seen[t] {
+				seen[t] = true
+				spec.topics = append(spec.topics, l.topics[t])
+			}
+		}
+
+		l.conns = append(l.conns, spec)
+		l.byUser[spec.userId]++
+		l.byType[spec.clientType]++
+		for _, t := range spec.topics {
+			l.byTopic[t]++
+		}
+	}
+	return l
+}
+
+// topicPicker 按 topic 分布选取 topic 下标
+func (l *layout) topicPicker(r *rand.Rand) func() int {
+	return picker(r, l.topicDist, len(l.topics))
+}
+
+// userPicker 按用户分布选取 userId
+func (l *layout) userPicker(r *rand.Rand) func() int {
+	return picker(r, l.userDist, l.users)
+}
+
+// picker 返回在 [0, n) 中按分布取值的函数，zipf 时 0 最热
+func picker(r *rand.Rand, dist string, n int) func() int {
+	if dist == "zipf" && n > 1 {
+		z := rand.NewZipf(r, 1.1, 1, uint64(n-1))
+		return func() int { return int(z.Uint64()) }
+	}
+	return func() int { return r.IntN(n) }
+}
+
+// mix 发布目标的累计权重
+type mix struct {
+	kinds   []string
+	weights []int
+	total   int
+}
+
+// parseMix 解析 topic=8,user=1,type=1
+func parseMix(s string) (mix, error) {
+	var m mix
+	for _, part := range strings.Split(s, ",") {
+		kind, w, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			w = "1"
+		}
+		if kind != "topic" && kind != "user" && kind != "type" {
+			return m, fmt.Errorf("--mix: 未知的目标 %q，可选 topic | user | type", kind)
+		}
+		n, err := strconv.Atoi(w)
+		if err != nil || n < 0 {
+			return m, fmt.Errorf("--mix: 无效的权重 %q", part)
+		}
+		m.total += n
+		m.kinds = append(m.kinds, kind)
+		m.weights = append(m.weights, m.total)
+	}
+	if m.total == 0 {
+		return m, fmt.Errorf("--mix: 权重之和必须大于 0")
+	}
+	return m, nil
+}
+
+func (m mix) pick(r *rand.Rand) string {
+	n := r.IntN(m.total)
+	for i, w := range m.weights {
+		if n < w {
+			return m.kinds[i]
+		}
+	}
+	return m.kinds[len(m.kinds)-1]
+}
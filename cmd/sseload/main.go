@@ -0,0 +1,199 @@
+// sseload 是 Hub 的压测工具：建立 N 个 SSE 连接，按目标速率发布消息，统计端到端延迟、投递率、丢弃数与每连接内存，结果以 JSON 输出便于跨提交对比。
+//
+//	sseload --conns 2000 --topics 50 --rate 5000 --duration 30s > before.json
+//	sseload --target http://localhost:8080 --conns 500 --mix topic=8,user=1,type=1
+//
+// 未指定 --target 时在进程内启动服务端（无持久化、默认租户），监听 127.0.0.1 的随机端口。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// options 压测参数，原样写入结果便于复现
+type options struct {
+	Target        string        `json:"target"`
+	Conns         int           `json:"conns"`
+	ConnectRate   int           `json:"connectRate"`
+	Topics        int           `json:"topics"`
+	TopicsPerConn int           `json:"topicsPerConn"`
+	TopicDist     string        `json:"topicDist"`
+	Users         int           `json:"users"`
+	UserDist      string        `json:"userDist"`
+	Types         []string      `json:"types"`
+	Rate          int           `json:"rate"`
+	Batch         int           `json:"batch"`
+	Mix           string        `json:"mix"`
+	Size          int           `json:"size"`
+	Duration      time.Duration `json:"duration"`
+	Drain         time.Duration `json:"drain"`
+	Seed          uint64        `json:"seed"`
+	DropSlow      bool          `json:"dropSlow"`
+}
+
+func main() {
+	var o options
+	types := ""
+	flag.StringVar(&o.Target, "target", "", "被测服务的 HTTP 地址，为空时在进程内启动服务端")
+	flag.IntVar(&o.Conns, "conns", 1000, "SSE 连接数")
+	flag.IntVar(&o.ConnectRate, "connect-rate", 500, "每秒新建连接数，0 表示不限制")
+	flag.IntVar(&o.Topics, "topics", 10, "topic 总数，名称为 load.0 ~ load.N-1")
+	flag.IntVar(&o.TopicsPerConn, "topics-per-conn", 1, "每个连接订阅的 topic 数")
+	flag.StringVar(&o.TopicDist, "topic-dist", "uniform", "topic 的选取分布：uniform | zipf")
+	flag.IntVar(&o.Users, "users", 100, "用户数，连接的 userId 取 0 ~ N-1")
+	flag.StringVar(&o.UserDist, "user-dist", "uniform", "userId 的选取分布：uniform | zipf")
+	flag.StringVar(&types, "types", "web,app", "客户端类型，逗号分隔，按连接轮流分配")
+	flag.IntVar(&o.Rate, "rate", 1000, "目标发布速率（条/秒）")
+	flag.IntVar(&o.Batch, "batch", 100, "每次 publishBatch 的最大条数，1 表示逐条调用 publishBy* 接口")
+	flag.StringVar(&o.Mix, "mix", "topic=1", "发布目标的比例，如 topic=8,user=1,type=1")
+	flag.IntVar(&o.Size, "size", 128, "消息大小（字节，含时间戳等元数据）")
+	flag.DurationVar(&o.Duration, "duration", 10*time.Second, "发布持续时间")
+	flag.DurationVar(&o.Drain, "drain", 2*time.Second, "停止发布后等待在途消息的时间")
+	flag.Uint64Var(&o.Seed, "seed", 1, "随机种子，相同参数与种子得到相同的连接分布")
+	flag.BoolVar(&o.DropSlow, "drop-slow", true, "进程内服务端是否断开慢客户端")
+	out := flag.String("out", "", "结果写入该文件，默认输出到标准输出")
+	flag.Parse()
+	o.Types = strings.Split(types, ",")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := run(ctx, o)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sseload:", err)
+		os.Exit(1)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sseload:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(result)
+}
+
+// Result 一次压测的结果
+type Result struct {
+	Options   options       `json:"options"`
+	GoVersion string        `json:"goVersion"`
+	StartedAt time.Time     `json:"startedAt"`
+	Connect   connectStats  `json:"connect"`
+	Publish   publishStats  `json:"publish"`
+	Delivery  deliveryStats `json:"delivery"`
+	LatencyMs latencyStats  `json:"latencyMs"`
+	Memory    *memoryStats  `json:"memory,omitempty"`
+}
+
+type connectStats struct {
+	Requested   int     `json:"requested"`
+	Established int     `json:"established"`
+	Failed      int     `json:"failed"`
+	Lost        int64   `json:"lost"` // 压测期间被服务端断开的连接
+	Seconds     float64 `json:"seconds"`
+}
+
+type publishStats struct {
+	Sent         int64   `json:"sent"`
+	Errors       int64   `json:"errors"`
+	AchievedRate float64 `json:"achievedRate"`
+}
+
+type deliveryStats struct {
+	Expected  int64   `json:"expected"`
+	Delivered int64   `json:"delivered"`
+	Ratio     float64 `json:"ratio"`
+	// 服务端 /metrics 中 sse_messages_dropped_total 与 sse_slow_client_evictions_total 的增量
+	ServerDropped   float64 `json:"serverDropped"`
+	ServerEvictions float64 `json:"serverEvictions"`
+}
+
+type memoryStats struct {
+	// 进程内模式下建立连接前后的堆与协程栈增量除以连接数，包含压测端自身的读取协程
+	BytesPerConn float64 `json:"bytesPerConn"`
+	Goroutines   int     `json:"goroutines"`
+}
+
+func run(ctx context.Context, o options) (*Result, error) {
+	mix, err := parseMix(o.Mix)
+	if err != nil {
+		return nil, err
+	}
+	if o.Conns <= 0 || o.Topics <= 0 || o.Users <= 0 || o.Rate <= 0 || len(o.Types) == 0 {
+		return nil, fmt.Errorf("conns、topics、users、rate 必须为正数，types 不能为空")
+	}
+
+	res := &Result{Options: o, GoVersion: runtime.Version(), StartedAt: time.Now()}
+
+	inProcess := o.Target == ""
+	if inProcess {
+		addr, shutdown, err := startServer(o.DropSlow)
+		if err != nil {
+			return nil, err
+		}
+		defer shutdown()
+		o.Target = addr
+	}
+	o.Target = strings.TrimRight(o.Target, "/")
+
+	layout := newLayout(o)
+	before := scrape(ctx, o.Target)
+	memBefore := memInuse()
+
+	fmt.Fprintf(os.Stderr, "建立 %d 个连接到 %s\n", o.Conns, o.Target)
+	subs := newSubscribers(o.Target, layout)
+	res.Connect = subs.open(ctx, o.ConnectRate)
+	if inProcess && res.Connect.Established > 0 {
+		res.Memory = &memoryStats{
+			BytesPerConn: (float64(memInuse()) - float64(memBefore)) / float64(res.Connect.Established),
+			Goroutines:   runtime.NumGoroutine(),
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "以 %d 条/秒发布 %s\n", o.Rate, o.Duration)
+	pub := newPublisher(o, layout, mix)
+	res.Publish = pub.run(ctx, o.Duration)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(o.Drain):
+	}
+	subs.close()
+
+	after := scrape(context.Background(), o.Target)
+	res.Connect.Lost = subs.lost.Load()
+	res.Delivery = deliveryStats{
+		Expected:        pub.expected.Load(),
+		Delivered:       subs.delivered.Load(),
+		ServerDropped:   after.dropped - before.dropped,
+		ServerEvictions: after.evictions - before.evictions,
+	}
+	if res.Delivery.Expected > 0 {
+		res.Delivery.Ratio = float64(res.Delivery.Delivered) / float64(res.Delivery.Expected)
+	}
+	res.LatencyMs = subs.latency.stats()
+	return res, nil
+}
+
+// memInuse GC 后的堆与协程栈占用
+func memInuse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse + m.StackInuse
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"sse/pkg/publisher"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// loadPublisher 按目标速率发布，并累计每条消息的期望投递数
+type loadPublisher struct {
+	o      options
+	layout *layout
+	mix    mix
+	p      *publisher.Publisher
+
+	r         *rand.Rand
+	pickTopic func() int
+	pickUser  func() int
+	padding   string
+
+	expected atomic.Int64
+}
+
+func newPublisher(o options, l *layout, m mix) *loadPublisher {
+	// 与连接分布使用不同的随机流
+	r := rand.New(rand.NewPCG(o.Seed, 1))
+	return &loadPublisher{
+		o:      o,
+		layout: l,
+		mix:    m,
+		// 压测关注原始吞吐，不重试
+		p:         publisher.NewHTTP(o.Target, publisher.Options{MaxRetries: -1}),
+		r:         r,
+		pickTopic: l.topicPicker(r),
+		pickUser:  l.userPicker(r),
+		padding:   strings.Repeat("x", max(o.Size-20, 0)),
+	}
+}
+
+// next 选取下一条消息的目标及其期望投递数
+func (p *loadPublisher) next() (publisher.Target, int64) {
+	switch p.mix.pick(p.r) {
+	case "user":
+		userId := int64(p.pickUser())
+		return publisher.User(userId), p.layout.byUser[userId]
+	case "type":
+		clientType := p.layout.types[p.r.IntN(len(p.layout.types))]
+		return publisher.ClientType(clientType), p.layout.byType[clientType]
+	default:
+		name := p.layout.topics[p.pickTopic()]
+		return publisher.Topic(name), p.layout.byTopic[name]
+	}
+}
+
+// run 发布 duration 时长，每个 tick 补发落后于目标速率的条数
+func (p *loadPublisher) run(ctx context.Context, duration time.Duration) publishStats {
+	var stats publishStats
+	start := time.Now()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= duration || ctx.Err() != nil {
+			break
+		}
+		due := int64(elapsed.Seconds()*float64(p.o.Rate)) - stats.Sent - stats.Errors
+		for due > 0 && ctx.Err() == nil {
+			n := min(due, int64(max(p.o.Batch, 1)))
+			sent, failed := p.send(ctx, int(n))
+			stats.Sent += sent
+			stats.Errors += failed
+			due -= n
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	stats.AchievedRate = float64(stats.Sent) / time.Since(start).Seconds()
+	return stats
+}
+
+// send 发送 n 条消息，返回成功与失败的条数
+func (p *loadPublisher) send(ctx context.Context, n int) (int64, int64) {
+	items := make([]publisher.Item, n)
+	expect := make([]int64, n)
+	for i := range items {
+		target, e := p.next()
+		items[i] = publisher.Item{Target: target, Event: publisher.Event{
+			Message: strconv.FormatInt(time.Now().UnixNano(), 10) + " " + p.padding,
+		}}
+		expect[i] = e
+	}
+
+	if p.o.Batch <= 1 {
+		if err := p.p.Publish(ctx, items[0].Target, items[0].Event); err != nil {
+			return 0, 1
+		}
+		p.expected.Add(expect[0])
+		return 1, 0
+	}
+
+	accepted, _ := p.p.PublishBatch(ctx, items)
+	for _, e := range expect[:accepted] {
+		p.expected.Add(e)
+	}
+	return int64(accepted), int64(n - accepted)
+}
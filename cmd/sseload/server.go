@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sse/internal/adapters/hub"
+	"sse/internal/adapters/tenant"
+	apiHttp "sse/internal/api/http"
+	"sse/internal/ports"
+	"sse/pkg/health"
+	"strconv"
+	"strings"
+)
+
+// startServer 在进程内启动不带持久化与心跳的服务端，返回其 HTTP 地址
+func startServer(dropSlow bool) (string, func(), error) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	settings := &hub.Settings{SampleEvery: 1000}
+	settings.DropSlowClient.Store(dropSlow)
+	newHub := func(name string, quota ports.Quota) ports.Hub {
+		return hub.NewShardedHub(quota, settings, log)
+	}
+	registry := tenant.NewRegistry(newHub, ports.Quota{}, nil, false, 0, 0)
+
+	handler := apiHttp.RegisterRoutes(apiHttp.Deps{
+		Tenants:  registry,
+		Resolver: &tenant.Resolver{Mode: tenant.ModeNone},
+		Health:   health.New(),
+		Logger:   log,
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	server := &http.Server{Handler: handler}
+	go func() { _ = server.Serve(ln) }()
+	return "http://" + ln.Addr().String(), func() { _ = server.Close() }, nil
+}
+
+// serverCounters 从 /metrics 读取的计数器
+type serverCounters struct {
+	dropped   float64
+	evictions float64
+}
+
+// scrape 读取被测服务的丢弃与断开计数，失败时返回零值
+func scrape(ctx context.Context, base string) serverCounters {
+	var c serverCounters
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/metrics", nil)
+	if err != nil {
+		return c
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return c
+	}
+	defer resp.Body.Close()
+
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		sp := strings.LastIndexByte(line, ' ')
+		if sp < 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value := line[:sp], line[sp+1:]
+		if i := strings.IndexByte(name, '{'); i >= 0 {
+			name = name[:i]
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "sse_messages_dropped_total":
+			c.dropped += v
+		case "sse_slow_client_evictions_total":
+			c.evictions += v
+		}
+	}
+	return c
+}
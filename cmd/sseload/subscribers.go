@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sse/pkg/client"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// subscribers 管理压测连接，统计收到的消息与端到端延迟
+type subscribers struct {
+	base   string
+	layout *layout
+	http   *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	delivered atomic.Int64
+	lost      atomic.Int64
+	latency   *histogram
+}
+
+func newSubscribers(base string, l *layout) *subscribers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &subscribers{
+		base:   base,
+		layout: l,
+		// 每个连接独占一条 TCP 连接，不设超时
+		http:    &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: -1, DisableCompression: true}},
+		ctx:     ctx,
+		cancel:  cancel,
+		latency: newHistogram(),
+	}
+}
+
+// open 按 connectRate 建立全部连接，等待每个连接收到响应头后返回
+func (s *subscribers) open(ctx context.Context, connectRate int) connectStats {
+	stats := connectStats{Requested: len(s.layout.conns)}
+	start := time.Now()
+
+	var interval time.Duration
+	if connectRate > 0 {
+		interval = time.Second / time.Duration(connectRate)
+	}
+	results := make(chan bool, len(s.layout.conns))
+	for i, spec := range s.layout.conns {
+		if ctx.Err() != nil {
+			results <- false
+			continue
+		}
+		s.wg.Add(1)
+		go s.subscribe(spec, results)
+		if interval > 0 {
+			// 按目标速率排布，而不是每次固定睡眠，避免累计误差
+			time.Sleep(time.Until(start.Add(time.Duration(i+1) * interval)))
+		}
+	}
+	for range s.layout.conns {
+		if <-results {
+			stats.Established++
+		} else {
+			stats.Failed++
+		}
+	}
+	stats.Seconds = time.Since(start).Seconds()
+	return stats
+}
+
+// subscribe 建立单个连接并持续读取，直到 close 或被服务端断开
+func (s *subscribers) subscribe(spec connSpec, established chan<- bool) {
+	defer s.wg.Done()
+
+	q := url.Values{}
+	q.Set("topics", strings.Join(spec.topics, ","))
+	q.Set("userId", strconv.FormatInt(spec.userId, 10))
+	q.Set("clientType", spec.clientType)
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, s.base+"/sse?"+q.Encode(), nil)
+	if err != nil {
+		established <- false
+		return
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		established <- false
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		established <- false
+		return
+	}
+	established <- true
+
+	p := client.NewParser(resp.Body)
+	for {
+		ev, err := p.Next()
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.lost.Add(1)
+			}
+			return
+		}
+		// 压测消息以发布时的纳秒时间戳开头，心跳等其他数据直接忽略
+		sent, ok := parseStamp(ev.Data)
+		if !ok {
+			continue
+		}
+		s.delivered.Add(1)
+		s.latency.observe(time.Since(time.Unix(0, sent)))
+	}
+}
+
+// close 断开全部连接并等待读取协程退出
+func (s *subscribers) close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func parseStamp(data string) (int64, bool) {
+	stamp, _, ok := strings.Cut(data, " ")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(stamp, 10, 64)
+	return n, err == nil
+}
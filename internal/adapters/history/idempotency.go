@@ -0,0 +1,99 @@
+package history
+
+import (
+	"container/list"
+	"sse/internal/ports"
+	"sync"
+	"time"
+)
+
+// MemoryIdempotency 进程内的 Idempotency 实现，记录保留 window，最多 maxKeys 条，超出时丢弃最早的
+type MemoryIdempotency struct {
+	mu sync.Mutex
+	// 按占用时间排序，最早的在前；窗口相同，最早占用的也最先过期
+	order *list.List
+	keys  map[string]*list.Element
+
+	window  time.Duration
+	maxKeys int
+	now     func() time.Time
+}
+
+type claim struct {
+	key string
+	at  time.Time
+	// 首次发布的结果，nil 表示尚未完成
+	res *ports.Published
+}
+
+// NewMemoryIdempotency 创建内存幂等键记录
+// window: 幂等键的有效期
+// maxKeys: 最多保留的键数，<=0 表示不限制
+func NewMemoryIdempotency(window time.Duration, maxKeys int) *MemoryIdempotency {
+	return &MemoryIdempotency{
+		order:   list.New(),
+		keys:    make(map[string]*list.Element),
+		window:  window,
+		maxKeys: maxKeys,
+		now:     time.Now,
+	}
+}
+
+func idempotencyKey(tenant, key string) string {
+	return tenant + "\x00" + key
+}
+
+// expire 删除超过窗口的记录，调用方需持有 mu
+func (m *MemoryIdempotency) expire(now time.Time) {
+	cutoff := now.Add(-m.window)
+	for e := m.order.Front(); e != nil && !e.Value.(*claim).at.After(cutoff); e = m.order.Front() {
+		m.remove(e)
+	}
+}
+
+// remove 删除一条记录，调用方需持有 mu
+func (m *MemoryIdempotency) remove(e *list.Element) {
+	delete(m.keys, e.Value.(*claim).key)
+	m.order.Remove(e)
+}
+
+func (m *MemoryIdempotency) Claim(tenant, key string) (*ports.Published, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.expire(now)
+	k := idempotencyKey(tenant, key)
+	if e, ok := m.keys[k]; ok {
+		if res := e.Value.(*claim).res; res != nil {
+			cp := *res
+			return &cp, false, nil
+		}
+		return nil, false, nil
+	}
+	m.keys[k] = m.order.PushBack(&claim{key: k, at: now})
+	// 超出条数时丢弃最早的
+	for m.maxKeys > 0 && len(m.keys) > m.maxKeys {
+		m.remove(m.order.Front())
+	}
+	return nil, true, nil
+}
+
+func (m *MemoryIdempotency) Complete(tenant, key string, res ports.Published) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.keys[idempotencyKey(tenant, key)]; ok {
+		e.Value.(*claim).res = &res
+	}
+}
+
+func (m *MemoryIdempotency) Release(tenant, key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 已完成的记录保留到窗口结束
+	if e, ok := m.keys[idempotencyKey(tenant, key)]; ok && e.Value.(*claim).res == nil {
+		m.remove(e)
+	}
+}
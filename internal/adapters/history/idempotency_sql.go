@@ -0,0 +1,191 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sse/internal/ports"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// sqlTimeout 单次幂等键读写的超时
+const sqlTimeout = 5 * time.Second
+
+// purgeEvery 清理整张表中过期记录的间隔
+const purgeEvery = time.Minute
+
+// idempotencyQueries 按方言改写后的语句
+type idempotencyQueries struct {
+	create string
+	// 键已存在时不插入，RowsAffected 为 0
+	claim    string
+	get      string
+	expire   string
+	complete string
+	release  string
+	purge    string
+}
+
+func newIdempotencyQueries(kind string) (idempotencyQueries, error) {
+	q := idempotencyQueries{
+		create: `CREATE TABLE IF NOT EXISTS sse_idempotency (
+	tenant VARCHAR(255) NOT NULL,
+	idem_key VARCHAR(255) NOT NULL,
+	claimed_at BIGINT NOT NULL,
+	result TEXT NULL,
+	PRIMARY KEY (tenant, idem_key)
+)`,
+		get:      `SELECT claimed_at, result FROM sse_idempotency WHERE tenant = ? AND idem_key = ?`,
+		expire:   `DELETE FROM sse_idempotency WHERE tenant = ? AND idem_key = ? AND claimed_at <= ?`,
+		complete: `UPDATE sse_idempotency SET result = ? WHERE tenant = ? AND idem_key = ?`,
+		release:  `DELETE FROM sse_idempotency WHERE tenant = ? AND idem_key = ? AND result IS NULL`,
+		purge:    `DELETE FROM sse_idempotency WHERE claimed_at <= ?`,
+	}
+	switch kind {
+	case "mysql":
+		q.claim = `INSERT IGNORE INTO sse_idempotency (tenant, idem_key, claimed_at) VALUES (?, ?, ?)`
+	case "postgres":
+		q.claim = `INSERT INTO sse_idempotency (tenant, idem_key, claimed_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+		for _, s := range []*string{&q.claim, &q.get, &q.expire, &q.complete, &q.release, &q.purge} {
+			*s = numbered(*s)
+		}
+	default:
+		return q, fmt.Errorf("不支持的持久化类型 %q", kind)
+	}
+	return q, nil
+}
+
+// numbered 把 ? 占位符改写为 postgres 的 $1、$2…
+func numbered(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// SQLIdempotency 保存在 mysql / postgres 中的 Idempotency 实现，多个实例共享同一张表 sse_idempotency。
+// 记录保留 window，不限制条数；Claim 时数据库出错返回 ports.ErrIdempotencyUnavailable，不投递
+type SQLIdempotency struct {
+	db     *sql.DB
+	q      idempotencyQueries
+	window time.Duration
+	log    *slog.Logger
+	now    func() time.Time
+	// 上次清理过期记录的时间，UnixNano
+	purged atomic.Int64
+}
+
+// NewSQLIdempotency 使用 db 保存幂等键，表不存在时创建
+// kind: mysql / postgres，决定 SQL 方言
+// window: 幂等键的有效期
+func NewSQLIdempotency(db *sql.DB, kind string, window time.Duration, log *slog.Logger) (*SQLIdempotency, error) {
+	q, err := newIdempotencyQueries(kind)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, q.create); err != nil {
+		return nil, fmt.Errorf("创建幂等键表失败: %w", err)
+	}
+	return &SQLIdempotency{db: db, q: q, window: window, log: log, now: time.Now}, nil
+}
+
+func (s *SQLIdempotency) Claim(tenant, key string) (*ports.Published, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	now := s.now()
+	cutoff := now.Add(-s.window).UnixMilli()
+	s.purge(ctx, now, cutoff)
+	// 已有的记录过期或在查询前被释放时重新占用，最多尝试 3 次
+	for range 3 {
+		res, err := s.db.ExecContext(ctx, s.q.claim, tenant, key, now.UnixMilli())
+		if err != nil {
+			return s.failed("占用", tenant, key, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return s.failed("占用", tenant, key, err)
+		} else if n == 1 {
+			return nil, true, nil
+		}
+
+		var claimedAt int64
+		var result sql.NullString
+		err = s.db.QueryRowContext(ctx, s.q.get, tenant, key).Scan(&claimedAt, &result)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return s.failed("查询", tenant, key, err)
+		}
+		if claimedAt <= cutoff {
+			if _, err := s.db.ExecContext(ctx, s.q.expire, tenant, key, cutoff); err != nil {
+				return s.failed("清理", tenant, key, err)
+			}
+			continue
+		}
+		if !result.Valid {
+			return nil, false, nil
+		}
+		var prev ports.Published
+		if err := json.Unmarshal([]byte(result.String), &prev); err != nil {
+			return s.failed("解析", tenant, key, err)
+		}
+		return &prev, false, nil
+	}
+	return nil, false, nil
+}
+
+func (s *SQLIdempotency) Complete(tenant, key string, res ports.Published) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		s.log.Warn("记录幂等键的发布结果失败", "tenant", tenant, "key", key, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, s.q.complete, string(data), tenant, key); err != nil {
+		// 记录保持未完成，窗口内的重复请求收到 ErrInProgress
+		s.log.Warn("记录幂等键的发布结果失败", "tenant", tenant, "key", key, "err", err)
+	}
+}
+
+func (s *SQLIdempotency) Release(tenant, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, s.q.release, tenant, key); err != nil {
+		// 占用保留到窗口结束，期间的重试收到 ErrInProgress
+		s.log.Warn("释放幂等键失败", "tenant", tenant, "key", key, "err", err)
+	}
+}
+
+// failed 记录数据库错误，返回 ports.ErrIdempotencyUnavailable；错误详情只写入日志
+func (s *SQLIdempotency) failed(op, tenant, key string, err error) (*ports.Published, bool, error) {
+	s.log.Warn(op+"幂等键失败", "tenant", tenant, "key", key, "err", err)
+	return nil, false, ports.ErrIdempotencyUnavailable
+}
+
+// purge 每隔 purgeEvery 删除整张表中过期的记录，多个实例各自清理
+func (s *SQLIdempotency) purge(ctx context.Context, now time.Time, cutoff int64) {
+	last := s.purged.Load()
+	if now.UnixNano()-last < int64(purgeEvery) || !s.purged.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, s.q.purge, cutoff); err != nil {
+		s.log.Warn("清理过期幂等键失败", "err", err)
+	}
+}
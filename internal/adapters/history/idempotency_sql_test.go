@@ -0,0 +1,298 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"sse/internal/ports"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB 内存中的 sse_idempotency 表，按语句文本识别 SQLIdempotency 发出的几种语句
+type fakeDB struct {
+	mu      sync.Mutex
+	rows    map[[2]string]*fakeRow
+	queries []string
+	// 非 nil 时所有语句返回该错误
+	err error
+}
+
+type fakeRow struct {
+	claimedAt int64
+	result    *string
+}
+
+var (
+	fakeMu  sync.Mutex
+	fakeDBs = map[string]*fakeDB{}
+)
+
+func init() {
+	sql.Register("fakeidem", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	return &fakeConn{db: fakeDBs[dsn]}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("不支持 Prepare") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("不支持事务") }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query, values(args))
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, values(args))
+}
+
+func values(args []driver.NamedValue) []any {
+	out := make([]any, len(args))
+	for i, a := range args {
+		out[i] = a.Value
+	}
+	return out
+}
+
+var placeholder = regexp.MustCompile(`\$\d+`)
+
+// normalize 记录语句并把 postgres 的 $n 统一成 ?
+func (db *fakeDB) normalize(query string) string {
+	db.queries = append(db.queries, query)
+	return placeholder.ReplaceAllString(query, "?")
+}
+
+func (db *fakeDB) exec(query string, args []any) (driver.Result, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	q := db.normalize(query)
+	if db.err != nil {
+		return nil, db.err
+	}
+	key := func() [2]string { return [2]string{args[0].(string), args[1].(string)} }
+	switch {
+	case strings.HasPrefix(q, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(q, "INSERT"):
+		k := [2]string{args[0].(string), args[1].(string)}
+		if _, ok := db.rows[k]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		db.rows[k] = &fakeRow{claimedAt: args[2].(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(q, "UPDATE"):
+		s := args[0].(string)
+		if r, ok := db.rows[[2]string{args[1].(string), args[2].(string)}]; ok {
+			r.result = &s
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasSuffix(q, "result IS NULL"):
+		if r, ok := db.rows[key()]; ok && r.result == nil {
+			delete(db.rows, key())
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(q, "DELETE FROM sse_idempotency WHERE tenant"):
+		if r, ok := db.rows[key()]; ok && r.claimedAt <= args[2].(int64) {
+			delete(db.rows, key())
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(q, "DELETE FROM sse_idempotency WHERE claimed_at"):
+		var n int64
+		for k, r := range db.rows {
+			if r.claimedAt <= args[0].(int64) {
+				delete(db.rows, k)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return nil, errors.New("未知语句: " + query)
+}
+
+func (db *fakeDB) query(query string, args []any) (driver.Rows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	q := db.normalize(query)
+	if db.err != nil {
+		return nil, db.err
+	}
+	if !strings.HasPrefix(q, "SELECT claimed_at, result") {
+		return nil, errors.New("未知语句: " + query)
+	}
+	rows := &fakeRows{}
+	if r, ok := db.rows[[2]string{args[0].(string), args[1].(string)}]; ok {
+		var result driver.Value
+		if r.result != nil {
+			result = *r.result
+		}
+		rows.values = [][]driver.Value{{r.claimedAt, result}}
+	}
+	return rows, nil
+}
+
+type fakeRows struct{ values [][]driver.Value }
+
+func (r *fakeRows) Columns() []string { return []string{"claimed_at", "result"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newFakeSQLIdempotency 每个测试使用独立的 fakeDB
+func newFakeSQLIdempotency(t *testing.T, kind string, window time.Duration) (*SQLIdempotency, *fakeDB, *stepClock) {
+	t.Helper()
+	db := &fakeDB{rows: map[[2]string]*fakeRow{}}
+	fakeMu.Lock()
+	fakeDBs[t.Name()] = db
+	fakeMu.Unlock()
+
+	conn, err := sql.Open("fakeidem", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	s, err := NewSQLIdempotency(conn, kind, window, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newStepClock()
+	s.now = clock.now
+	return s, db, clock
+}
+
+func TestNumbered(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"SELECT 1", "SELECT 1"},
+		{"a = ?", "a = $1"},
+		{"a = ? AND b = ? AND c <= ?", "a = $1 AND b = $2 AND c <= $3"},
+		{"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"},
+	}
+	for _, tt := range tests {
+		if got := numbered(tt.in); got != tt.want {
+			t.Errorf("numbered(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIdempotencyQueries(t *testing.T) {
+	my, err := newIdempotencyQueries("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(my.claim, "INSERT IGNORE INTO sse_idempotency") || strings.Contains(my.claim, "ON CONFLICT") {
+		t.Fatalf("mysql 占用语句 %q", my.claim)
+	}
+	pg, err := newIdempotencyQueries("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(pg.claim, "IGNORE") || !strings.HasSuffix(pg.claim, "VALUES ($1, $2, $3) ON CONFLICT DO NOTHING") {
+		t.Fatalf("postgres 占用语句 %q", pg.claim)
+	}
+	for _, q := range []string{my.claim, my.get, my.expire, my.complete, my.release, my.purge} {
+		if strings.Contains(q, "$") {
+			t.Errorf("mysql 语句使用了 $ 占位符: %q", q)
+		}
+	}
+	for _, q := range []string{pg.claim, pg.get, pg.expire, pg.complete, pg.release, pg.purge} {
+		if strings.Contains(q, "?") {
+			t.Errorf("postgres 语句仍有 ? 占位符: %q", q)
+		}
+	}
+	if pg.complete != "UPDATE sse_idempotency SET result = $1 WHERE tenant = $2 AND idem_key = $3" {
+		t.Errorf("postgres 更新语句 %q", pg.complete)
+	}
+	if _, err := newIdempotencyQueries("sqlite"); err == nil {
+		t.Fatal("不支持的类型应返回错误")
+	}
+}
+
+func TestSQLIdempotency(t *testing.T) {
+	for _, kind := range []string{"mysql", "postgres"} {
+		t.Run(kind, func(t *testing.T) {
+			s, db, clock := newFakeSQLIdempotency(t, kind, time.Minute)
+			testIdempotency(t, s, clock, time.Minute)
+
+			// 实际发出的是对应方言的语句
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			if !strings.HasPrefix(db.queries[0], "CREATE TABLE IF NOT EXISTS sse_idempotency") {
+				t.Fatalf("首条语句 %q", db.queries[0])
+			}
+			var claims int
+			for _, q := range db.queries {
+				if !strings.HasPrefix(q, "INSERT") {
+					continue
+				}
+				claims++
+				if q != s.q.claim {
+					t.Fatalf("占用语句 %q", q)
+				}
+			}
+			if claims == 0 {
+				t.Fatal("没有发出占用语句")
+			}
+		})
+	}
+}
+
+func TestSQLIdempotencyPurge(t *testing.T) {
+	s, db, clock := newFakeSQLIdempotency(t, "mysql", time.Minute)
+	if _, claimed, _ := s.Claim("acme", "old"); !claimed {
+		t.Fatal("占用失败")
+	}
+	// 超过 purgeEvery 后的下一次占用清理整张表中过期的记录
+	clock.t = clock.t.Add(purgeEvery + time.Minute)
+	if _, claimed, _ := s.Claim("acme", "new"); !claimed {
+		t.Fatal("占用失败")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.rows[[2]string{"acme", "old"}]; ok {
+		t.Fatal("过期记录未清理")
+	}
+	if len(db.rows) != 1 {
+		t.Fatalf("剩余 %d 条", len(db.rows))
+	}
+}
+
+func TestSQLIdempotencyUnavailable(t *testing.T) {
+	s, db, _ := newFakeSQLIdempotency(t, "postgres", time.Minute)
+	db.mu.Lock()
+	db.err = errors.New("connection refused")
+	db.mu.Unlock()
+
+	prev, claimed, err := s.Claim("acme", "k")
+	if !errors.Is(err, ports.ErrIdempotencyUnavailable) || claimed || prev != nil {
+		t.Fatalf("数据库出错时返回 %v %v %v", prev, claimed, err)
+	}
+	// 数据库错误的详情不返回给调用方
+	if strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("错误 %v 含数据库详情", err)
+	}
+	// Complete / Release 出错只记录日志
+	s.Complete("acme", "k", ports.Published{})
+	s.Release("acme", "k")
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"sse/internal/ports"
+	"testing"
+	"time"
+)
+
+// stepClock 手动推进的时间，供 now 字段使用
+type stepClock struct{ t time.Time }
+
+func (c *stepClock) now() time.Time { return c.t }
+
+func newStepClock() *stepClock {
+	return &stepClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// testIdempotency 内存与数据库两种实现共用的测试
+func testIdempotency(t *testing.T, store ports.Idempotency, clock *stepClock, window time.Duration) {
+	t.Helper()
+	claim := func(tenant, key string) (*ports.Published, bool) {
+		t.Helper()
+		prev, claimed, err := store.Claim(tenant, key)
+		if err != nil {
+			t.Fatalf("Claim(%s, %s): %v", tenant, key, err)
+		}
+		return prev, claimed
+	}
+
+	if prev, claimed := claim("acme", "k"); !claimed || prev != nil {
+		t.Fatalf("首次占用返回 %v %v", prev, claimed)
+	}
+	// 首次发布尚未完成
+	if prev, claimed := claim("acme", "k"); claimed || prev != nil {
+		t.Fatalf("未完成时返回 %v %v", prev, claimed)
+	}
+	// 不同租户的同名键互不影响
+	if _, claimed := claim("other", "k"); !claimed {
+		t.Fatal("其他租户的同名键应能占用")
+	}
+
+	res := ports.Published{EventID: "e1", Matched: 3}
+	store.Complete("acme", "k", res)
+	prev, claimed := claim("acme", "k")
+	if claimed || prev == nil || *prev != res {
+		t.Fatalf("窗口内重复返回 %+v %v，期望 %+v", prev, claimed, res)
+	}
+	// 返回的是副本
+	prev.Duplicate = true
+	if again, _ := claim("acme", "k"); again.Duplicate {
+		t.Fatal("修改返回值影响了记录")
+	}
+	// 已完成的键不能释放
+	store.Release("acme", "k")
+	if _, claimed := claim("acme", "k"); claimed {
+		t.Fatal("已完成的键被释放")
+	}
+
+	// 发布失败后释放，重试可以重新占用
+	claim("acme", "failed")
+	store.Release("acme", "failed")
+	if _, claimed := claim("acme", "failed"); !claimed {
+		t.Fatal("释放后应能重新占用")
+	}
+
+	// 窗口结束前仍重复，结束后重新占用
+	clock.t = clock.t.Add(window - time.Millisecond)
+	if _, claimed := claim("acme", "k"); claimed {
+		t.Fatal("窗口内被重新占用")
+	}
+	clock.t = clock.t.Add(time.Millisecond)
+	if prev, claimed := claim("acme", "k"); !claimed || prev != nil {
+		t.Fatalf("窗口结束后返回 %v %v", prev, claimed)
+	}
+}
+
+func TestMemoryIdempotency(t *testing.T) {
+	clock := newStepClock()
+	m := NewMemoryIdempotency(time.Minute, 0)
+	m.now = clock.now
+	testIdempotency(t, m, clock, time.Minute)
+}
+
+func TestMemoryIdempotencyMaxKeys(t *testing.T) {
+	clock := newStepClock()
+	m := NewMemoryIdempotency(time.Minute, 2)
+	m.now = clock.now
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, claimed, _ := m.Claim("acme", key); !claimed {
+			t.Fatalf("占用 %s 失败", key)
+		}
+		clock.t = clock.t.Add(time.Second)
+	}
+	// 超出上限时丢弃最早的 a，b 与 c 仍在
+	if _, claimed, _ := m.Claim("acme", "b"); claimed {
+		t.Fatal("b 不应被丢弃")
+	}
+	if _, claimed, _ := m.Claim("acme", "c"); claimed {
+		t.Fatal("c 不应被丢弃")
+	}
+	if _, claimed, _ := m.Claim("acme", "a"); !claimed {
+		t.Fatal("最早的 a 应已被丢弃")
+	}
+	// 重新占用的 a 最新，再丢弃的是 b
+	if _, claimed, _ := m.Claim("acme", "b"); !claimed {
+		t.Fatal("b 应已被丢弃")
+	}
+	if len(m.keys) != 2 {
+		t.Fatalf("保留 %d 个键", len(m.keys))
+	}
+}
+
+func TestMemoryIdempotencyExpireOrder(t *testing.T) {
+	clock := newStepClock()
+	m := NewMemoryIdempotency(time.Minute, 0)
+	m.now = clock.now
+
+	m.Claim("acme", "a")
+	clock.t = clock.t.Add(30 * time.Second)
+	m.Claim("acme", "b")
+	clock.t = clock.t.Add(30 * time.Second)
+	// a 已过窗口被清理，b 还在
+	if _, claimed, err := m.Claim("acme", "c"); !claimed || err != nil {
+		t.Fatal("占用 c 失败")
+	}
+	if _, ok := m.keys[idempotencyKey("acme", "a")]; ok {
+		t.Fatal("过期的 a 未清理")
+	}
+	if _, claimed, _ := m.Claim("acme", "b"); claimed {
+		t.Fatal("b 仍在窗口内")
+	}
+}
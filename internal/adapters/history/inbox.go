@@ -0,0 +1,114 @@
+package history
+
+import (
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryInbox 进程内的 Inbox 实现，每个用户保留最近 maxItems 条且不超过 maxAge
+type MemoryInbox struct {
+	mu    sync.Mutex
+	seq   uint64
+	items map[string][]ports.InboxItem
+
+	maxItems int
+	maxAge   time.Duration
+	now      func() time.Time
+}
+
+// NewMemoryInbox 创建内存 Inbox
+// maxItems: 每个用户的保留条数，<=0 表示不限制
+// maxAge: 未确认消息的保留时长，<=0 表示不限制
+func NewMemoryInbox(maxItems int, maxAge time.Duration) *MemoryInbox {
+	return &MemoryInbox{
+		items:    make(map[string][]ports.InboxItem),
+		maxItems: maxItems,
+		maxAge:   maxAge,
+		now:      time.Now,
+	}
+}
+
+func inboxKey(tenant string, userId int64) string {
+	return tenant + "\x00" + strconv.FormatInt(userId, 10)
+}
+
+// expire 裁掉超过 maxAge 与已过期的条目，调用方需持有 mu
+func (m *MemoryInbox) expire(list []ports.InboxItem) []ports.InboxItem {
+	now := m.now()
+	if m.maxAge > 0 {
+		cutoff := now.Add(-m.maxAge)
+		i := 0
+		for i < len(list) && !list[i].Time.After(cutoff) {
+			i++
+		}
+		list = list[i:]
+	}
+	n := len(list)
+	list = slices.DeleteFunc(list, func(item ports.InboxItem) bool { return ports.Expired(item.Expiry(), now) })
+	if expired := n - len(list); expired > 0 {
+		metrics.MessagesExpired.With(metrics.ExpiredInbox).Add(float64(expired))
+	}
+	return list
+}
+
+// set 保存用户的列表，为空时删除键，调用方需持有 mu
+func (m *MemoryInbox) set(k string, list []ports.InboxItem) {
+	if len(list) == 0 {
+		delete(m.items, k)
+		return
+	}
+	m.items[k] = list
+}
+
+func (m *MemoryInbox) Put(tenant string, userId int64, clientType, message string, expiresAt time.Time) ports.InboxItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	item := ports.InboxItem{ID: m.seq, ClientType: clientType, Message: message, Time: m.now(), ExpiresAt: ports.Expiry(expiresAt)}
+	k := inboxKey(tenant, userId)
+	list := append(m.items[k], item)
+	// 超出条数时丢弃最旧的
+	if m.maxItems > 0 && len(list) > m.maxItems {
+		list = list[len(list)-m.maxItems:]
+	}
+	m.set(k, m.expire(list))
+	return item
+}
+
+func (m *MemoryInbox) List(tenant string, userId int64) []ports.InboxItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := inboxKey(tenant, userId)
+	list := m.expire(m.items[k])
+	m.set(k, list)
+	// 返回副本，调用方可以安全持有
+	return append([]ports.InboxItem{}, list...)
+}
+
+func (m *MemoryInbox) Ack(tenant string, userId int64, ids []uint64) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := inboxKey(tenant, userId)
+	list := m.items[k]
+	n := len(list)
+	list = slices.DeleteFunc(list, func(item ports.InboxItem) bool { return slices.Contains(ids, item.ID) })
+	m.set(k, list)
+	return n - len(list)
+}
+
+func (m *MemoryInbox) Clear(tenant string, userId int64) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := inboxKey(tenant, userId)
+	n := len(m.items[k])
+	delete(m.items, k)
+	return n
+}
@@ -0,0 +1,32 @@
+package history
+
+import "sse/internal/ports"
+
+// inboxHub 用户的新连接注册后补发其收件箱中未确认的消息，其余方法透传
+type inboxHub struct {
+	ports.Hub
+	tenant string
+	inbox  ports.Inbox
+}
+
+// Inboxed 包装租户的 Hub，使用户连接时收到收件箱中的积压消息
+func Inboxed(hub ports.Hub, tenant string, inbox ports.Inbox) ports.Hub {
+	return &inboxHub{Hub: hub, tenant: tenant, inbox: inbox}
+}
+
+func (h *inboxHub) NewClient(userId int64, clientType string, topics []string, filter string, transport string) (*ports.Client, error) {
+	c, err := h.Hub.NewClient(userId, clientType, topics, filter, transport)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range h.inbox.List(h.tenant, userId) {
+		if item.ClientType != "" && item.ClientType != clientType {
+			continue
+		}
+		// 通道已满时停止，剩余的留在收件箱等下次连接
+		if !h.Hub.Send(c.ID, item.Payload(), item.Expiry()) {
+			break
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,90 @@
+package history
+
+import (
+	"sort"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// Memory 进程内的 History 实现，每个 topic 保留最近 maxlen 条且不超过 retention
+type Memory struct {
+	mu      sync.RWMutex
+	seq     uint64
+	records map[string][]ports.Record
+
+	maxlen    int
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewMemory 创建内存 History
+// maxlen: 每个 topic 的保留条数，<=0 表示不限制
+// retention: 保留时长，<=0 表示不限制
+func NewMemory(maxlen int, retention time.Duration) *Memory {
+	return &Memory{
+		records:   make(map[string][]ports.Record),
+		maxlen:    maxlen,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+func key(tenant, topic string) string {
+	return tenant + "\x00" + topic
+}
+
+func (m *Memory) Append(tenant, topic string, payload []byte, expiresAt time.Time) ports.Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	rec := ports.Record{ID: m.seq, Topic: topic, Message: string(payload), Time: m.now(), ExpiresAt: ports.Expiry(expiresAt)}
+	k := key(tenant, topic)
+	list := append(m.records[k], rec)
+
+	// 先按条数裁剪，再裁掉过期的记录
+	if m.maxlen > 0 && len(list) > m.maxlen {
+		list = list[len(list)-m.maxlen:]
+	}
+	if m.retention > 0 {
+		cutoff := rec.Time.Add(-m.retention)
+		i := sort.Search(len(list), func(i int) bool { return list[i].Time.After(cutoff) })
+		list = list[i:]
+	}
+	m.records[k] = list
+	return rec
+}
+
+func (m *Memory) Range(tenant, topic string, after uint64, limit int) []ports.Record {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := m.records[key(tenant, topic)]
+	i := sort.Search(len(list), func(i int) bool { return list[i].ID > after })
+	list = list[i:]
+
+	// 过期的记录按条数与保留时长裁剪前仍在列表中，读取时跳过；返回副本，调用方可以安全持有
+	now := m.now()
+	n := len(list)
+	if limit > 0 {
+		n = min(n, limit)
+	}
+	out := make([]ports.Record, 0, n)
+	expired := 0
+	for _, rec := range list {
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+		if rec.ExpiresAt != nil && ports.Expired(*rec.ExpiresAt, now) {
+			expired++
+			continue
+		}
+		out = append(out, rec)
+	}
+	if expired > 0 {
+		metrics.MessagesExpired.With(metrics.ExpiredHistory).Add(float64(expired))
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package history
+
+import (
+	"sse/internal/ports"
+	"sse/pkg/topic"
+)
+
+// recordingHub 在广播前把命中过滤规则的 topic 消息写入 History，其余方法透传
+type recordingHub struct {
+	ports.Hub
+	tenant string
+	store  ports.History
+	topics *topic.Filter
+}
+
+// Recording 包装租户的 Hub，使其广播的消息按 topics 规则持久化
+func Recording(hub ports.Hub, tenant string, store ports.History, topics *topic.Filter) ports.Hub {
+	return &recordingHub{Hub: hub, tenant: tenant, store: store, topics: topics}
+}
+
+func (h *recordingHub) Broadcast(name string, payload []byte, opts ports.PublishOptions) ports.Published {
+	if h.topics.Allow(name) {
+		h.store.Append(h.tenant, name, payload, opts.ExpiresAt)
+	}
+	return h.Hub.Broadcast(name, payload, opts)
+}
@@ -0,0 +1,320 @@
+package hub
+
+import (
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/id"
+	"sse/pkg/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// consumerKey 确认模式下未确认事件的归属；同一用户的同类连接共享，重连后可继续补发
+type consumerKey struct {
+	userId     int64
+	clientType string
+}
+
+// inflight 一条已下发、等待确认的事件
+type inflight struct {
+	eventID string
+	// 带 eventId 的下发内容
+	payload  []byte
+	sentAt   time.Time
+	attempts int
+	// 过期后不再重发，零值表示不过期
+	expiresAt time.Time
+	// 重发时使用的优先级通道
+	lane int
+}
+
+// ackTracker 记录确认模式的未确认事件与最近事件的投递状态
+type ackTracker struct {
+	settings *Settings
+
+	mu        sync.Mutex
+	consumers map[consumerKey][]*inflight
+	status    map[string]*ports.DeliveryStatus
+	// 按发布顺序保存事件 ID，超出 StatusMax 时淘汰最旧的状态
+	order []string
+}
+
+func newAckTracker(settings *Settings) *ackTracker {
+	return &ackTracker{
+		settings:  settings,
+		consumers: make(map[consumerKey][]*inflight),
+		status:    make(map[string]*ports.DeliveryStatus),
+	}
+}
+
+// trackState 一次发布中消费者进入确认窗口的结果
+type trackState uint8
+
+const (
+	trackNone trackState = iota
+	trackAccepted
+	// 确认窗口已满，该消费者的所有连接都不下发
+	trackRejected
+)
+
+// publication 一次发布的上下文，fanout 逐个客户端调用
+type publication struct {
+	eventID string
+	payload []byte
+	target  string
+	topic   string
+	// 过期时间，零值表示不过期
+	expiresAt time.Time
+	// 优先级通道
+	lane int
+
+	matched   int
+	delivered int
+	// 确认模式的下发内容，首次遇到确认模式的客户端时生成
+	wrapped []byte
+	// 本次已跟踪的消费者及结果，同一消费者的多个连接只跟踪一次
+	tracked map[consumerKey]trackState
+
+	// 合并主题的键，空表示不合并，见 conflateKey
+	key string
+
+	// 解码后的消息，供内容过滤与合并使用，见 document
+	doc     any
+	decoded bool
+	docOK   bool
+}
+
+// begin 为新事件登记投递状态
+func (t *ackTracker) begin(payload []byte, opts ports.PublishOptions, target, topic string) *publication {
+	p := &publication{eventID: strconv.FormatInt(id.NextGlobalID(), 10), payload: payload, target: target, topic: topic,
+		expiresAt: opts.ExpiresAt, lane: laneOf(opts.Priority)}
+	_, _, _, statusMax := t.settings.ack()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status[p.eventID] = &ports.DeliveryStatus{EventID: p.eventID, Time: time.Now()}
+	t.order = append(t.order, p.eventID)
+	if over := len(t.order) - statusMax; over > 0 {
+		for _, old := range t.order[:over] {
+			delete(t.status, old)
+		}
+		t.order = t.order[over:]
+	}
+	return p
+}
+
+// finish 汇总普通连接的投递数
+func (t *ackTracker) finish(p *publication) ports.Published {
+	t.mu.Lock()
+	if st := t.status[p.eventID]; st != nil {
+		st.Matched, st.Delivered = p.matched, p.delivered
+	}
+	t.mu.Unlock()
+	return ports.Published{EventID: p.eventID, Matched: p.matched}
+}
+
+// track 把事件加入消费者的确认窗口，窗口已满时记为失败并返回 false
+func (t *ackTracker) track(p *publication, key consumerKey) bool {
+	_, _, window, _ := t.settings.ack()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.status[p.eventID]
+	if len(t.consumers[key]) >= window {
+		if st != nil {
+			st.Failed++
+		}
+		metrics.MessagesDropped.With(metrics.DropAckWindow).Inc()
+		return false
+	}
+	t.consumers[key] = append(t.consumers[key], &inflight{eventID: p.eventID, payload: p.wrapped, sentAt: time.Now(), attempts: 1, expiresAt: p.expiresAt, lane: p.lane})
+	if st != nil {
+		st.Pending++
+	}
+	return true
+}
+
+// ack 从消费者的确认窗口移除事件
+func (t *ackTracker) ack(key consumerKey, eventIDs []string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := t.consumers[key]
+	n := len(list)
+	list = slices.DeleteFunc(list, func(f *inflight) bool {
+		if !slices.Contains(eventIDs, f.eventID) {
+			return false
+		}
+		if st := t.status[f.eventID]; st != nil {
+			st.Pending--
+			st.Acked++
+		}
+		return true
+	})
+	t.set(key, list)
+	return n - len(list)
+}
+
+// set 保存消费者的确认窗口，为空时删除键，调用方需持有 mu
+func (t *ackTracker) set(key consumerKey, list []*inflight) {
+	if len(list) == 0 {
+		delete(t.consumers, key)
+		return
+	}
+	t.consumers[key] = list
+}
+
+// expire 移除已过期的事件并记为过期，调用方需持有 mu
+func (t *ackTracker) expire(key consumerKey, now time.Time) []*inflight {
+	list := slices.DeleteFunc(t.consumers[key], func(f *inflight) bool {
+		if !ports.Expired(f.expiresAt, now) {
+			return false
+		}
+		if st := t.status[f.eventID]; st != nil {
+			st.Pending--
+			st.Expired++
+		}
+		metrics.MessagesExpired.With(metrics.ExpiredRedelivery).Inc()
+		return true
+	})
+	t.set(key, list)
+	return list
+}
+
+// due 返回各消费者已超时的事件并计入重发次数；次数用尽的移除并记为失败，已过期的移除并记为过期
+func (t *ackTracker) due(now time.Time) map[consumerKey][]*inflight {
+	timeout, maxAttempts, _, _ := t.settings.ack()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[consumerKey][]*inflight)
+	for key := range t.consumers {
+		list := slices.DeleteFunc(t.expire(key, now), func(f *inflight) bool {
+			if now.Sub(f.sentAt) < timeout {
+				return false
+			}
+			// 消费者不在线时同样计数，保证未确认事件的存活时间有上限
+			if f.attempts >= maxAttempts {
+				if st := t.status[f.eventID]; st != nil {
+					st.Pending--
+					st.Failed++
+				}
+				return true
+			}
+			f.attempts++
+			f.sentAt = now
+			out[key] = append(out[key], f)
+			return false
+		})
+		t.set(key, list)
+	}
+	return out
+}
+
+// pending 返回消费者全部未确认且未过期的事件，并重新开始计时
+func (t *ackTracker) pending(key consumerKey) []inflight {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	list := t.expire(key, now)
+	out := make([]inflight, 0, len(list))
+	for _, f := range list {
+		f.sentAt = now
+		out = append(out, *f)
+	}
+	return out
+}
+
+func (t *ackTracker) get(eventID string) (ports.DeliveryStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.status[eventID]
+	if !ok {
+		return ports.DeliveryStatus{}, false
+	}
+	return *st, true
+}
+
+// fanout 投递给单个命中的客户端；确认模式的客户端收到带 eventId 的内容并进入确认窗口。
+// 调用方需持有 clientsMu 读锁
+func (h *ShardedHub) fanout(p *publication, c *client) {
+	p.matched++
+	if !c.ack.Load() {
+		if h.deliverLatest(c, p) {
+			p.delivered++
+		}
+		return
+	}
+
+	if p.wrapped == nil {
+		p.wrapped = ports.AckPayload(p.eventID, p.payload, p.expiresAt)
+	}
+	key := consumerKey{userId: c.userId, clientType: c.clientType}
+	state := p.tracked[key]
+	if state == trackNone {
+		state = trackRejected
+		if h.acks.track(p, key) {
+			state = trackAccepted
+		}
+		if p.tracked == nil {
+			p.tracked = make(map[consumerKey]trackState)
+		}
+		p.tracked[key] = state
+	}
+	if state == trackRejected {
+		return
+	}
+	// 通道已满时留在确认窗口，超时后重发
+	c.enqueue(p.wrapped, p.expiresAt, func(msg []byte) bool { return h.deliver(c, p.lane, msg, p.target, p.topic) })
+}
+
+// ackClients 消费者当前在线的确认模式连接，调用方需持有 clientsMu 读锁
+func (h *ShardedHub) ackClients(key consumerKey) []*client {
+	var out []*client
+	for _, clientID := range h.userMapping[key.userId] {
+		if c := h.clients[clientID]; c.clientType == key.clientType && c.ack.Load() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// redeliver 重发已超时的事件，由心跳驱动；调用方需持有 clientsMu 读锁
+func (h *ShardedHub) redeliver() {
+	for key, list := range h.acks.due(time.Now()) {
+		for _, c := range h.ackClients(key) {
+			for _, f := range list {
+				if !c.enqueue(f.payload, f.expiresAt, c.sender(f.lane)) {
+					break
+				}
+				metrics.MessagesRedelivered.With().Inc()
+			}
+		}
+	}
+}
+
+func (h *ShardedHub) EnableAck(c *ports.Client) {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	cl, ok := h.clients[c.ID]
+	if !ok || cl.ack.Swap(true) {
+		return
+	}
+	// 重连：补发之前连接未确认的事件，剩余的等超时重发
+	for _, f := range h.acks.pending(consumerKey{userId: cl.userId, clientType: cl.clientType}) {
+		if !cl.enqueue(f.payload, f.expiresAt, cl.sender(f.lane)) {
+			break
+		}
+		metrics.MessagesRedelivered.With().Inc()
+	}
+}
+
+func (h *ShardedHub) Ack(userId int64, clientType string, eventIDs []string) int {
+	return h.acks.ack(consumerKey{userId: userId, clientType: clientType}, eventIDs)
+}
+
+func (h *ShardedHub) DeliveryStatus(eventID string) (ports.DeliveryStatus, bool) {
+	return h.acks.get(eventID)
+}
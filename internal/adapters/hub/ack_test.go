@@ -0,0 +1,52 @@
+package hub
+
+import (
+	"io"
+	"log/slog"
+	"sse/internal/ports"
+	"testing"
+	"time"
+)
+
+// 确认窗口已满时，同一消费者的所有连接都不下发，只记一次失败
+func TestAckWindowFullSkipsAllConnections(t *testing.T) {
+	h := NewShardedHub(ports.Quota{}, &Settings{AckWindow: 1}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var conns []*ports.Client
+	for range 2 {
+		c, err := h.NewClient(1, "web", []string{"news"}, "", ports.TransportSSE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.EnableAck(c)
+		conns = append(conns, c)
+	}
+
+	first := h.Broadcast("news", []byte("a"), ports.PublishOptions{})
+	for i, c := range conns {
+		select {
+		case msg := <-c.SendCh:
+			if _, data, _ := c.Take(msg); string(data) != string(ports.AckPayload(first.EventID, []byte("a"), time.Time{})) {
+				t.Fatalf("连接 %d 收到 %s", i, data)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("连接 %d 未收到第一条事件", i)
+		}
+	}
+
+	second := h.Broadcast("news", []byte("b"), ports.PublishOptions{})
+	for i, c := range conns {
+		select {
+		case msg := <-c.SendCh:
+			_, data, _ := c.Take(msg)
+			t.Fatalf("确认窗口已满，连接 %d 仍收到 %s", i, data)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	st, ok := h.DeliveryStatus(second.EventID)
+	if !ok {
+		t.Fatal("没有投递状态")
+	}
+	if st.Matched != 2 || st.Failed != 1 || st.Pending != 0 {
+		t.Fatalf("投递状态 %+v", st)
+	}
+}
@@ -1,38 +1,70 @@
 package hub
 
 import (
+	"sse/pkg/filter"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type client struct {
 	// 连接唯一 ID（用于管理/定位/踢线）
 	id     int64
 	userId int64
-	// 写给该连接的数据通道（内部用，外部暴露只读视图）
+	// 按优先级分开的发送通道，由 pump 按权重搬到 ch
+	lanes [laneCount]chan []byte
+	// 写循环读取的通道（外部暴露只读视图），无缓冲，排队都在 lanes 中
 	ch chan []byte
 	// 关闭信号（close 后读协程退出）
 	done chan struct{}
 	// 原子布尔，表示是否已关闭（防止重复关闭）
 	closed atomic.Bool
+	// 已被判定为慢客户端、等待断开
+	evicting atomic.Bool
+	// 确认模式，见 ports.Hub.EnableAck
+	ack atomic.Bool
 
 	// 保护 topics 等内部字段
 	mu sync.RWMutex
 	// 该连接当前订阅的主题集合
-	topics     []string
+	topics []string
+	// 按订阅规则保存的内容过滤条件，没有条件的规则不在其中
+	filters    map[string]*filter.Expr
 	clientType string
+	// 传输方式，见 ports.TransportSSE 等
+	transport string
+	// 以占位消息排队的内容：合并主题的最新值、快照与带过期时间的消息
+	held placeholders
+	// 被新连接替换时最后写出的消息，在关闭前设置，pump 退出时写出
+	farewell []byte
 }
 
-// 创建客户端
-func newClient(id int64, userId int64, buf int, clientType string, topics []string) *client {
-	return &client{
+// 创建客户端，并启动按权重搬运各优先级通道的协程
+func newClient(id int64, userId int64, lanes [laneCount]Lane, clientType string, topics []string, transport string) *client {
+	c := &client{
 		id:         id,
 		userId:     userId,
-		ch:         make(chan []byte, buf),
+		ch:         make(chan []byte),
 		done:       make(chan struct{}),
 		topics:     topics,
 		clientType: clientType,
+		transport:  transport,
 	}
+	var weights [laneCount]int
+	for i, l := range lanes {
+		c.lanes[i] = make(chan []byte, l.Size)
+		weights[i] = l.Weight
+	}
+	go c.pump(weights)
+	return c
+}
+
+// enqueue 通过 send 写入消息；带过期时间时以占位消息排队，写出前已过期的不再下发
+func (c *client) enqueue(msg []byte, expiresAt time.Time, send func(msg []byte) bool) bool {
+	if expiresAt.IsZero() {
+		return send(msg)
+	}
+	return c.held.expiring(value{payload: msg, expiresAt: expiresAt}, send)
 }
 
 func (c *client) close() {
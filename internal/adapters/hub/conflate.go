@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"sse/pkg/metrics"
+	"sse/pkg/topic"
+	"strconv"
+	"strings"
+)
+
+// conflateKey 按第一条命中的规则计算合并键；没有命中、键字段缺失或不是标量时返回空，按普通消息投递
+func (s *Settings) conflateKey(p *publication) string {
+	rules := s.conflate.Load()
+	if rules == nil {
+		return ""
+	}
+	for _, r := range *rules {
+		if !topic.Match(r.Topic, p.topic) {
+			continue
+		}
+		if r.Key == "" {
+			return p.topic
+		}
+		doc, ok := p.document()
+		if !ok {
+			return ""
+		}
+		v, ok := lookup(doc, r.Key)
+		if !ok {
+			return ""
+		}
+		// 不同主题的同名键互不影响
+		return p.topic + "\x00" + v
+	}
+	return ""
+}
+
+// lookup 读取 JSON 对象中按 "." 分隔的字段，只接受字符串、数字与布尔值
+func lookup(doc any, path string) (string, bool) {
+	v := doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		v = obj[key]
+	}
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(x), true
+	}
+	return "", false
+}
+
+// deliverLatest 合并主题写入客户端的最新值，其余消息同 deliver；调用方需持有 clientsMu 读锁
+func (h *ShardedHub) deliverLatest(c *client, p *publication) bool {
+	send := func(msg []byte) bool { return h.deliver(c, p.lane, msg, p.target, p.topic) }
+	if p.key == "" {
+		return c.enqueue(p.payload, p.expiresAt, send)
+	}
+	ok, replaced := c.held.latest(p.key, value{payload: p.payload, expiresAt: p.expiresAt}, send)
+	if replaced {
+		metrics.MessagesConflated.With().Inc()
+	}
+	return ok
+}
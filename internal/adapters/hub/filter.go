@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"encoding/json"
+	"sse/pkg/filter"
+	"sse/pkg/topic"
+)
+
+// compileFilter 编译订阅的过滤表达式，空表示不过滤
+func compileFilter(src string) (*filter.Expr, error) {
+	if src == "" {
+		return nil, nil
+	}
+	return filter.Compile(src)
+}
+
+// setFilter 设置一组订阅规则的过滤条件，expr 为 nil 时清除；调用方需持有 clientsMu 写锁
+func (c *client) setFilter(topics []string, expr *filter.Expr) {
+	for _, t := range topics {
+		if expr == nil {
+			delete(c.filters, t)
+			continue
+		}
+		if c.filters == nil {
+			c.filters = make(map[string]*filter.Expr)
+		}
+		c.filters[t] = expr
+	}
+}
+
+// accepts 主题消息是否通过客户端的过滤条件：命中的订阅规则中任一条没有条件或条件成立即可。
+// 调用方需持有 clientsMu 读锁
+func (c *client) accepts(p *publication) bool {
+	if len(c.filters) == 0 {
+		return true
+	}
+	for _, pattern := range c.topics {
+		if !topic.Match(pattern, p.topic) {
+			continue
+		}
+		expr, ok := c.filters[pattern]
+		if !ok {
+			return true
+		}
+		if doc, ok := p.document(); ok && expr.Match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+// document 解码消息供过滤条件使用，同一次发布只解码一次；不是 JSON 时返回 false，带条件的订阅不会收到
+func (p *publication) document() (any, bool) {
+	if !p.decoded {
+		p.decoded = true
+		p.docOK = json.Unmarshal(p.payload, &p.doc) == nil
+	}
+	return p.doc, p.docOK
+}
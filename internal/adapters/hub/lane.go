@@ -0,0 +1,208 @@
+package hub
+
+import (
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"time"
+)
+
+// 优先级通道的下标，按优先级从高到低
+const (
+	laneHigh = iota
+	laneNormal
+	laneLow
+	laneCount
+)
+
+var laneNames = [laneCount]string{ports.PriorityHigh, ports.PriorityNormal, ports.PriorityLow}
+
+// laneOf 优先级对应的通道，未知或空的优先级使用普通通道
+func laneOf(priority string) int {
+	switch priority {
+	case ports.PriorityHigh:
+		return laneHigh
+	case ports.PriorityLow:
+		return laneLow
+	}
+	return laneNormal
+}
+
+// 通道已满时的处理方式
+const (
+	// OnFullDefault 按 Settings.DropSlowClient 决定断开客户端或丢弃当前消息
+	OnFullDefault = ""
+	// OnFullDrop 丢弃当前消息
+	OnFullDrop = "drop"
+	// OnFullDropOldest 丢弃该通道中最旧的一条，为当前消息腾出位置
+	OnFullDropOldest = "dropOldest"
+	// OnFullDisconnect 断开客户端
+	OnFullDisconnect = "disconnect"
+)
+
+// Lane 一个优先级通道的参数，零值字段使用默认值
+type Lane struct {
+	// 通道容量
+	Size int
+	// 写出权重：每一轮最多从该通道连续取 Weight 条，通道为空时让给其他通道
+	Weight int
+	// 通道已满时的处理方式，见 OnFullDrop 等
+	OnFull string
+}
+
+// Lanes 三个优先级通道的参数
+type Lanes struct {
+	High   Lane
+	Normal Lane
+	Low    Lane
+}
+
+var defaultLanes = [laneCount]Lane{
+	{Size: 64, Weight: 8},
+	{Size: 255, Weight: 4},
+	{Size: 255, Weight: 1, OnFull: OnFullDrop},
+}
+
+// lanes 返回各通道的参数，零值字段使用默认值
+func (s *Settings) lanes() [laneCount]Lane {
+	out := [laneCount]Lane{s.Lanes.High, s.Lanes.Normal, s.Lanes.Low}
+	for i := range out {
+		if out[i].Size <= 0 {
+			out[i].Size = defaultLanes[i].Size
+		}
+		if out[i].Weight <= 0 {
+			out[i].Weight = defaultLanes[i].Weight
+		}
+		if out[i].OnFull == OnFullDefault {
+			out[i].OnFull = defaultLanes[i].OnFull
+		}
+	}
+	return out
+}
+
+// pump 按权重把各优先级通道的消息搬到写循环读取的 ch，客户端关闭后关闭 ch；
+// 关闭时通道中剩余的消息不再写出，只写出 farewell
+func (c *client) pump(weights [laneCount]int) {
+	defer c.finish()
+	credits := weights
+	for {
+		msg, ok := c.next(weights, &credits)
+		if !ok {
+			return
+		}
+		select {
+		case c.ch <- msg:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// finish 写出 farewell 后关闭 ch，写循环已退出时最多等待 farewellWait
+func (c *client) finish() {
+	if c.farewell != nil {
+		select {
+		case c.ch <- c.farewell:
+		case <-time.After(farewellWait):
+		}
+	}
+	close(c.ch)
+}
+
+// next 取下一条消息：本轮还有配额的通道按优先级依次取，都为空或配额用完时开始新一轮；
+// 全部通道为空时阻塞，客户端关闭时返回 false
+func (c *client) next(weights [laneCount]int, credits *[laneCount]int) ([]byte, bool) {
+	for round := 0; round < 2; round++ {
+		for i := range c.lanes {
+			if credits[i] == 0 {
+				continue
+			}
+			select {
+			case msg := <-c.lanes[i]:
+				credits[i]--
+				return msg, true
+			default:
+			}
+		}
+		*credits = weights
+	}
+
+	var msg []byte
+	var lane int
+	select {
+	case msg = <-c.lanes[laneHigh]:
+		lane = laneHigh
+	case msg = <-c.lanes[laneNormal]:
+		lane = laneNormal
+	case msg = <-c.lanes[laneLow]:
+		lane = laneLow
+	case <-c.done:
+		return nil, false
+	}
+	credits[lane]--
+	return msg, true
+}
+
+// queued 各通道积压的消息总数
+func (c *client) queued() int {
+	n := 0
+	for _, l := range c.lanes {
+		n += len(l)
+	}
+	return n
+}
+
+// trySend 非阻塞写入，通道已满时返回 false 且不断开客户端；调用方需持有 clientsMu 读锁
+func (c *client) trySend(lane int, msg []byte) bool {
+	select {
+	case c.lanes[lane] <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// sender 写入指定通道的 trySend
+func (c *client) sender(lane int) func(msg []byte) bool {
+	return func(msg []byte) bool { return c.trySend(lane, msg) }
+}
+
+// deliver 非阻塞地把消息写入客户端的优先级通道，通道已满时按该通道的 OnFull 处理；调用方需持有 clientsMu 读锁
+func (h *ShardedHub) deliver(c *client, lane int, msg []byte, target, topic string) bool {
+	if c.trySend(lane, msg) {
+		metrics.MessagesDelivered.With(target).Inc()
+		h.deliveryLog.Debug("投递成功",
+			"clientId", c.id, "userId", c.userId, "clientType", c.clientType, "topic", topic, "target", target,
+			"priority", laneNames[lane])
+		return true
+	}
+
+	// 持有读锁时不能阻塞，通道已满按配置丢弃或断开
+	onFull := h.settings.lanes()[lane].OnFull
+	if onFull == OnFullDefault {
+		onFull = OnFullDrop
+		if h.settings.DropSlowClient.Load() {
+			onFull = OnFullDisconnect
+		}
+	}
+	metrics.MessagesDropped.With(metrics.DropQueueFull).Inc()
+	metrics.LaneDropped.With(laneNames[lane]).Inc()
+	if onFull == OnFullDropOldest {
+		select {
+		case old := <-c.lanes[lane]:
+			c.held.discard(old)
+		default:
+		}
+		if c.trySend(lane, msg) {
+			metrics.MessagesDelivered.With(target).Inc()
+			return true
+		}
+	}
+	h.deliveryLog.Warn("通道已满，丢弃消息",
+		"clientId", c.id, "userId", c.userId, "clientType", c.clientType, "topic", topic, "target", target,
+		"priority", laneNames[lane], "onFull", onFull)
+	if onFull == OnFullDisconnect && c.evicting.CompareAndSwap(false, true) {
+		// 当前持有读锁，异步获取写锁后再断开
+		go h.evict(c)
+	}
+	return false
+}
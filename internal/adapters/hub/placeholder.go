@@ -0,0 +1,160 @@
+package hub
+
+import (
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// placeholders 通道中只放占位消息、读取时才换成实际内容的消息：
+// 合并主题的最新值（同一键在通道中只占一个位置）、快照（需要带事件类型）与带过期时间的消息
+type placeholders struct {
+	mu sync.Mutex
+	// 合并键到尚未写出的最新值，每个键在通道中最多一个占位消息
+	values map[string]*slot
+	// 占位消息到实际内容，占位消息按切片地址识别，不会与业务消息混淆
+	markers map[*byte]placeholder
+}
+
+type value struct {
+	payload []byte
+	// 零值表示不过期
+	expiresAt time.Time
+}
+
+// slot 合并键的最新值及其占位消息的状态
+type slot struct {
+	value
+	// 占位消息正在写入通道，写入结束（成功或失败）后关闭；为 nil 表示已在通道中
+	adding chan struct{}
+}
+
+type placeholder struct {
+	// 合并键，非空时内容取 slot；只有 values[key] 仍是该 slot 时才属于这个占位消息
+	key   string
+	slot  *slot
+	event string
+	value
+}
+
+// init 初始化映射，调用方需持有 mu
+func (ps *placeholders) init() {
+	if ps.markers == nil {
+		ps.values = make(map[string]*slot)
+		ps.markers = make(map[*byte]placeholder)
+	}
+}
+
+// release 占位消息被读取或丢弃后释放所属的 slot，调用方需持有 mu
+func (ps *placeholders) release(p placeholder) {
+	if p.key != "" && ps.values[p.key] == p.slot {
+		delete(ps.values, p.key)
+	}
+}
+
+// add 先登记内容再通过 enqueue 放入占位消息，写入失败时撤销登记；
+// enqueue 不持有 mu，通道已满时可以通过 discard 腾出位置
+func (ps *placeholders) add(p placeholder, enqueue func(marker []byte) bool) bool {
+	marker := make([]byte, 1)
+	ps.mu.Lock()
+	ps.init()
+	ps.markers[&marker[0]] = p
+	ps.mu.Unlock()
+	if enqueue(marker) {
+		return true
+	}
+	ps.discard(marker)
+	return false
+}
+
+// latest 写入合并键的最新值；键的占位消息已在通道中时直接替换，否则放入占位消息。
+// 其他发布正在写入该键的占位消息时等待其结果：写入失败或已被读取时由本次重新放入，不会丢失本次的值。
+// replaced 表示替换了尚未写出的旧值
+func (ps *placeholders) latest(key string, v value, enqueue func(marker []byte) bool) (ok, replaced bool) {
+	for {
+		ps.mu.Lock()
+		ps.init()
+		s := ps.values[key]
+		if s == nil {
+			break
+		}
+		if s.adding == nil {
+			s.value = v
+			ps.mu.Unlock()
+			return true, true
+		}
+		// enqueue 不阻塞，等待时间很短
+		adding := s.adding
+		ps.mu.Unlock()
+		<-adding
+	}
+
+	// 持有 mu：登记 slot 与占位消息
+	s := &slot{value: v, adding: make(chan struct{})}
+	ps.values[key] = s
+	marker := make([]byte, 1)
+	p := placeholder{key: key, slot: s}
+	ps.markers[&marker[0]] = p
+	ps.mu.Unlock()
+
+	ok = enqueue(marker)
+
+	ps.mu.Lock()
+	if !ok {
+		delete(ps.markers, &marker[0])
+		ps.release(p)
+	}
+	close(s.adding)
+	s.adding = nil
+	ps.mu.Unlock()
+	return ok, false
+}
+
+// event 放入带事件类型的消息
+func (ps *placeholders) event(event string, payload []byte, enqueue func(marker []byte) bool) bool {
+	return ps.add(placeholder{event: event, value: value{payload: payload}}, enqueue)
+}
+
+// expiring 放入带过期时间的消息，写出前已过期的由 take 丢弃
+func (ps *placeholders) expiring(v value, enqueue func(marker []byte) bool) bool {
+	return ps.add(placeholder{value: v}, enqueue)
+}
+
+// discard 撤销从通道中丢弃或未能写入的占位消息，其余消息忽略
+func (ps *placeholders) discard(msg []byte) {
+	if len(msg) != 1 {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if p, ok := ps.markers[&msg[0]]; ok {
+		delete(ps.markers, &msg[0])
+		ps.release(p)
+	}
+}
+
+// take 实现 ports.Client.Resolve
+func (ps *placeholders) take(msg []byte) (string, []byte, bool) {
+	// 占位消息长度固定为 1，其余消息无需加锁
+	if len(msg) != 1 {
+		return "", msg, true
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.markers[&msg[0]]
+	if !ok {
+		return "", msg, true
+	}
+	delete(ps.markers, &msg[0])
+	v := p.value
+	if p.key != "" {
+		v = p.slot.value
+		ps.release(p)
+	}
+	if ports.Expired(v.expiresAt, time.Now()) {
+		metrics.MessagesExpired.With(metrics.ExpiredQueue).Inc()
+		return "", nil, false
+	}
+	return p.event, v.payload, true
+}
@@ -0,0 +1,83 @@
+package hub
+
+import (
+	"testing"
+	"time"
+)
+
+// 首次写入合并键的发布写入通道失败时，期间替换该键的发布不能丢失
+func TestLatestConcurrentFailedAdd(t *testing.T) {
+	var ps placeholders
+	entered, release := make(chan struct{}), make(chan struct{})
+	aDone := make(chan bool)
+	go func() {
+		ok, _ := ps.latest("k", value{payload: []byte("a")}, func([]byte) bool {
+			close(entered)
+			<-release
+			return false // 通道已满
+		})
+		aDone <- ok
+	}()
+	<-entered
+
+	ch := make(chan []byte, 1)
+	type result struct{ ok, replaced bool }
+	bDone := make(chan result)
+	go func() {
+		ok, replaced := ps.latest("k", value{payload: []byte("b")}, func(m []byte) bool {
+			ch <- m
+			return true
+		})
+		bDone <- result{ok, replaced}
+	}()
+
+	// A 的写入尚未结束，B 不能直接替换
+	select {
+	case r := <-bDone:
+		t.Fatalf("B 在 A 写入结束前返回 %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if <-aDone {
+		t.Fatal("A 应写入失败")
+	}
+	r := <-bDone
+	if !r.ok || r.replaced {
+		t.Fatalf("B 返回 %+v，期望放入自己的占位消息", r)
+	}
+	_, payload, ok := ps.take(<-ch)
+	if !ok || string(payload) != "b" {
+		t.Fatalf("读出 %q %v", payload, ok)
+	}
+}
+
+// 占位消息已在通道中时替换为最新值，读出后再写入会放入新的占位消息
+func TestLatestReplace(t *testing.T) {
+	var ps placeholders
+	ch := make(chan []byte, 4)
+	send := func(m []byte) bool { ch <- m; return true }
+
+	if ok, replaced := ps.latest("k", value{payload: []byte("1")}, send); !ok || replaced {
+		t.Fatal("首次写入应放入占位消息")
+	}
+	if ok, replaced := ps.latest("k", value{payload: []byte("2")}, send); !ok || !replaced {
+		t.Fatal("第二次写入应替换")
+	}
+	if len(ch) != 1 {
+		t.Fatalf("通道中有 %d 条", len(ch))
+	}
+	if _, payload, _ := ps.take(<-ch); string(payload) != "2" {
+		t.Fatalf("读出 %q", payload)
+	}
+	if ok, replaced := ps.latest("k", value{payload: []byte("3")}, send); !ok || replaced {
+		t.Fatal("读出后应放入新的占位消息")
+	}
+	// 丢弃占位消息后该键不再视为排队中
+	ps.discard(<-ch)
+	if ok, replaced := ps.latest("k", value{payload: []byte("4")}, send); !ok || replaced {
+		t.Fatal("丢弃后应放入新的占位消息")
+	}
+	if _, payload, _ := ps.take(<-ch); string(payload) != "4" {
+		t.Fatalf("读出 %q", payload)
+	}
+}
@@ -0,0 +1,190 @@
+package hub
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"sse/pkg/topic"
+	"strings"
+	"sync"
+	"time"
+)
+
+// presenceDebounce 返回离开事件的去抖时间，零值使用默认值
+func (s *Settings) presenceDebounce() time.Duration {
+	if s.PresenceDebounce <= 0 {
+		return 2 * time.Second
+	}
+	return s.PresenceDebounce
+}
+
+// Presence 实现 ports.Hub.Presence，与加入/离开事件一样只统计精确订阅
+func (h *ShardedHub) Presence(name string) []ports.PresenceUser {
+	if !tracked(name) {
+		return []ports.PresenceUser{}
+	}
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	byUser := make(map[int64][]*client)
+	for _, id := range h.subs.Exact(name) {
+		if c := h.clients[id]; c != nil {
+			byUser[c.userId] = append(byUser[c.userId], c)
+		}
+	}
+	out := make([]ports.PresenceUser, 0, len(byUser))
+	for userId, clients := range byUser {
+		out = append(out, presenceOf(userId, clients))
+	}
+	slices.SortFunc(out, func(a, b ports.PresenceUser) int { return cmp.Compare(a.UserId, b.UserId) })
+	return out
+}
+
+// IsOnline 实现 ports.Hub.IsOnline
+func (h *ShardedHub) IsOnline(userId int64) (ports.PresenceUser, bool) {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	clients := make([]*client, 0, len(h.userMapping[userId]))
+	for _, id := range h.userMapping[userId] {
+		if c := h.clients[id]; c != nil {
+			clients = append(clients, c)
+		}
+	}
+	if len(clients) == 0 {
+		return ports.PresenceUser{UserId: userId, ClientTypes: []string{}}, false
+	}
+	return presenceOf(userId, clients), true
+}
+
+// presenceOf 合并同一用户的多个连接
+func presenceOf(userId int64, clients []*client) ports.PresenceUser {
+	types := make([]string, 0, len(clients))
+	for _, c := range clients {
+		types = append(types, c.clientType)
+	}
+	slices.Sort(types)
+	return ports.PresenceUser{UserId: userId, Connections: len(clients), ClientTypes: slices.Compact(types)}
+}
+
+// presenceKey 用户在某个主题上的在线状态
+type presenceKey struct {
+	topic  string
+	userId int64
+}
+
+// presenceTracker 按主题与用户统计精确订阅（不含通配与系统主题）的连接数，产生加入/离开事件：
+// 用户的第一个连接订阅时发出 join，最后一个连接断开或取消订阅后等待去抖时间再发出 leave，
+// 期间重新订阅则两者都不发出，避免快速重连造成抖动
+type presenceTracker struct {
+	mu     sync.Mutex
+	counts map[presenceKey]int
+	// 连接数降为 0、等待去抖后发出 leave 的用户
+	leaving map[presenceKey]*time.Timer
+	// 待广播的事件，由 drainPresence 按产生顺序逐条广播
+	queue    []ports.PresenceEvent
+	draining bool
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{counts: make(map[presenceKey]int), leaving: make(map[presenceKey]*time.Timer)}
+}
+
+// tracked 是否统计该订阅的在线状态
+func tracked(name string) bool {
+	return !strings.HasPrefix(name, topic.SystemPrefix) && topic.ValidateTopic(name) == nil
+}
+
+// joined 记录客户端订阅了 topics，调用方需持有 clientsMu 写锁
+func (h *ShardedHub) joined(c *client, topics []string) {
+	p := h.presence
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, name := range topics {
+		if !tracked(name) {
+			continue
+		}
+		k := presenceKey{topic: name, userId: c.userId}
+		p.counts[k]++
+		if p.counts[k] > 1 {
+			continue
+		}
+		if t, ok := p.leaving[k]; ok {
+			// 去抖期内重新上线，离开与加入都不发出
+			t.Stop()
+			delete(p.leaving, k)
+			continue
+		}
+		h.emitPresence(ports.PresenceJoin, k)
+	}
+}
+
+// left 记录客户端不再订阅 topics，调用方需持有 clientsMu 写锁
+func (h *ShardedHub) left(c *client, topics []string) {
+	p := h.presence
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, name := range topics {
+		if !tracked(name) {
+			continue
+		}
+		k := presenceKey{topic: name, userId: c.userId}
+		if p.counts[k]--; p.counts[k] > 0 {
+			continue
+		}
+		delete(p.counts, k)
+		if h.settings.PresenceTopics == nil || !h.settings.PresenceTopics.Allow(k.topic) {
+			continue
+		}
+		var t *time.Timer
+		t = time.AfterFunc(h.settings.presenceDebounce(), func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			// 已被重新上线取消，或被之后的离开替换
+			if p.leaving[k] != t {
+				return
+			}
+			delete(p.leaving, k)
+			h.emitPresence(ports.PresenceLeave, k)
+		})
+		p.leaving[k] = t
+	}
+}
+
+// emitPresence 按 Settings.PresenceTopics 过滤后排队广播事件，调用方需持有 presence.mu
+func (h *ShardedHub) emitPresence(typ string, k presenceKey) {
+	p := h.presence
+	if h.settings.PresenceTopics == nil || !h.settings.PresenceTopics.Allow(k.topic) {
+		return
+	}
+	p.queue = append(p.queue, ports.PresenceEvent{Type: typ, Topic: k.topic, UserId: k.userId, Time: time.Now()})
+	metrics.PresenceEvents.With(typ).Inc()
+	if !p.draining {
+		// 调用方持有 clientsMu 写锁，广播需要在其他协程中进行
+		p.draining = true
+		go h.drainPresence()
+	}
+}
+
+// drainPresence 依次广播排队的事件，队列为空时退出
+func (h *ShardedHub) drainPresence() {
+	p := h.presence
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.draining = false
+			p.mu.Unlock()
+			return
+		}
+		ev := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		payload, _ := json.Marshal(ev)
+		h.Broadcast(ports.PresenceTopicPrefix+ev.Topic, payload, ports.PublishOptions{})
+	}
+}
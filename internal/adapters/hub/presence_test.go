@@ -0,0 +1,174 @@
+package hub
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sse/internal/ports"
+	"sse/pkg/topic"
+	"testing"
+	"time"
+)
+
+const testDebounce = 50 * time.Millisecond
+
+func newPresenceHub(t *testing.T, topics ...string) *ShardedHub {
+	t.Helper()
+	return NewShardedHub(ports.Quota{}, &Settings{
+		PresenceTopics:   topic.NewFilter(topics, nil),
+		PresenceDebounce: testDebounce,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func connect(t *testing.T, h *ShardedHub, userId int64, clientType string, topics ...string) *ports.Client {
+	t.Helper()
+	c, err := h.NewClient(userId, clientType, topics, "", ports.TransportSSE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// watch 订阅 $presence.<name> 的观察者
+func watch(t *testing.T, h *ShardedHub, name string) *ports.Client {
+	t.Helper()
+	return connect(t, h, 999, "observer", ports.PresenceTopicPrefix+name)
+}
+
+// nextPresence 在 timeout 内读出一条在线状态事件，没有时返回 false
+func nextPresence(t *testing.T, c *ports.Client, timeout time.Duration) (ports.PresenceEvent, bool) {
+	t.Helper()
+	select {
+	case msg := <-c.SendCh:
+		_, data, _ := c.Take(msg)
+		var ev ports.PresenceEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			t.Fatalf("事件 %s: %v", data, err)
+		}
+		return ev, true
+	case <-time.After(timeout):
+		return ports.PresenceEvent{}, false
+	}
+}
+
+func expectPresence(t *testing.T, c *ports.Client, typ string, userId int64) {
+	t.Helper()
+	ev, ok := nextPresence(t, c, time.Second)
+	if !ok {
+		t.Fatalf("等待 %s 超时", typ)
+	}
+	if ev.Type != typ || ev.UserId != userId || ev.Topic != "chat.1" {
+		t.Fatalf("收到 %+v，期望 %s user %d", ev, typ, userId)
+	}
+}
+
+func expectNoPresence(t *testing.T, c *ports.Client) {
+	t.Helper()
+	if ev, ok := nextPresence(t, c, 3*testDebounce); ok {
+		t.Fatalf("不应收到 %+v", ev)
+	}
+}
+
+func TestPresenceDebounce(t *testing.T) {
+	h := newPresenceHub(t, "chat.>")
+	obs := watch(t, h, "chat.1")
+
+	c := connect(t, h, 1, "web", "chat.1")
+	expectPresence(t, obs, ports.PresenceJoin, 1)
+
+	// 去抖时间内重连：离开与加入都不发出
+	h.Remove(c)
+	c = connect(t, h, 1, "web", "chat.1")
+	expectNoPresence(t, obs)
+
+	// 取消订阅同样按离开处理，去抖结束后发出 leave
+	h.Unsubscribe(c, []string{"chat.1"})
+	expectPresence(t, obs, ports.PresenceLeave, 1)
+
+	// 之后重新订阅发出新的 join
+	if err := h.Subscribe(c, []string{"chat.1"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	expectPresence(t, obs, ports.PresenceJoin, 1)
+	h.Remove(c)
+	expectPresence(t, obs, ports.PresenceLeave, 1)
+}
+
+func TestPresenceMultipleDevices(t *testing.T) {
+	h := newPresenceHub(t, "chat.>")
+	obs := watch(t, h, "chat.1")
+
+	web := connect(t, h, 1, "web", "chat.1")
+	expectPresence(t, obs, ports.PresenceJoin, 1)
+	ios := connect(t, h, 1, "ios", "chat.1")
+	web2 := connect(t, h, 1, "web", "chat.1")
+	connect(t, h, 2, "web", "chat.1")
+	expectPresence(t, obs, ports.PresenceJoin, 2)
+	// 同一用户的后续设备不再发出 join
+	expectNoPresence(t, obs)
+
+	users := h.Presence("chat.1")
+	if len(users) != 2 || users[0].UserId != 1 || users[1].UserId != 2 {
+		t.Fatalf("在线用户 %+v", users)
+	}
+	if u := users[0]; u.Connections != 3 || len(u.ClientTypes) != 2 || u.ClientTypes[0] != "ios" || u.ClientTypes[1] != "web" {
+		t.Fatalf("用户 1 为 %+v", u)
+	}
+
+	// 还有设备在线时断开其他设备不发出 leave
+	h.Remove(web)
+	h.Remove(web2)
+	expectNoPresence(t, obs)
+	if u, ok := h.IsOnline(1); !ok || u.Connections != 1 || u.ClientTypes[0] != "ios" {
+		t.Fatalf("IsOnline 返回 %+v %v", u, ok)
+	}
+	h.Remove(ios)
+	expectPresence(t, obs, ports.PresenceLeave, 1)
+	if _, ok := h.IsOnline(1); ok {
+		t.Fatal("所有设备断开后仍在线")
+	}
+}
+
+// 通配订阅既不出现在 Presence 中，也不产生加入/离开事件
+func TestPresenceExactOnly(t *testing.T) {
+	h := newPresenceHub(t, "chat.>")
+	obs := watch(t, h, "chat.1")
+
+	wild := connect(t, h, 1, "web", "chat.*", "chat.>")
+	expectNoPresence(t, obs)
+	if users := h.Presence("chat.1"); len(users) != 0 {
+		t.Fatalf("通配订阅计入了在线用户 %+v", users)
+	}
+	h.Remove(wild)
+	expectNoPresence(t, obs)
+
+	connect(t, h, 2, "web", "chat.1")
+	expectPresence(t, obs, ports.PresenceJoin, 2)
+	if users := h.Presence("chat.1"); len(users) != 1 || users[0].UserId != 2 {
+		t.Fatalf("在线用户 %+v", users)
+	}
+	if users := h.Presence("chat.*"); len(users) != 0 {
+		t.Fatalf("通配主题返回 %+v", users)
+	}
+}
+
+// 未配置在线状态主题时断开连接不启动去抖计时
+func TestPresenceDisabledNoTimer(t *testing.T) {
+	h := newPresenceHub(t)
+	obs := watch(t, h, "chat.1")
+
+	c := connect(t, h, 1, "web", "chat.1")
+	h.Remove(c)
+	h.presence.mu.Lock()
+	n := len(h.presence.leaving)
+	h.presence.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("有 %d 个去抖计时", n)
+	}
+	expectNoPresence(t, obs)
+	// 查询不受事件开关影响
+	connect(t, h, 2, "web", "chat.1")
+	if users := h.Presence("chat.1"); len(users) != 1 {
+		t.Fatalf("在线用户 %+v", users)
+	}
+}
@@ -0,0 +1,65 @@
+package hub
+
+import (
+	"sse/pkg/topic"
+	"sync/atomic"
+	"time"
+)
+
+// Settings Hub 的运行参数，所有租户的 Hub 共享同一个实例，修改后立即对全部 Hub 生效
+type Settings struct {
+	// 逐条投递日志每 N 条输出 1 条
+	SampleEvery int
+	// 通道满时断开慢客户端；为 false 时只丢弃当前消息
+	DropSlowClient atomic.Bool
+
+	// 确认模式：超过 AckTimeout 未确认的事件在下一次心跳时重发，最多 AckMaxAttempts 次
+	AckTimeout     time.Duration
+	AckMaxAttempts int
+	// 确认模式下每个用户与客户端类型最多未确认的事件数，超出的事件不再下发并记为失败
+	AckWindow int
+	// 保留投递状态的最近事件数
+	StatusMax int
+	// 每个租户最多保存的快照条数，0 表示使用默认值
+	SnapshotMax int
+	// 每个连接的优先级通道，只对之后建立的连接生效
+	Lanes Lanes
+	// 发出在线状态事件（$presence.<topic>）的主题，nil 表示不发出；
+	// 用户最后一个连接离开后等待 PresenceDebounce 再发出 leave，0 表示使用默认值
+	PresenceTopics   *topic.Filter
+	PresenceDebounce time.Duration
+
+	// 合并主题的规则，见 SetConflate
+	conflate atomic.Pointer[[]ConflateRule]
+}
+
+// ConflateRule 合并主题：命中 Topic（订阅规则语法）的消息按 Key 字段合并，
+// 同一键尚未写出的旧值被新值替换，慢客户端只收到最新状态。Key 为空时整个主题只保留最新一条
+type ConflateRule struct {
+	Topic string
+	Key   string
+}
+
+// SetConflate 替换合并主题的规则，按顺序取第一条命中的规则
+func (s *Settings) SetConflate(rules []ConflateRule) {
+	rules = append([]ConflateRule(nil), rules...)
+	s.conflate.Store(&rules)
+}
+
+// ack 返回确认相关参数，零值使用默认值
+func (s *Settings) ack() (timeout time.Duration, maxAttempts, window, statusMax int) {
+	timeout, maxAttempts, window, statusMax = s.AckTimeout, s.AckMaxAttempts, s.AckWindow, s.StatusMax
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	if window <= 0 {
+		window = 256
+	}
+	if statusMax <= 0 {
+		statusMax = 10000
+	}
+	return
+}
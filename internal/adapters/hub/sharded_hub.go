@@ -1,12 +1,15 @@
 package hub
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
 	"sse/internal/ports"
 	"sse/pkg/id"
+	"sse/pkg/logger"
+	"sse/pkg/metrics"
+	"sse/pkg/topic"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type ShardedHub struct {
@@ -21,170 +24,180 @@ type ShardedHub struct {
 	clientTyp map[string][]int64
 	// 用户映射，用户 ID 到客户端 ID
 	userMapping map[int64][]int64
+	// 订阅索引，按主题分段查找订阅了该主题（含通配）的客户端 ID
+	subs *topic.Trie[int64]
+	// 连接配额（所属租户）
+	quota ports.Quota
+	// 共享的运行参数
+	settings *Settings
+	// 日志，deliveryLog 为逐条投递使用的采样日志
+	log         *slog.Logger
+	deliveryLog *slog.Logger
+	// 确认模式与投递状态
+	acks *ackTracker
+	// 主题快照，主题到键到快照，与订阅索引一同由 clientsMu 保护
+	snapshots     map[string]map[string]ports.SnapshotEntry
+	snapshotCount int
+	// 按主题的用户在线状态，产生 $presence.<topic> 事件
+	presence *presenceTracker
 }
 
-func (h *ShardedHub) PublishByUserId(userId int64, message string) {
+func (h *ShardedHub) PublishByUserId(userId int64, message string, opts ports.PublishOptions) ports.Published {
 	// 读锁 (不写)
 	h.clientsMu.RLock()
 	defer h.clientsMu.RUnlock()
 
-	int64s := h.userMapping[userId]
-
-	for _, clients := range int64s {
-		client := h.clients[clients]
-		// 客户端写锁
-		client.mu.Lock()
-		client.mu.Unlock()
-		select {
-		case client.ch <- []byte(message): // 尝试发送消息到客户端的通道
-			fmt.Println("发送成功")
-		// 发送成功，您可以选择记录日志或执行其他操作
-		default:
-			// 如果通道已满或者客户端未准备好，则可以考虑丢弃消息或记录
-			// 这里不阻塞，如果通道关闭了，则发送将失败
-			// 可以记录客户端已断开的消息，比如用 log
-			log.Printf("Failed to send message to userId: %s, client might be disconnected", userId)
-		}
+	metrics.MessagesPublished.With(metrics.TargetUser).Inc()
+	p := h.acks.begin([]byte(message), opts, metrics.TargetUser, "")
+	for _, clientID := range h.userMapping[userId] {
+		h.fanout(p, h.clients[clientID])
 	}
-
+	return h.acks.finish(p)
 }
 
-func (h *ShardedHub) PublishByClientType(clientType string, message string) {
+func (h *ShardedHub) PublishByClientType(clientType string, message string, opts ports.PublishOptions) ports.Published {
 	// 读锁 (不写)
 	h.clientsMu.RLock()
 	defer h.clientsMu.RUnlock()
 
-	int64s := h.clientTyp[clientType]
-
-	for _, clients := range int64s {
-		client := h.clients[clients]
-		// 客户端写锁
-		client.mu.Lock()
-		client.mu.Unlock()
-		select {
-		case client.ch <- []byte(message): // 尝试发送消息到客户端的通道
-			fmt.Println("发送成功")
-			// 发送成功，您可以选择记录日志或执行其他操作
-		}
+	metrics.MessagesPublished.With(metrics.TargetClientType).Inc()
+	p := h.acks.begin([]byte(message), opts, metrics.TargetClientType, "")
+	for _, clientID := range h.clientTyp[clientType] {
+		h.fanout(p, h.clients[clientID])
 	}
-
+	return h.acks.finish(p)
 }
 
-func (h *ShardedHub) PublishToClient(clientType string, userId int64, message string) {
+func (h *ShardedHub) PublishToClient(clientType string, userId int64, message string, opts ports.PublishOptions) ports.Published {
 	// 读锁 (不写)
 	h.clientsMu.RLock()
 	defer h.clientsMu.RUnlock()
 
-	// 根据 userId 获取与用户相关联的客户端 ID 列表
-	clientIDs := h.userMapping[userId]
-
-	// 遍历客户端 ID，查找符合类型的客户端
-	for _, clientID := range clientIDs {
-		client := h.clients[clientID]
-		// 客户端写锁
-		client.mu.Lock()
-		client.mu.Unlock()
-		// 检查客户端类型
-		if client.clientType == clientType {
-
-			select {
-			case client.ch <- []byte(message): // 尝试发送消息到客户端的通道
-				fmt.Println("Message sent successfully to client:", clientID)
-			default:
-				// 发送失败，记录日志
-				log.Printf("Failed to send message to clientID: %d, client might be disconnected", clientID)
-			}
+	metrics.MessagesPublished.With(metrics.TargetClient).Inc()
+	// 根据 userId 获取与用户相关联的客户端 ID 列表，再筛选类型
+	p := h.acks.begin([]byte(message), opts, metrics.TargetClient, "")
+	for _, clientID := range h.userMapping[userId] {
+		if client := h.clients[clientID]; client.clientType == clientType {
+			h.fanout(p, client)
 		}
 	}
+	return h.acks.finish(p)
 }
+
 func (h *ShardedHub) HeaderBeat(byte []byte) {
 	// 获取读锁
 	h.clientsMu.RLock()
 	defer h.clientsMu.RUnlock()
-	log.Printf("top3: clientsSize: %d\n", len(h.clients))
+	h.log.Debug("心跳", "clients", len(h.clients))
 
+	depth := metrics.QueueDepth.With()
 	for _, client := range h.clients {
-		client.mu.Lock()
-		// 使用通道中断来确认客户端连接状态
-		if client.ch == nil {
-			log.Printf("客户端 %d 的通道已关闭，跳过发送\n", client.id)
-			client.mu.Unlock()
-			continue
-		}
-
-		select {
-		case client.ch <- byte: // 尝试发送数据
-		default: // 通道已满或关闭
-			log.Printf("客户端 %d 已关闭通道，无法发送数据\n", client.id)
+		// 借心跳顺带采样通道积压
+		depth.Observe(float64(client.queued()))
+		// 心跳走高优先级通道，不会被大量普通消息挤掉
+		if !client.trySend(laneHigh, byte) {
+			metrics.HeartbeatFailures.With().Inc()
+			h.deliveryLog.Warn("心跳写入失败，通道已满", "clientId", client.id, "userId", client.userId)
 		}
-		client.mu.Unlock()
 	}
+	// 借心跳重发确认模式下超时未确认的事件
+	h.redeliver()
 }
-func (h *ShardedHub) Broadcast(topic string, payload []byte) {
+
+func (h *ShardedHub) Broadcast(topic string, payload []byte, opts ports.PublishOptions) ports.Published {
 	// 读锁 (不写)
 	h.clientsMu.RLock()
 	defer h.clientsMu.RUnlock()
 
-	// 遍历所有客户端并发送消息
-	for userId, client := range h.clients {
-		// 客户端写锁
-		client.mu.Lock()
-		client.mu.Unlock()
-		if makeStringMap(topic, client.topics) {
-
-			select {
-			case client.ch <- []byte(payload): // 尝试发送消息到客户端的通道
-				fmt.Println("发送成功")
-			// 发送成功，您可以选择记录日志或执行其他操作
-			default:
-				// 如果通道已满或者客户端未准备好，则可以考虑丢弃消息或记录
-				// 这里不阻塞，如果通道关闭了，则发送将失败
-				// 可以记录客户端已断开的消息，比如用 log
-				log.Printf("Failed to send message to userId: %s, client might be disconnected", userId)
-			}
+	metrics.MessagesPublished.With(metrics.TargetTopic).Inc()
+	// 通过订阅索引找到命中的客户端，通配订阅无需遍历全部连接
+	p := h.acks.begin(payload, opts, metrics.TargetTopic, topic)
+	p.key = h.settings.conflateKey(p)
+	for _, clientID := range h.subs.Match(topic) {
+		if c := h.clients[clientID]; c.accepts(p) {
+			h.fanout(p, c)
 		}
 	}
+	return h.acks.finish(p)
+}
+
+// Send 补发消息给单个客户端；与 deliver 不同，通道已满时只返回 false，由调用方稍后再发
+func (h *ShardedHub) Send(clientID int64, message string, expiresAt time.Time) bool {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	c, ok := h.clients[clientID]
+	if !ok || !c.enqueue([]byte(message), expiresAt, c.sender(laneNormal)) {
+		return false
+	}
+	metrics.MessagesDelivered.With(metrics.TargetClient).Inc()
+	return true
+}
+
+// evict 断开慢客户端，写循环随通道关闭退出
+func (h *ShardedHub) evict(c *client) {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+
+	if h.clients[c.id] != c {
+		return
+	}
+	h.removeLocked(c)
+	metrics.SlowClientEvictions.With().Inc()
+	h.log.Warn("慢客户端已断开", "clientId", c.id, "userId", c.userId, "clientType", c.clientType)
+}
+
+// Kick 断开指定客户端，写循环随通道关闭退出
+func (h *ShardedHub) Kick(clientID int64) bool {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+
+	c, ok := h.clients[clientID]
+	if !ok {
+		return false
+	}
+	h.removeLocked(c)
+	h.log.Info("客户端已被踢出", "clientId", c.id, "userId", c.userId, "clientType", c.clientType)
+	return true
 }
 
 // ShardedHub 的 Remove 方法
 func (h *ShardedHub) Remove(c *ports.Client) {
-	log.Printf("客户端 %d 断开连接, Remove 关闭连接\n", c.ID)
-
 	c.Mu.Lock() // 确保安全访问
 	defer c.Mu.Unlock()
 
-	log.Printf("top1: clientsSize: %d\n", len(h.clients))
-
-	// 确保外部客户端的发送通道未关闭
+	// 外部句柄与内部 client 共用同一通道，只置空句柄；
+	// 真正的关闭在持有 clientsMu 写锁时进行，避免与发送方竞争
 	if c.SendCh != nil {
-		log.Printf("外部客户端 %d 正在关闭\n", c.ID)
-
-		close(c.SendCh) // 关闭发送通道
-		c.SendCh = nil  // 设置为 nil，避免后续操作冲突
+		c.SendCh = nil // 设置为 nil，避免后续操作冲突
 	}
 
 	// 使用写锁获取对 clientsMu 的独占访问权限
 	h.clientsMu.Lock()
 	defer h.clientsMu.Unlock()
 	// 确保检查客户机的存在性
-	if client, exists := h.clients[c.ID]; exists && client != nil {
-		log.Printf("客户端 %d 正在关闭\n", c.ID)
+	client, exists := h.clients[c.ID]
+	if !exists || client == nil {
+		// 写循环与断开检测都会调用 Remove，第二次调用走到这里是正常的
+		return
+	}
 
-		// 关闭客户端的通道
-		if client.ch != nil {
-			close(client.ch)
-			client.ch = nil // 保持一致性
-		}
+	h.removeLocked(client)
+	h.log.Info("客户端已移除",
+		"clientId", client.id, "userId", client.userId, "clientType", client.clientType, "clients", len(h.clients))
+}
 
-		log.Printf("等待客户 %d 完成清理\n", client.id)
-		client.close()          // 用户定义的 close 方法
-		delete(h.clients, c.ID) // 从 Hub 中移除
-	} else {
-		log.Printf("客户端 %d 不存在或已关闭, 无法移除\n", c.ID)
-	}
+// removeLocked 关闭通道并从所有索引中移除客户端，调用方需持有 clientsMu 写锁
+func (h *ShardedHub) removeLocked(client *client) {
+	// 关闭信号使 pump 退出并关闭写循环读取的通道
+	client.close()
 
-	log.Printf("top2: clientsSize: %d\n", len(h.clients))
+	delete(h.clients, client.id) // 从 Hub 中移除
+	h.unindex(client)
+	atomic.AddInt64(&h.totalConns, -1)
+	metrics.ActiveConnections.With(client.clientType).Dec()
 }
+
 func (h *ShardedHub) Stats() []ports.HubStats {
 	// 读锁 (不写)
 	h.clientsMu.RLock()
@@ -198,9 +211,10 @@ func (h *ShardedHub) Stats() []ports.HubStats {
 			// 检查该客户端是否在 clients 中
 			if clientInfo, exists := h.clients[clientID]; exists {
 				data = append(data, ports.HubStats{
-					ClientID: clientID,
-					UserID:   userID,
-					Type:     clientInfo.clientType, // 从客户端信息获取类型
+					ClientID:  clientID,
+					UserID:    userID,
+					Type:      clientInfo.clientType, // 从客户端信息获取类型
+					Transport: clientInfo.transport,
 				})
 			}
 		}
@@ -209,44 +223,173 @@ func (h *ShardedHub) Stats() []ports.HubStats {
 	return data
 }
 
+// 当前连接数
+func (h *ShardedHub) Conns() int64 {
+	return atomic.LoadInt64(&h.totalConns)
+}
+
 // 构建分片hub实例
-// numShards: 分片数
-func NewShardedHub() *ShardedHub {
+// quota: 所属租户的连接配额
+// settings: 各租户 Hub 共享的运行参数
+// log: 日志
+func NewShardedHub(quota ports.Quota, settings *Settings, log *slog.Logger) *ShardedHub {
 	return &ShardedHub{
 		totalConns:  0,
 		clientsMu:   sync.RWMutex{},
 		clients:     make(map[int64]*client),
 		clientTyp:   make(map[string][]int64),
 		userMapping: make(map[int64][]int64),
+		subs:        topic.NewTrie[int64](),
+		quota:       quota,
+		settings:    settings,
+		log:         log,
+		deliveryLog: logger.Sampled(log, settings.SampleEvery),
+		acks:        newAckTracker(settings),
+		snapshots:   make(map[string]map[string]ports.SnapshotEntry),
+		presence:    newPresenceTracker(),
 	}
 }
 
 // NewClient 实现 ports.Hub.NewClient，创建新的客户端并返回。
 // 参数：
-//   - buf: 客户端通道的缓冲区大小
+//   - filter: 内容过滤表达式，作用于 topics 中的全部主题，空表示不过滤
+//   - transport: 传输方式，仅用于统计
 //
 // 返回：
 //   - *ports.Client: 返回的客户端句柄供上层使用
-func (h *ShardedHub) NewClient(userId int64, clientType string, topics []string) *ports.Client {
+//   - error: 超过租户配额时返回 ports.ErrTooManyConnections / ports.ErrTooManyTopics /
+//     ports.ErrTooManyUserConnections，订阅规则不合法时返回 topic.ErrInvalidPattern，过滤表达式不合法时返回 filter.ErrInvalid
+func (h *ShardedHub) NewClient(userId int64, clientType string, topics []string, filter string, transport string) (*ports.Client, error) {
+	if h.quota.MaxTopicsPerClient > 0 && len(topics) > h.quota.MaxTopicsPerClient {
+		return nil, ports.ErrTooManyTopics
+	}
+	if err := validatePatterns(topics); err != nil {
+		return nil, err
+	}
+	expr, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
 	// 写锁
 	h.clientsMu.Lock()
 	defer h.clientsMu.Unlock()
 
+	// 先检查全部配额，都通过后才断开被替换的连接
+	replaced, err := h.userLimit(userId, clientType)
+	if err != nil {
+		return nil, err
+	}
+	if h.quota.MaxConns > 0 && len(h.clients)-len(replaced) >= h.quota.MaxConns {
+		return nil, ports.ErrTooManyConnections
+	}
+
 	globalID := id.NextGlobalID()
-	c := newClient(globalID, userId, 255, clientType, topics) // 创建 client 实例
+	for _, r := range replaced {
+		h.replaceLocked(r, globalID)
+	}
+	c := newClient(globalID, userId, h.settings.lanes(), clientType, topics, transport) // 创建 client 实例
+	c.setFilter(topics, expr)
 
 	atomic.AddInt64(&h.totalConns, 1) // 更新总连接数
+	metrics.ActiveConnections.With(clientType).Inc()
 	h.clients[globalID] = c
 	h.clientTyp[clientType] = append(h.clientTyp[clientType], globalID)
 	h.userMapping[userId] = append(h.userMapping[userId], globalID)
+	for _, t := range topics {
+		h.subs.Add(t, globalID)
+	}
+	h.joined(c, topics)
+	h.sendSnapshots(c, topics, nil)
 
-	log.Printf("添加用户:%d ,唯一ID:%d ,clientsSize:%d ,clientTyp:%d ,clientTypeSize:%d ,userMapping:%d ,userMappingSize:%d ,",
-		userId, globalID, len(h.clients), clientType, len(h.clientTyp[clientType]), userId, len(h.userMapping[userId]))
+	h.log.Info("客户端已添加",
+		"clientId", globalID, "userId", userId, "clientType", clientType, "transport", transport, "topics", topics,
+		"clients", len(h.clients))
 	// 返回上层只读的客户端句柄
 	return &ports.Client{
-		ID:     globalID,
-		SendCh: c.ch,
-		Done:   c.done, // 返回关闭信号等待通道
+		ID:      globalID,
+		SendCh:  c.ch,
+		Done:    c.done, // 返回关闭信号等待通道
+		Resolve: c.held.take,
+	}, nil
+}
+
+// Subscribe 为客户端追加订阅，已订阅的主题只更新过滤条件
+func (h *ShardedHub) Subscribe(c *ports.Client, topics []string, filter string) error {
+	if err := validatePatterns(topics); err != nil {
+		return err
+	}
+	expr, err := compileFilter(filter)
+	if err != nil {
+		return err
+	}
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+
+	client, ok := h.clients[c.ID]
+	if !ok {
+		return nil
+	}
+	merged := append([]string(nil), client.topics...)
+	for _, t := range topics {
+		if !makeStringMap(t, merged) {
+			merged = append(merged, t)
+		}
+	}
+	if h.quota.MaxTopicsPerClient > 0 && len(merged) > h.quota.MaxTopicsPerClient {
+		return ports.ErrTooManyTopics
+	}
+	previous, added := client.topics, merged[len(client.topics):]
+	for _, t := range added {
+		h.subs.Add(t, client.id)
+	}
+	h.joined(client, added)
+	// 整体替换切片，避免影响已取出旧切片的读者
+	client.topics = merged
+	client.setFilter(topics, expr)
+	h.sendSnapshots(client, added, previous)
+	return nil
+}
+
+// Unsubscribe 取消客户端的订阅
+func (h *ShardedHub) Unsubscribe(c *ports.Client, topics []string) {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+
+	client, ok := h.clients[c.ID]
+	if !ok {
+		return
+	}
+	kept := make([]string, 0, len(client.topics))
+	var removed []string
+	for _, t := range client.topics {
+		if makeStringMap(t, topics) {
+			h.subs.Remove(t, client.id)
+			delete(client.filters, t)
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	client.topics = kept
+	h.left(client, removed)
+}
+
+// unindex 从类型、用户与订阅索引中移除客户端，调用方需持有 clientsMu 写锁
+func (h *ShardedHub) unindex(c *client) {
+	for _, t := range c.topics {
+		h.subs.Remove(t, c.id)
+	}
+	h.left(c, c.topics)
+	if ids := removeValue(h.clientTyp[c.clientType], c.id); len(ids) > 0 {
+		h.clientTyp[c.clientType] = ids
+	} else {
+		delete(h.clientTyp, c.clientType)
+	}
+	if ids := removeValue(h.userMapping[c.userId], c.id); len(ids) > 0 {
+		h.userMapping[c.userId] = ids
+	} else {
+		delete(h.userMapping, c.userId)
 	}
 }
 
@@ -264,6 +407,16 @@ func removeValue(slice []int64, value int64) []int64 {
 	return newSlice // 返回的新切片
 }
 
+// validatePatterns 检查订阅规则，见 topic.ValidatePattern
+func validatePatterns(topics []string) error {
+	for _, t := range topics {
+		if err := topic.ValidatePattern(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // makeStringMap 函数接受一个字符串和一个字符串切片，返回切片中该字符串是否存在
 func makeStringMap(str string, slice []string) bool {
 	for _, v := range slice {
@@ -0,0 +1,118 @@
+package hub
+
+import (
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"sse/pkg/topic"
+	"strings"
+	"time"
+)
+
+// snapshotMax 返回快照条数上限，零值使用默认值
+func (s *Settings) snapshotMax() int {
+	if s.SnapshotMax <= 0 {
+		return 10000
+	}
+	return s.SnapshotMax
+}
+
+// SetSnapshot 持有 clientsMu 写锁：与持读锁的各发布方法互斥，而 NewClient / Subscribe 也在写锁下通过
+// sendSnapshots 下发快照，因此新订阅者总是先收到快照，再收到之后发布的消息
+func (h *ShardedHub) SetSnapshot(name, key, message string) error {
+	if err := topic.ValidateTopic(name); err != nil {
+		return err
+	}
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+
+	entries := h.snapshots[name]
+	if _, ok := entries[key]; !ok {
+		if h.snapshotCount >= h.settings.snapshotMax() {
+			return ports.ErrTooManySnapshots
+		}
+		if entries == nil {
+			entries = make(map[string]ports.SnapshotEntry)
+			h.snapshots[name] = entries
+		}
+		h.snapshotCount++
+	}
+	entries[key] = ports.SnapshotEntry{Key: key, Message: message, Time: time.Now()}
+	return nil
+}
+
+func (h *ShardedHub) ClearSnapshot(name, key string) int {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+
+	entries := h.snapshots[name]
+	removed := len(entries)
+	if key == "" {
+		delete(h.snapshots, name)
+	} else if _, ok := entries[key]; ok {
+		removed = 1
+		delete(entries, key)
+		if len(entries) == 0 {
+			delete(h.snapshots, name)
+		}
+	} else {
+		removed = 0
+	}
+	h.snapshotCount -= removed
+	return removed
+}
+
+func (h *ShardedHub) Snapshot(name string) []ports.SnapshotEntry {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+	return sortedEntries(h.snapshots[name])
+}
+
+// sortedEntries 整个主题的快照（键为空）排在最前，其余按键排序
+func sortedEntries(entries map[string]ports.SnapshotEntry) []ports.SnapshotEntry {
+	out := make([]ports.SnapshotEntry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b ports.SnapshotEntry) int { return strings.Compare(a.Key, b.Key) })
+	return out
+}
+
+// sendSnapshots 新增订阅 added 后下发命中的快照；已被 previous 中的订阅覆盖的主题之前已经下发过，跳过。
+// 快照同样经过内容过滤；通道已满时停止，剩余的不再补发。调用方需持有 clientsMu 写锁
+func (h *ShardedHub) sendSnapshots(c *client, added, previous []string) {
+	if len(h.snapshots) == 0 || len(added) == 0 {
+		return
+	}
+	names := make([]string, 0, len(h.snapshots))
+	for name := range h.snapshots {
+		if matchAny(added, name) && !matchAny(previous, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	enqueue := c.sender(laneNormal)
+	for _, name := range names {
+		for _, e := range sortedEntries(h.snapshots[name]) {
+			payload := []byte(e.Message)
+			if !c.accepts(&publication{topic: name, payload: payload}) {
+				continue
+			}
+			if !c.held.event(ports.EventSnapshot, payload, enqueue) {
+				h.deliveryLog.Warn("通道已满，停止下发快照", "clientId", c.id, "userId", c.userId, "topic", name)
+				return
+			}
+			metrics.MessagesDelivered.With(metrics.TargetSnapshot).Inc()
+		}
+	}
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if topic.Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
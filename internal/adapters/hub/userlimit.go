@@ -0,0 +1,95 @@
+package hub
+
+import (
+	"encoding/json"
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"time"
+)
+
+// farewellWait 被替换的连接写出 event: replaced 的最长等待时间，超时后直接关闭
+const farewellWait = time.Second
+
+// replacement 为新连接让位的旧连接及原因
+type replacement struct {
+	client *client
+	reason string
+}
+
+// userLimit 按租户配额检查用户的连接数，返回新连接建立前需要断开的旧连接；
+// 超过上限且策略为拒绝时返回 ports.ErrTooManyUserConnections。调用方需持有 clientsMu 写锁
+func (h *ShardedHub) userLimit(userId int64, clientType string) ([]replacement, error) {
+	q := h.quota
+	// 匿名连接共用 userId 0，不按用户限制
+	if userId == 0 {
+		return nil, nil
+	}
+	// userMapping 按建立顺序保存，最早的在前
+	existing := make([]*client, 0, len(h.userMapping[userId]))
+	for _, id := range h.userMapping[userId] {
+		if c := h.clients[id]; c != nil {
+			existing = append(existing, c)
+		}
+	}
+	sameType := func(c *client) bool { return c.clientType == clientType }
+
+	var out []replacement
+	replaced := func(c *client) bool {
+		return slices.ContainsFunc(out, func(r replacement) bool { return r.client == c })
+	}
+	if q.SingleSession {
+		for _, c := range existing {
+			if sameType(c) {
+				out = append(out, replacement{client: c, reason: metrics.ReplacedSingleSession})
+			}
+		}
+	}
+	// limit 保证加入新连接后 match 命中的连接数不超过 max
+	limit := func(max int, match func(*client) bool, reason string) error {
+		if max <= 0 {
+			return nil
+		}
+		var kept []*client
+		for _, c := range existing {
+			if match(c) && !replaced(c) {
+				kept = append(kept, c)
+			}
+		}
+		over := len(kept) + 1 - max
+		if over <= 0 {
+			return nil
+		}
+		if q.OnUserLimit != ports.UserLimitEvictOldest {
+			return ports.ErrTooManyUserConnections
+		}
+		for _, c := range kept[:over] {
+			out = append(out, replacement{client: c, reason: reason})
+		}
+		return nil
+	}
+	if err := limit(q.MaxConnsPerUserType, sameType, metrics.ReplacedUserTypeLimit); err != nil {
+		return nil, err
+	}
+	if err := limit(q.MaxConnsPerUser, func(*client) bool { return true }, metrics.ReplacedUserLimit); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// replaceLocked 断开为新连接让位的旧连接，写循环先收到 event: replaced 再随通道关闭退出；
+// 调用方需持有 clientsMu 写锁
+func (h *ShardedHub) replaceLocked(r replacement, by int64) {
+	c := r.client
+	payload, _ := json.Marshal(struct {
+		Reason string `json:"reason"`
+	}{r.reason})
+	c.held.event(ports.EventReplaced, payload, func(marker []byte) bool {
+		c.farewell = marker
+		return true
+	})
+	h.removeLocked(c)
+	metrics.ConnectionsReplaced.With(r.reason).Inc()
+	h.log.Info("客户端已被新连接替换",
+		"clientId", c.id, "userId", c.userId, "clientType", c.clientType, "reason", r.reason, "by", by)
+}
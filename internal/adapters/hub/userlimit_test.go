@@ -0,0 +1,160 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"testing"
+	"time"
+)
+
+func newQuotaHub(q ports.Quota) *ShardedHub {
+	return NewShardedHub(q, &Settings{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// expectReplaced 连接最后收到 event: replaced 并随后关闭
+func expectReplaced(t *testing.T, c *ports.Client, reason string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	var event string
+	var data []byte
+	for {
+		select {
+		case msg, ok := <-c.SendCh:
+			if !ok {
+				if event != ports.EventReplaced {
+					t.Fatalf("连接 %d 关闭前最后收到 %q %s", c.ID, event, data)
+				}
+				var body struct{ Reason string }
+				if err := json.Unmarshal(data, &body); err != nil || body.Reason != reason {
+					t.Fatalf("连接 %d 的替换原因为 %s，期望 %s", c.ID, data, reason)
+				}
+				return
+			}
+			event, data, _ = c.Take(msg)
+		case <-timeout:
+			t.Fatalf("连接 %d 未被替换", c.ID)
+		}
+	}
+}
+
+// expectOpen 连接仍然可用：能收到之后的广播
+func expectOpen(t *testing.T, h *ShardedHub, conns ...*ports.Client) {
+	t.Helper()
+	h.Broadcast("news", []byte("ping"), ports.PublishOptions{})
+	for _, c := range conns {
+		select {
+		case msg, ok := <-c.SendCh:
+			if !ok {
+				t.Fatalf("连接 %d 已关闭", c.ID)
+			}
+			if _, data, _ := c.Take(msg); string(data) != "ping" {
+				t.Fatalf("连接 %d 收到 %s", c.ID, data)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("连接 %d 未收到广播", c.ID)
+		}
+	}
+}
+
+func TestUserLimitReject(t *testing.T) {
+	h := newQuotaHub(ports.Quota{MaxConnsPerUser: 2})
+	a := connect(t, h, 1, "web", "news")
+	b := connect(t, h, 1, "ios", "news")
+	if _, err := h.NewClient(1, "android", []string{"news"}, "", ports.TransportSSE); !errors.Is(err, ports.ErrTooManyUserConnections) {
+		t.Fatalf("超过上限返回 %v", err)
+	}
+	// 其他用户与匿名连接不受影响
+	other := connect(t, h, 2, "web", "news")
+	for range 3 {
+		connect(t, h, 0, "web")
+	}
+	expectOpen(t, h, a, b, other)
+
+	// 断开一个后可以再连
+	h.Remove(a)
+	connect(t, h, 1, "android", "news")
+}
+
+func TestUserTypeLimitReject(t *testing.T) {
+	h := newQuotaHub(ports.Quota{MaxConnsPerUserType: 1, OnUserLimit: ports.UserLimitReject})
+	web := connect(t, h, 1, "web", "news")
+	if _, err := h.NewClient(1, "web", []string{"news"}, "", ports.TransportSSE); !errors.Is(err, ports.ErrTooManyUserConnections) {
+		t.Fatalf("超过上限返回 %v", err)
+	}
+	ios := connect(t, h, 1, "ios", "news")
+	expectOpen(t, h, web, ios)
+}
+
+func TestUserLimitEvictOldest(t *testing.T) {
+	h := newQuotaHub(ports.Quota{MaxConnsPerUser: 2, OnUserLimit: ports.UserLimitEvictOldest})
+	a := connect(t, h, 1, "web", "news")
+	b := connect(t, h, 1, "ios", "news")
+	c := connect(t, h, 1, "android", "news")
+	expectReplaced(t, a, metrics.ReplacedUserLimit)
+	expectOpen(t, h, b, c)
+	if u, _ := h.IsOnline(1); u.Connections != 2 {
+		t.Fatalf("用户 1 有 %d 个连接", u.Connections)
+	}
+
+	d := connect(t, h, 1, "web", "news")
+	expectReplaced(t, b, metrics.ReplacedUserLimit)
+	expectOpen(t, h, c, d)
+}
+
+func TestUserTypeLimitEvictOldest(t *testing.T) {
+	h := newQuotaHub(ports.Quota{MaxConnsPerUserType: 2, MaxConnsPerUser: 3, OnUserLimit: ports.UserLimitEvictOldest})
+	web1 := connect(t, h, 1, "web", "news")
+	ios := connect(t, h, 1, "ios", "news")
+	web2 := connect(t, h, 1, "web", "news")
+	// 同类型超过上限，断开最早的同类型连接
+	web3 := connect(t, h, 1, "web", "news")
+	expectReplaced(t, web1, metrics.ReplacedUserTypeLimit)
+	expectOpen(t, h, ios, web2, web3)
+
+	// 同类型让位后总数仍超过上限，再断开最早的连接
+	android := connect(t, h, 1, "android", "news")
+	expectReplaced(t, ios, metrics.ReplacedUserLimit)
+	expectOpen(t, h, web2, web3, android)
+}
+
+func TestSingleSession(t *testing.T) {
+	h := newQuotaHub(ports.Quota{SingleSession: true})
+	web1 := connect(t, h, 1, "web", "news")
+	ios := connect(t, h, 1, "ios", "news")
+	other := connect(t, h, 2, "web", "news")
+	web2 := connect(t, h, 1, "web", "news")
+	expectReplaced(t, web1, metrics.ReplacedSingleSession)
+	expectOpen(t, h, ios, other, web2)
+
+	// 匿名连接不按用户限制
+	anon1 := connect(t, h, 0, "web", "news")
+	anon2 := connect(t, h, 0, "web", "news")
+	expectOpen(t, h, ios, other, web2, anon1, anon2)
+}
+
+// 被替换的连接不计入 MaxConns，新连接可以顶替
+func TestSingleSessionAtMaxConns(t *testing.T) {
+	h := newQuotaHub(ports.Quota{SingleSession: true, MaxConns: 1})
+	old := connect(t, h, 1, "web", "news")
+	if _, err := h.NewClient(2, "web", []string{"news"}, "", ports.TransportSSE); !errors.Is(err, ports.ErrTooManyConnections) {
+		t.Fatalf("超过总连接数返回 %v", err)
+	}
+	cur := connect(t, h, 1, "web", "news")
+	expectReplaced(t, old, metrics.ReplacedSingleSession)
+	expectOpen(t, h, cur)
+}
+
+// 拒绝时不断开任何旧连接
+func TestUserLimitRejectKeepsExisting(t *testing.T) {
+	h := newQuotaHub(ports.Quota{SingleSession: true, MaxConnsPerUser: 1, MaxConns: 1})
+	old := connect(t, h, 1, "web", "news")
+	// 单会话只会替换同类型连接，ios 仍超过用户上限
+	if _, err := h.NewClient(1, "ios", []string{"news"}, "", ports.TransportSSE); !errors.Is(err, ports.ErrTooManyUserConnections) {
+		t.Fatalf("返回 %v", err)
+	}
+	expectOpen(t, h, old)
+}
@@ -0,0 +1,151 @@
+package tenant
+
+import (
+	"sort"
+	"sse/internal/ports"
+	"sse/pkg/rate"
+	"sync"
+)
+
+// 单个租户的运行时状态
+type entry struct {
+	hub     ports.Hub
+	quota   ports.Quota
+	limiter *rate.Limiter
+}
+
+// Registry 按租户隔离的 Hub 注册表
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*entry
+
+	// 创建租户 Hub 的工厂
+	newHub func(tenant string, quota ports.Quota) ports.Hub
+	// 未单独配置的租户使用的默认配额
+	defaultQuota ports.Quota
+	// 按租户名单独配置的配额
+	quotas map[string]ports.Quota
+	// 严格模式下只允许 quotas 中声明的租户
+	strict bool
+	// 非严格模式下最多创建的未声明租户数，<=0 表示不限制；租户名来自客户端，需要限制以免无限创建 Hub
+	maxUndeclared int
+	undeclared    int
+	// 实例级发布限流，所有租户共享
+	global *rate.Limiter
+}
+
+// NewRegistry 创建租户注册表，publishQps 为实例级发布限流，maxUndeclared 为未声明租户数的上限
+func NewRegistry(newHub func(tenant string, quota ports.Quota) ports.Hub, defaultQuota ports.Quota, quotas map[string]ports.Quota, strict bool, maxUndeclared int, publishQps int) *Registry {
+	if quotas == nil {
+		quotas = make(map[string]ports.Quota)
+	}
+	return &Registry{
+		entries:       make(map[string]*entry),
+		newHub:        newHub,
+		defaultQuota:  defaultQuota,
+		quotas:        quotas,
+		strict:        strict,
+		maxUndeclared: maxUndeclared,
+		global:        rate.NewLimiter(publishQps),
+	}
+}
+
+// SetPublishQps 调整实例级发布限流
+func (r *Registry) SetPublishQps(qps int) {
+	r.global.SetQps(qps)
+}
+
+// Hub 获取租户对应的 Hub，首次访问时按配额创建
+func (r *Registry) Hub(tenant string) (ports.Hub, error) {
+	e, err := r.entry(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return e.hub, nil
+}
+
+// AllowPublish 先检查租户限流，通过后再占用实例级限流，超出自身配额的租户不消耗共享的令牌
+func (r *Registry) AllowPublish(tenant string) bool {
+	e, err := r.entry(tenant)
+	if err != nil {
+		return false
+	}
+	return e.limiter.Allow() && r.global.Allow()
+}
+
+// Each 按租户名顺序遍历已创建的 Hub
+func (r *Registry) Each(fn func(tenant string, hub ports.Hub)) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.entries))
+	hubs := make(map[string]ports.Hub, len(r.entries))
+	for name, e := range r.entries {
+		names = append(names, name)
+		hubs[name] = e.hub
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		fn(name, hubs[name])
+	}
+}
+
+// Stats 各租户的连接数与配额
+func (r *Registry) Stats() []ports.TenantStats {
+	data := make([]ports.TenantStats, 0)
+	r.Each(func(tenant string, hub ports.Hub) {
+		data = append(data, ports.TenantStats{
+			Tenant:      tenant,
+			Connections: int(hub.Conns()),
+			Quota:       r.quotaOf(tenant),
+		})
+	})
+	return data
+}
+
+func (r *Registry) entry(tenant string) (*entry, error) {
+	if tenant == "" {
+		tenant = ports.DefaultTenant
+	}
+
+	r.mu.RLock()
+	e, ok := r.entries[tenant]
+	r.mu.RUnlock()
+	if ok {
+		return e, nil
+	}
+
+	_, declared := r.quotas[tenant]
+	declared = declared || tenant == ports.DefaultTenant
+	if r.strict && !declared {
+		return nil, ports.ErrUnknownTenant
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 双重检查，避免并发重复创建
+	if e, ok := r.entries[tenant]; ok {
+		return e, nil
+	}
+	if !declared {
+		if r.maxUndeclared > 0 && r.undeclared >= r.maxUndeclared {
+			return nil, ports.ErrTooManyTenants
+		}
+		r.undeclared++
+	}
+	quota := r.quotaOf(tenant)
+	e = &entry{
+		hub:     r.newHub(tenant, quota),
+		quota:   quota,
+		limiter: rate.NewLimiter(quota.PublishQps),
+	}
+	r.entries[tenant] = e
+	return e, nil
+}
+
+func (r *Registry) quotaOf(tenant string) ports.Quota {
+	if q, ok := r.quotas[tenant]; ok {
+		return q
+	}
+	return r.defaultQuota
+}
@@ -0,0 +1,170 @@
+package tenant
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sse/internal/adapters/hub"
+	"sse/internal/ports"
+	"testing"
+	"time"
+)
+
+// created 记录 newHub 的调用
+type created struct {
+	names  []string
+	quotas map[string]ports.Quota
+}
+
+func newTestRegistry(t *testing.T, quotas map[string]ports.Quota, strict bool, maxUndeclared, publishQps int) (*Registry, *created) {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := &created{quotas: map[string]ports.Quota{}}
+	newHub := func(name string, quota ports.Quota) ports.Hub {
+		c.names = append(c.names, name)
+		c.quotas[name] = quota
+		return hub.NewShardedHub(quota, &hub.Settings{}, log)
+	}
+	return NewRegistry(newHub, ports.Quota{MaxConns: 10}, quotas, strict, maxUndeclared, publishQps), c
+}
+
+func mustHub(t *testing.T, r *Registry, name string) ports.Hub {
+	t.Helper()
+	h, err := r.Hub(name)
+	if err != nil {
+		t.Fatalf("Hub(%q): %v", name, err)
+	}
+	return h
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	r, c := newTestRegistry(t, map[string]ports.Quota{"acme": {MaxConns: 1}}, false, 0, 0)
+	acme := mustHub(t, r, "acme")
+	other := mustHub(t, r, "other")
+	if acme == other {
+		t.Fatal("不同租户共用了 Hub")
+	}
+	if mustHub(t, r, "acme") != acme {
+		t.Fatal("同一租户重复创建了 Hub")
+	}
+	// 空租户名使用默认租户
+	if mustHub(t, r, "") != mustHub(t, r, ports.DefaultTenant) {
+		t.Fatal("空租户名未使用默认租户")
+	}
+	if len(c.names) != 3 {
+		t.Fatalf("创建了 %v", c.names)
+	}
+	// 声明的租户使用自己的配额，其余使用默认配额
+	if c.quotas["acme"].MaxConns != 1 || c.quotas["other"].MaxConns != 10 {
+		t.Fatalf("配额 %+v", c.quotas)
+	}
+
+	// 同一主题、同一用户的消息不会跨租户下发
+	a, err := acme.NewClient(1, "web", []string{"news"}, "", ports.TransportSSE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := other.NewClient(1, "web", []string{"news"}, "", ports.TransportSSE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Broadcast("news", []byte("for other"), ports.PublishOptions{})
+	other.PublishByUserId(1, "user of other", ports.PublishOptions{})
+	select {
+	case msg := <-a.SendCh:
+		t.Fatalf("acme 收到 other 的消息 %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := len(drain(b)); n != 2 {
+		t.Fatalf("other 收到 %d 条", n)
+	}
+	// 连接配额按租户计算
+	if _, err := acme.NewClient(2, "web", nil, "", ports.TransportSSE); !errors.Is(err, ports.ErrTooManyConnections) {
+		t.Fatalf("acme 超过配额返回 %v", err)
+	}
+	if _, err := other.NewClient(2, "web", nil, "", ports.TransportSSE); err != nil {
+		t.Fatalf("other 不受 acme 的配额影响: %v", err)
+	}
+
+	stats := r.Stats()
+	if len(stats) != 3 || stats[0].Tenant != "acme" || stats[0].Connections != 1 || stats[2].Tenant != "other" || stats[2].Connections != 2 {
+		t.Fatalf("统计 %+v", stats)
+	}
+}
+
+// drain 读出已排队的消息
+func drain(c *ports.Client) [][]byte {
+	var out [][]byte
+	for {
+		select {
+		case msg := <-c.SendCh:
+			out = append(out, msg)
+		case <-time.After(50 * time.Millisecond):
+			return out
+		}
+	}
+}
+
+func TestRegistryStrict(t *testing.T) {
+	r, c := newTestRegistry(t, map[string]ports.Quota{"acme": {}}, true, 0, 0)
+	mustHub(t, r, "acme")
+	mustHub(t, r, "")
+	if _, err := r.Hub("unknown"); !errors.Is(err, ports.ErrUnknownTenant) {
+		t.Fatalf("未声明的租户返回 %v", err)
+	}
+	if r.AllowPublish("unknown") {
+		t.Fatal("未声明的租户允许发布")
+	}
+	if len(c.names) != 2 {
+		t.Fatalf("创建了 %v", c.names)
+	}
+}
+
+func TestRegistryMaxUndeclared(t *testing.T) {
+	r, c := newTestRegistry(t, map[string]ports.Quota{"acme": {}}, false, 2, 0)
+	mustHub(t, r, "a")
+	mustHub(t, r, "b")
+	if _, err := r.Hub("c"); !errors.Is(err, ports.ErrTooManyTenants) {
+		t.Fatalf("超过上限返回 %v", err)
+	}
+	// 已创建的、声明的与默认租户不受上限影响
+	mustHub(t, r, "a")
+	mustHub(t, r, "acme")
+	mustHub(t, r, "")
+	if _, err := r.Hub("d"); !errors.Is(err, ports.ErrTooManyTenants) {
+		t.Fatalf("超过上限返回 %v", err)
+	}
+	if len(c.names) != 4 {
+		t.Fatalf("创建了 %v", c.names)
+	}
+}
+
+// 租户自身限流未通过时不消耗实例级的令牌
+func TestRegistryLimiterOrder(t *testing.T) {
+	r, _ := newTestRegistry(t, map[string]ports.Quota{
+		"slow": {PublishQps: 1},
+		"fast": {PublishQps: 100},
+	}, false, 0, 2)
+
+	if !r.AllowPublish("slow") {
+		t.Fatal("slow 的第一次发布被拒绝")
+	}
+	for range 5 {
+		if r.AllowPublish("slow") {
+			t.Fatal("slow 超过自身配额仍被允许")
+		}
+	}
+	// 实例级还剩一个令牌，留给其他租户
+	if !r.AllowPublish("fast") {
+		t.Fatal("slow 的拒绝消耗了实例级令牌")
+	}
+	if r.AllowPublish("fast") {
+		t.Fatal("实例级令牌已用完")
+	}
+
+	// 实例级限流可以热更新，0 表示不限制
+	r.SetPublishQps(0)
+	if !r.AllowPublish("fast") {
+		t.Fatal("关闭实例级限流后仍被拒绝")
+	}
+}
@@ -0,0 +1,106 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// 租户解析方式
+const (
+	ModeNone   = ""
+	ModeHeader = "header"
+	ModeHost   = "host"
+	ModePath   = "path"
+	ModeToken  = "token"
+)
+
+var (
+	// ErrMissingTenant 请求中没有可用的租户信息
+	ErrMissingTenant = errors.New("缺少租户信息")
+	// ErrInvalidToken token 格式错误或签名校验失败
+	ErrInvalidToken = errors.New("无效的 token")
+)
+
+// Resolver 根据配置从请求属性中解析租户
+type Resolver struct {
+	Mode   string
+	Header string
+	Claim  string
+	Secret []byte
+}
+
+// FromHost 取主机名的第一段作为租户，如 shop.sse.example.com -> shop
+func (r *Resolver) FromHost(host string) (string, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return "", ErrMissingTenant
+	}
+	return labels[0], nil
+}
+
+// FromPath 取路径第一段作为租户，返回租户与去掉前缀后的路径，如 /shop/sse -> shop, /sse
+func (r *Resolver) FromPath(path string) (string, string, error) {
+	trimmed := strings.TrimPrefix(path, "/")
+	tenant, rest, _ := strings.Cut(trimmed, "/")
+	if tenant == "" {
+		return "", path, ErrMissingTenant
+	}
+	return tenant, "/" + rest, nil
+}
+
+// FromToken 校验 HS256 签名的 JWT（头部 alg 必须为 HS256），并读取配置的 claim 作为租户
+func (r *Resolver) FromToken(token string) (string, error) {
+	token = strings.TrimPrefix(token, "Bearer ")
+	if token == "" {
+		return "", ErrMissingTenant
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+	// 只接受 HS256，拒绝 alg 为 none 或其他算法的 token
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil || h.Alg != "HS256" {
+		return "", ErrInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, r.Secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	claims := make(map[string]any)
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", ErrInvalidToken
+	}
+	if exp, ok := claims["exp"].(float64); ok && time.Now().Unix() > int64(exp) {
+		return "", ErrInvalidToken
+	}
+	tenant, _ := claims[r.Claim].(string)
+	if tenant == "" {
+		return "", ErrMissingTenant
+	}
+	return tenant, nil
+}
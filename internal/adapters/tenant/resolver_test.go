@@ -0,0 +1,115 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("s3cret")
+
+func b64(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// sign 用 key 对 header.payload 做 HS256 签名
+func sign(key []byte, header, payload string) string {
+	signing := b64(header) + "." + b64(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signing))
+	return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+const hs256 = `{"alg":"HS256","typ":"JWT"}`
+
+func TestFromToken(t *testing.T) {
+	r := &Resolver{Claim: "tenant", Secret: secret}
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	valid := sign(secret, hs256, `{"tenant":"acme","exp":`+strconv.FormatInt(future, 10)+`}`)
+
+	// 篡改 payload 但保留原签名
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + b64(`{"tenant":"evil","exp":`+strconv.FormatInt(future, 10)+`}`) + "." + parts[2]
+
+	tests := []struct {
+		name   string
+		token  string
+		tenant string
+		err    error
+	}{
+		{"有效", valid, "acme", nil},
+		{"带 Bearer 前缀", "Bearer " + valid, "acme", nil},
+		{"header 只有 alg", sign(secret, `{"alg":"HS256"}`, `{"tenant":"acme"}`), "acme", nil},
+		{"没有 exp", sign(secret, hs256, `{"tenant":"acme"}`), "acme", nil},
+		{"空", "", "", ErrMissingTenant},
+		{"只有 Bearer", "Bearer ", "", ErrMissingTenant},
+		{"缺少 claim", sign(secret, hs256, `{"sub":"1"}`), "", ErrMissingTenant},
+		{"claim 不是字符串", sign(secret, hs256, `{"tenant":1}`), "", ErrMissingTenant},
+		{"已过期", sign(secret, hs256, `{"tenant":"acme","exp":`+strconv.FormatInt(past, 10)+`}`), "", ErrInvalidToken},
+		{"payload 被篡改", tampered, "", ErrInvalidToken},
+		{"签名被篡改", parts[0] + "." + parts[1] + "." + b64("bad"), "", ErrInvalidToken},
+		{"密钥不同", sign([]byte("other"), hs256, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"alg 为 none", b64(`{"alg":"none"}`) + "." + b64(`{"tenant":"acme"}`) + ".", "", ErrInvalidToken},
+		{"alg 为 none 但带签名", sign(secret, `{"alg":"none"}`, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"alg 为 HS512", sign(secret, `{"alg":"HS512"}`, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"alg 为 RS256", sign(secret, `{"alg":"RS256"}`, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"alg 大小写不同", sign(secret, `{"alg":"hs256"}`, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"没有 alg", sign(secret, `{"typ":"JWT"}`, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"header 不是 JSON", sign(secret, `HS256`, `{"tenant":"acme"}`), "", ErrInvalidToken},
+		{"payload 不是 JSON", sign(secret, hs256, `acme`), "", ErrInvalidToken},
+		{"段数不对", "a.b", "", ErrInvalidToken},
+		{"header 不是 base64", "!!." + parts[1] + "." + parts[2], "", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.FromToken(tt.token)
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("错误为 %v，期望 %v", err, tt.err)
+			}
+			if got != tt.tenant {
+				t.Fatalf("租户为 %q，期望 %q", got, tt.tenant)
+			}
+		})
+	}
+}
+
+func TestFromHost(t *testing.T) {
+	r := &Resolver{}
+	for host, want := range map[string]string{
+		"shop.sse.example.com":      "shop",
+		"shop.sse.example.com:8080": "shop",
+	} {
+		if got, err := r.FromHost(host); err != nil || got != want {
+			t.Errorf("FromHost(%q) = %q, %v", host, got, err)
+		}
+	}
+	for _, host := range []string{"localhost", "example.com", "localhost:8080"} {
+		if _, err := r.FromHost(host); !errors.Is(err, ErrMissingTenant) {
+			t.Errorf("FromHost(%q) 返回 %v", host, err)
+		}
+	}
+}
+
+func TestFromPath(t *testing.T) {
+	r := &Resolver{}
+	tests := []struct{ path, tenant, rest string }{
+		{"/shop/sse", "shop", "/sse"},
+		{"/shop/publish/user", "shop", "/publish/user"},
+		{"/shop", "shop", "/"},
+	}
+	for _, tt := range tests {
+		tenant, rest, err := r.FromPath(tt.path)
+		if err != nil || tenant != tt.tenant || rest != tt.rest {
+			t.Errorf("FromPath(%q) = %q %q %v", tt.path, tenant, rest, err)
+		}
+	}
+	if _, _, err := r.FromPath("/"); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("FromPath(/) 返回 %v", err)
+	}
+}
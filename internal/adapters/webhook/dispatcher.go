@@ -0,0 +1,438 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	mrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 回调请求头
+const (
+	HeaderSignature = "X-SSE-Signature" // sha256=<hex>，HMAC-SHA256(secret, timestamp + "." + body)
+	HeaderTimestamp = "X-SSE-Timestamp" // 签名时的 Unix 秒
+	HeaderDelivery  = "X-SSE-Delivery"  // 投递 ID，重试时不变，接收方可据此去重
+	HeaderAttempt   = "X-SSE-Attempt"   // 第几次尝试，从 1 开始
+)
+
+// Options 回调参数，零值字段使用默认值
+type Options struct {
+	// 默认签名密钥，为空且订阅未设置密钥时不签名
+	Secret string
+	// 单次请求超时
+	Timeout time.Duration
+	// 最多尝试次数，达到后转入死信
+	MaxAttempts int
+	// 首次重试的等待时间，之后每次翻倍
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// 并发请求数
+	Workers int
+	// 队列上限，超出的消息直接转入死信
+	QueueMax int
+	// 死信保留条数，超出时丢弃最旧的
+	DeadLetterMax int
+	// 队列快照文件，为空表示不落盘
+	QueueFile string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Minute
+	}
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.QueueMax <= 0 {
+		o.QueueMax = 10000
+	}
+	if o.DeadLetterMax <= 0 {
+		o.DeadLetterMax = 1000
+	}
+	return o
+}
+
+// entry 队列中的一条回调
+type entry struct {
+	ports.WebhookDelivery
+	inflight bool
+}
+
+// Dispatcher 把离线消息按订阅回调到 HTTP 地址，失败时指数退避重试，
+// 超过次数或被接收方拒绝（4xx）时转入死信
+type Dispatcher struct {
+	subs   []Subscription
+	opts   Options
+	log    *slog.Logger
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending []*entry
+	dead    []ports.WebhookDelivery
+	// 队列变化后尚未写入快照
+	dirty bool
+
+	wake chan struct{}
+	sem  chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDispatcher 创建回调分发器，需调用 Start 后才开始投递
+func NewDispatcher(subs []Subscription, opts Options, log *slog.Logger) *Dispatcher {
+	opts = opts.withDefaults()
+	return &Dispatcher{
+		subs:   subs,
+		opts:   opts,
+		log:    log,
+		client: &http.Client{Timeout: opts.Timeout},
+		now:    time.Now,
+		wake:   make(chan struct{}, 1),
+		sem:    make(chan struct{}, opts.Workers),
+		done:   make(chan struct{}),
+	}
+}
+
+// Offline 为每个匹配的订阅入队一条回调，不阻塞调用方
+func (d *Dispatcher) Offline(m ports.Undelivered) {
+	d.mu.Lock()
+	for i := range d.subs {
+		if !d.subs[i].Match(m) {
+			continue
+		}
+		dl := ports.WebhookDelivery{ID: newID(), URL: d.subs[i].URL, Subscription: i, Event: m, NextAt: d.now()}
+		if len(d.pending) >= d.opts.QueueMax {
+			dl.LastError = "队列已满"
+			d.bury(dl)
+			metrics.WebhookDeliveries.With(metrics.WebhookDropped).Inc()
+			continue
+		}
+		d.pending = append(d.pending, &entry{WebhookDelivery: dl})
+		d.dirty = true
+	}
+	d.gauge()
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Start 启动调度协程
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.run()
+}
+
+// Close 停止调度，等待调度协程与进行中的请求结束后写入最后一次快照
+func (d *Dispatcher) Close() error {
+	close(d.done)
+	d.wg.Wait()
+	return d.Save()
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 投递到期的回调，并每秒把有变化的队列写入快照
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	flush := time.NewTicker(time.Second)
+	defer flush.Stop()
+
+	for {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d.dispatch())
+
+		select {
+		case <-d.done:
+			return
+		case <-d.wake:
+		case <-timer.C:
+		case <-flush.C:
+			if err := d.Save(); err != nil {
+				d.log.Warn("写入 webhook 队列快照失败", "err", err)
+			}
+		}
+	}
+}
+
+// dispatch 在空闲 worker 允许的范围内发出到期的回调，返回距离下一条到期的时间
+func (d *Dispatcher) dispatch() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	next := time.Hour
+	var expired []*entry
+	defer func() {
+		// 过期的回调直接丢弃，不转入死信
+		for _, e := range expired {
+			d.remove(e)
+			metrics.MessagesExpired.With(metrics.ExpiredWebhook).Inc()
+		}
+		if len(expired) > 0 {
+			d.dirty = true
+			d.gauge()
+		}
+	}()
+	for _, e := range d.pending {
+		if e.inflight {
+			continue
+		}
+		if e.Event.ExpiresAt != nil && ports.Expired(*e.Event.ExpiresAt, now) {
+			expired = append(expired, e)
+			continue
+		}
+		if wait := e.NextAt.Sub(now); wait > 0 {
+			next = min(next, wait)
+			continue
+		}
+		select {
+		case d.sem <- struct{}{}:
+		default:
+			// worker 已满，等某个请求结束后由 notify 唤醒
+			return next
+		}
+		e.inflight = true
+		e.Attempts++
+		d.wg.Add(1)
+		go d.attempt(e, e.WebhookDelivery)
+	}
+	return next
+}
+
+// attempt 发出一次回调并根据结果移除、重排或转入死信
+func (d *Dispatcher) attempt(e *entry, dl ports.WebhookDelivery) {
+	defer d.wg.Done()
+	retryAfter, err := d.send(dl)
+	<-d.sem
+
+	d.mu.Lock()
+	e.inflight = false
+	switch {
+	case err == nil:
+		d.remove(e)
+		metrics.WebhookDeliveries.With(metrics.WebhookDelivered).Inc()
+	case !isRetryable(err) || e.Attempts >= d.opts.MaxAttempts:
+		e.LastError = err.Error()
+		d.remove(e)
+		d.bury(e.WebhookDelivery)
+		metrics.WebhookDeliveries.With(metrics.WebhookDead).Inc()
+		d.log.Warn("webhook 回调失败，转入死信", "id", e.ID, "url", e.URL, "attempts", e.Attempts, "err", err)
+	default:
+		e.LastError = err.Error()
+		e.NextAt = d.now().Add(max(d.backoff(e.Attempts), retryAfter))
+		metrics.WebhookDeliveries.With(metrics.WebhookRetry).Inc()
+		d.log.Debug("webhook 回调失败，稍后重试", "id", e.ID, "url", e.URL, "attempts", e.Attempts, "err", err)
+	}
+	d.dirty = true
+	d.gauge()
+	d.mu.Unlock()
+	d.notify()
+}
+
+// backoff 第 attempts 次失败后的等待时间，抖动 ±20%
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.opts.InitialBackoff
+	for i := 1; i < attempts && wait < d.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, d.opts.MaxBackoff)
+	return time.Duration(float64(wait) * (0.8 + 0.4*mrand.Float64()))
+}
+
+// statusError 接收方返回的非 2xx 状态
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.code, e.body)
+}
+
+// errBadRequest 无法构造回调请求（地址或事件无效），重试也不会成功
+var errBadRequest = errors.New("无法构造回调请求")
+
+// isRetryable 网络错误、5xx、408 与 429 可以重试，其余 4xx 说明请求本身不被接受
+func isRetryable(err error) bool {
+	if errors.Is(err, errBadRequest) {
+		return false
+	}
+	se, ok := err.(*statusError)
+	if !ok {
+		return true
+	}
+	return se.code >= 500 || se.code == http.StatusRequestTimeout || se.code == http.StatusTooManyRequests
+}
+
+// send 发出一次签名的回调，返回接收方要求的 Retry-After
+func (d *Dispatcher) send(dl ports.WebhookDelivery) (time.Duration, error) {
+	body, err := json.Marshal(dl.Event)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	ts := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(dl.Attempts))
+	if secret := d.secretFor(dl); secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, ts, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return 0, nil
+	}
+	var retryAfter time.Duration
+	if n, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && n > 0 {
+		retryAfter = min(time.Duration(n)*time.Second, d.opts.MaxBackoff)
+	}
+	return retryAfter, &statusError{code: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
+}
+
+// Sign 计算回调签名，接收方用同样的方式校验 X-SSE-Signature
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// secretFor 回调所属订阅的签名密钥；快照来自修改前的配置、下标已不对应同一地址时按地址查找
+func (d *Dispatcher) secretFor(dl ports.WebhookDelivery) string {
+	i := dl.Subscription
+	if i < 0 || i >= len(d.subs) || d.subs[i].URL != dl.URL {
+		i = slices.IndexFunc(d.subs, func(s Subscription) bool { return s.URL == dl.URL })
+	}
+	if i >= 0 && d.subs[i].Secret != "" {
+		return d.subs[i].Secret
+	}
+	return d.opts.Secret
+}
+
+// remove 从队列移除，调用方需持有 mu
+func (d *Dispatcher) remove(e *entry) {
+	if i := slices.Index(d.pending, e); i >= 0 {
+		d.pending = slices.Delete(d.pending, i, i+1)
+	}
+}
+
+// bury 加入死信并裁剪到上限，调用方需持有 mu
+func (d *Dispatcher) bury(dl ports.WebhookDelivery) {
+	d.dead = append(d.dead, dl)
+	if over := len(d.dead) - d.opts.DeadLetterMax; over > 0 {
+		d.dead = slices.Delete(d.dead, 0, over)
+	}
+	d.dirty = true
+}
+
+// gauge 更新队列长度指标，调用方需持有 mu
+func (d *Dispatcher) gauge() {
+	metrics.WebhookQueue.With("pending").Set(float64(len(d.pending)))
+	metrics.WebhookQueue.With("dead").Set(float64(len(d.dead)))
+}
+
+// Pending 等待投递或重试中的回调，包括正在发送的
+func (d *Dispatcher) Pending() []ports.WebhookDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]ports.WebhookDelivery, 0, len(d.pending))
+	for _, e := range d.pending {
+		out = append(out, e.WebhookDelivery)
+	}
+	return out
+}
+
+// DeadLetters 死信的副本，按转入的先后排列
+func (d *Dispatcher) DeadLetters() []ports.WebhookDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]ports.WebhookDelivery{}, d.dead...)
+}
+
+// Redrive 把死信放回队列并清零尝试次数，立即投递
+func (d *Dispatcher) Redrive(id string) bool {
+	d.mu.Lock()
+	i := slices.IndexFunc(d.dead, func(dl ports.WebhookDelivery) bool { return dl.ID == id })
+	if i < 0 {
+		d.mu.Unlock()
+		return false
+	}
+	dl := d.dead[i]
+	d.dead = slices.Delete(d.dead, i, i+1)
+	dl.Attempts, dl.NextAt = 0, d.now()
+	d.pending = append(d.pending, &entry{WebhookDelivery: dl})
+	d.dirty = true
+	d.gauge()
+	d.mu.Unlock()
+	d.notify()
+	return true
+}
+
+// Discard 删除一条死信
+func (d *Dispatcher) Discard(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := slices.IndexFunc(d.dead, func(dl ports.WebhookDelivery) bool { return dl.ID == id })
+	if i < 0 {
+		return false
+	}
+	d.dead = slices.Delete(d.dead, i, i+1)
+	d.dirty = true
+	d.gauge()
+	return true
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
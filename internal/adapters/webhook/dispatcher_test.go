@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sse/internal/ports"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// request 接收方收到的一次回调
+type request struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+// receiver 本地回调接收方，按 respond 依次返回状态码，用完后返回 200
+type receiver struct {
+	url string
+
+	mu       sync.Mutex
+	requests []request
+	respond  []func(w http.ResponseWriter)
+}
+
+func newReceiver(t *testing.T, respond ...func(w http.ResponseWriter)) *receiver {
+	t.Helper()
+	rc := &receiver{respond: respond}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.requests = append(rc.requests, request{header: r.Header.Clone(), body: body, at: time.Now()})
+		var fn func(http.ResponseWriter)
+		if len(rc.respond) > 0 {
+			fn, rc.respond = rc.respond[0], rc.respond[1:]
+		}
+		rc.mu.Unlock()
+		if fn != nil {
+			fn(w)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	rc.url = srv.URL
+	return rc
+}
+
+func (rc *receiver) received() []request {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]request(nil), rc.requests...)
+}
+
+// status 返回指定状态码
+func status(code int) func(http.ResponseWriter) {
+	return func(w http.ResponseWriter) { w.WriteHeader(code) }
+}
+
+func newTestDispatcher(t *testing.T, subs []Subscription, opts Options) *Dispatcher {
+	t.Helper()
+	return NewDispatcher(subs, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+var offline = ports.Undelivered{Tenant: "acme", Target: ports.TargetUser, UserId: 7, Message: "hi"}
+
+// waitFor 等待 cond 成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherSignature(t *testing.T) {
+	rc := newReceiver(t)
+	// 两个订阅共用同一地址，各自的回调使用各自的密钥
+	subs := []Subscription{
+		{URL: rc.url, Secret: "s1", Users: true},
+		{URL: rc.url, Secret: "s2", Users: true},
+	}
+	d := newTestDispatcher(t, subs, Options{})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	waitFor(t, "两次回调", func() bool { return len(rc.received()) == 2 && len(d.Pending()) == 0 })
+
+	used := map[string]bool{}
+	for _, r := range rc.received() {
+		ts := r.header.Get(HeaderTimestamp)
+		if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+			t.Fatalf("%s 为 %q", HeaderTimestamp, ts)
+		}
+		if r.header.Get(HeaderDelivery) == "" || r.header.Get(HeaderAttempt) != "1" {
+			t.Fatalf("请求头 %v", r.header)
+		}
+		sig := r.header.Get(HeaderSignature)
+		switch sig {
+		case Sign("s1", ts, r.body):
+			used["s1"] = true
+		case Sign("s2", ts, r.body):
+			used["s2"] = true
+		default:
+			t.Fatalf("签名 %q 与两个密钥都不匹配", sig)
+		}
+		if !strings.Contains(string(r.body), `"message":"hi"`) {
+			t.Fatalf("请求体 %s", r.body)
+		}
+	}
+	if !used["s1"] || !used["s2"] {
+		t.Fatalf("使用的密钥 %v", used)
+	}
+}
+
+func TestDispatcherDefaultSecret(t *testing.T) {
+	rc := newReceiver(t)
+	d := newTestDispatcher(t, []Subscription{{URL: rc.url, Users: true}}, Options{Secret: "default"})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	waitFor(t, "回调", func() bool { return len(rc.received()) == 1 })
+	r := rc.received()[0]
+	if r.header.Get(HeaderSignature) != Sign("default", r.header.Get(HeaderTimestamp), r.body) {
+		t.Fatal("未使用 Options.Secret 签名")
+	}
+}
+
+func TestDispatcherRetryBackoff(t *testing.T) {
+	const initial = 20 * time.Millisecond
+	rc := newReceiver(t, status(503), status(408), status(429), status(500))
+	d := newTestDispatcher(t, []Subscription{{URL: rc.url, Users: true}}, Options{InitialBackoff: initial, MaxAttempts: 10})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	waitFor(t, "重试后成功", func() bool { return len(rc.received()) == 5 && len(d.Pending()) == 0 })
+	if n := len(d.DeadLetters()); n != 0 {
+		t.Fatalf("死信 %d 条", n)
+	}
+
+	reqs := rc.received()
+	id := reqs[0].header.Get(HeaderDelivery)
+	for i, r := range reqs {
+		if r.header.Get(HeaderDelivery) != id {
+			t.Fatal("重试时投递 ID 改变")
+		}
+		if got := r.header.Get(HeaderAttempt); got != strconv.Itoa(i+1) {
+			t.Fatalf("第 %d 次请求的 %s 为 %s", i+1, HeaderAttempt, got)
+		}
+		if i == 0 {
+			continue
+		}
+		// 第 n 次失败后等待 initial*2^(n-1)，抖动 ±20%
+		least := time.Duration(float64(initial<<(i-1)) * 0.8)
+		if gap := r.at.Sub(reqs[i-1].at); gap < least {
+			t.Errorf("第 %d 次重试间隔 %v，期望至少 %v", i, gap, least)
+		}
+	}
+}
+
+func TestDispatcherRetryAfter(t *testing.T) {
+	rc := newReceiver(t, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	d := newTestDispatcher(t, []Subscription{{URL: rc.url, Users: true}}, Options{InitialBackoff: 10 * time.Millisecond})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	waitFor(t, "重试", func() bool { return len(rc.received()) == 2 })
+	reqs := rc.received()
+	if gap := reqs[1].at.Sub(reqs[0].at); gap < time.Second {
+		t.Fatalf("重试间隔 %v，未遵守 Retry-After", gap)
+	}
+}
+
+func TestDispatcherClientErrorDeadLetters(t *testing.T) {
+	for _, code := range []int{400, 401, 404, 410} {
+		t.Run(strconv.Itoa(code), func(t *testing.T) {
+			rc := newReceiver(t, status(code))
+			d := newTestDispatcher(t, []Subscription{{URL: rc.url, Users: true}}, Options{InitialBackoff: 10 * time.Millisecond})
+			d.Start()
+			defer d.Close()
+
+			d.Offline(offline)
+			waitFor(t, "转入死信", func() bool { return len(d.DeadLetters()) == 1 })
+			dead := d.DeadLetters()[0]
+			if dead.Attempts != 1 || !strings.Contains(dead.LastError, strconv.Itoa(code)) {
+				t.Fatalf("死信 %+v", dead)
+			}
+			if len(d.Pending()) != 0 || len(rc.received()) != 1 {
+				t.Fatalf("不应重试：队列 %d 条，请求 %d 次", len(d.Pending()), len(rc.received()))
+			}
+		})
+	}
+}
+
+// 无法构造请求时直接转入死信，不伪装成接收方的 400
+func TestDispatcherBadURLDeadLetters(t *testing.T) {
+	d := newTestDispatcher(t, []Subscription{{URL: "http://bad host/", Users: true}}, Options{InitialBackoff: 10 * time.Millisecond})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	waitFor(t, "转入死信", func() bool { return len(d.DeadLetters()) == 1 })
+	dead := d.DeadLetters()[0]
+	if dead.Attempts != 1 || !strings.Contains(dead.LastError, "无法构造回调请求") || strings.Contains(dead.LastError, "HTTP") {
+		t.Fatalf("死信 %+v", dead)
+	}
+	if n := len(d.Pending()); n != 0 {
+		t.Fatalf("不应重试：队列 %d 条", n)
+	}
+}
+
+func TestDispatcherMaxAttempts(t *testing.T) {
+	rc := newReceiver(t, status(500), status(500), status(500), status(500))
+	d := newTestDispatcher(t, []Subscription{{URL: rc.url, Users: true}}, Options{InitialBackoff: 5 * time.Millisecond, MaxAttempts: 3})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	waitFor(t, "转入死信", func() bool { return len(d.DeadLetters()) == 1 })
+	if dead := d.DeadLetters()[0]; dead.Attempts != 3 {
+		t.Fatalf("死信 %+v", dead)
+	}
+	if n := len(rc.received()); n != 3 {
+		t.Fatalf("请求 %d 次", n)
+	}
+}
+
+func TestDispatcherRedriveDiscard(t *testing.T) {
+	rc := newReceiver(t, status(400), status(400))
+	d := newTestDispatcher(t, []Subscription{{URL: rc.url, Users: true}}, Options{})
+	d.Start()
+	defer d.Close()
+
+	d.Offline(offline)
+	d.Offline(offline)
+	waitFor(t, "转入死信", func() bool { return len(d.DeadLetters()) == 2 })
+	dead := d.DeadLetters()
+
+	if d.Redrive("missing") || d.Discard("missing") {
+		t.Fatal("不存在的 id 应返回 false")
+	}
+	// 接收方已恢复，重新投递后成功，尝试次数从头计算
+	if !d.Redrive(dead[0].ID) {
+		t.Fatal("Redrive 失败")
+	}
+	waitFor(t, "重新投递", func() bool { return len(rc.received()) == 3 && len(d.Pending()) == 0 })
+	last := rc.received()[2]
+	if last.header.Get(HeaderDelivery) != dead[0].ID || last.header.Get(HeaderAttempt) != "1" {
+		t.Fatalf("重新投递的请求头 %v", last.header)
+	}
+
+	if !d.Discard(dead[1].ID) {
+		t.Fatal("Discard 失败")
+	}
+	if n := len(d.DeadLetters()); n != 0 {
+		t.Fatalf("死信剩余 %d 条", n)
+	}
+}
+
+func TestDispatcherQueueFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "webhooks.json")
+	rc := newReceiver(t, status(400))
+	subs := []Subscription{{URL: rc.url, Users: true}}
+
+	// 先产生一条死信，再在未启动时入队一条回调，关闭时写入快照
+	d1 := newTestDispatcher(t, subs, Options{QueueFile: file})
+	d1.Start()
+	d1.Offline(offline)
+	waitFor(t, "转入死信", func() bool { return len(d1.DeadLetters()) == 1 })
+	if err := d1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d2 := newTestDispatcher(t, subs, Options{QueueFile: file})
+	if err := d2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	d2.Offline(offline)
+	if err := d2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pending := d2.Pending()
+
+	// 重新加载后恢复队列与死信，并按原投递 ID 继续投递
+	d3 := newTestDispatcher(t, subs, Options{QueueFile: file})
+	if err := d3.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(d3.Pending()) != 1 || d3.Pending()[0].ID != pending[0].ID {
+		t.Fatalf("恢复的队列 %+v，期望 %+v", d3.Pending(), pending)
+	}
+	if dead := d3.DeadLetters(); len(dead) != 1 || dead[0].ID != d1.DeadLetters()[0].ID {
+		t.Fatalf("恢复的死信 %+v", dead)
+	}
+	d3.Start()
+	defer d3.Close()
+	waitFor(t, "恢复后投递", func() bool { return len(rc.received()) == 2 && len(d3.Pending()) == 0 })
+	if got := rc.received()[1].header.Get(HeaderDelivery); got != pending[0].ID {
+		t.Fatalf("投递 ID 为 %s，期望 %s", got, pending[0].ID)
+	}
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"sse/internal/ports"
+	"time"
+)
+
+// offlineHub 发布时没有命中在线客户端的消息转交给 sink，其余方法透传
+type offlineHub struct {
+	ports.Hub
+	tenant string
+	sink   ports.OfflineSink
+}
+
+// Offline 包装租户的 Hub，使无人接收的消息交给 sink 处理
+func Offline(hub ports.Hub, tenant string, sink ports.OfflineSink) ports.Hub {
+	return &offlineHub{Hub: hub, tenant: tenant, sink: sink}
+}
+
+func (h *offlineHub) undelivered(target string, p ports.Published, expiresAt time.Time) ports.Undelivered {
+	return ports.Undelivered{Tenant: h.tenant, EventID: p.EventID, Target: target, Time: time.Now(), ExpiresAt: ports.Expiry(expiresAt)}
+}
+
+func (h *offlineHub) Broadcast(name string, payload []byte, opts ports.PublishOptions) ports.Published {
+	p := h.Hub.Broadcast(name, payload, opts)
+	if p.Matched == 0 {
+		m := h.undelivered(ports.TargetTopic, p, opts.ExpiresAt)
+		m.Topic, m.Message = name, string(payload)
+		h.sink.Offline(m)
+	}
+	return p
+}
+
+func (h *offlineHub) PublishByUserId(userId int64, message string, opts ports.PublishOptions) ports.Published {
+	p := h.Hub.PublishByUserId(userId, message, opts)
+	if p.Matched == 0 {
+		m := h.undelivered(ports.TargetUser, p, opts.ExpiresAt)
+		m.UserId, m.Message = userId, message
+		h.sink.Offline(m)
+	}
+	return p
+}
+
+func (h *offlineHub) PublishByClientType(clientType string, message string, opts ports.PublishOptions) ports.Published {
+	p := h.Hub.PublishByClientType(clientType, message, opts)
+	if p.Matched == 0 {
+		m := h.undelivered(ports.TargetClientType, p, opts.ExpiresAt)
+		m.ClientType, m.Message = clientType, message
+		h.sink.Offline(m)
+	}
+	return p
+}
+
+func (h *offlineHub) PublishToClient(clientType string, userId int64, message string, opts ports.PublishOptions) ports.Published {
+	p := h.Hub.PublishToClient(clientType, userId, message, opts)
+	if p.Matched == 0 {
+		m := h.undelivered(ports.TargetClient, p, opts.ExpiresAt)
+		m.ClientType, m.UserId, m.Message = clientType, userId, message
+		h.sink.Offline(m)
+	}
+	return p
+}
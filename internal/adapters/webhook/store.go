@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sse/internal/ports"
+)
+
+// snapshot 队列快照文件的内容
+type snapshot struct {
+	Pending []ports.WebhookDelivery `json:"pending"`
+	Dead    []ports.WebhookDelivery `json:"dead"`
+}
+
+// Load 从 QueueFile 恢复上次退出时的队列与死信，文件不存在时不做任何事
+func (d *Dispatcher) Load() error {
+	if d.opts.QueueFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(d.opts.QueueFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, dl := range snap.Pending {
+		d.pending = append(d.pending, &entry{WebhookDelivery: dl})
+	}
+	for _, dl := range snap.Dead {
+		d.bury(dl)
+	}
+	d.gauge()
+	return nil
+}
+
+// Save 队列有变化时写入 QueueFile；先写临时文件再改名，避免留下写了一半的快照
+func (d *Dispatcher) Save() error {
+	if d.opts.QueueFile == "" {
+		return nil
+	}
+	d.mu.Lock()
+	if !d.dirty {
+		d.mu.Unlock()
+		return nil
+	}
+	snap := snapshot{Pending: make([]ports.WebhookDelivery, 0, len(d.pending)), Dead: d.dead}
+	for _, e := range d.pending {
+		snap.Pending = append(snap.Pending, e.WebhookDelivery)
+	}
+	data, err := json.Marshal(snap)
+	d.dirty = false
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.opts.QueueFile), filepath.Base(d.opts.QueueFile)+".*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), d.opts.QueueFile)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		// 下次再试
+		d.mu.Lock()
+		d.dirty = true
+		d.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"slices"
+	"sse/internal/ports"
+	"sse/pkg/topic"
+)
+
+// Subscription 一个回调地址及其关心的离线消息
+type Subscription struct {
+	URL string
+	// 签名密钥，为空时使用 Options.Secret
+	Secret string
+	// 限定租户，为空表示全部租户
+	Tenants []string
+	// 接收无人订阅的 topic 广播，nil 表示不接收
+	Topics *topic.Filter
+	// 接收按这些 clientType 发布（PublishByClientType / PublishToClient）且无人在线的消息
+	ClientTypes []string
+	// 接收按 userId 发布（PublishByUserId / PublishToClient）且用户不在线的消息
+	Users bool
+}
+
+// Match 判断离线消息是否需要回调到该地址
+func (s *Subscription) Match(m ports.Undelivered) bool {
+	if len(s.Tenants) > 0 && !slices.Contains(s.Tenants, m.Tenant) {
+		return false
+	}
+	switch m.Target {
+	case ports.TargetTopic:
+		return s.Topics != nil && s.Topics.Allow(m.Topic)
+	case ports.TargetUser:
+		return s.Users
+	case ports.TargetClientType:
+		return slices.Contains(s.ClientTypes, m.ClientType)
+	case ports.TargetClient:
+		return s.Users || slices.Contains(s.ClientTypes, m.ClientType)
+	}
+	return false
+}
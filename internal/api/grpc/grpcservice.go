@@ -1,57 +1,381 @@
-package __
+package grpcapi
 
 import (
 	"context"
+	"errors"
+	"sse/internal/adapters/tenant"
+	"sse/internal/app/publish"
 	"sse/internal/ports"
-	// 修改为生成的Go代码的包路径
+	pb "sse/pkg/ssepb"
+	"sse/pkg/topic"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
-	Hub ports.Hub // 用于处理消息的Hub
+	pb.UnimplementedMessageServiceServer
+
+	Tenants     ports.Tenants     // 按租户隔离的 Hub
+	Resolver    *tenant.Resolver  // 租户解析
+	Inbox       ports.Inbox       // 用户收件箱，nil 表示未启用
+	Scheduler   publish.Scheduler // 定时发布，nil 表示未启用
+	Idempotency ports.Idempotency // 发布幂等键记录，nil 表示未启用
 }
 
-func (s *Server) mustEmbedUnimplementedMessageServiceServer() {
-	// TODO implement me
-	panic("implement me")
+// publishStatus 把 publish.Dispatch / Batch 的错误转换为 gRPC 状态
+func publishStatus(err error) error {
+	switch {
+	case errors.Is(err, publish.ErrInboxDisabled), errors.Is(err, publish.ErrScheduleDisabled):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, publish.ErrInProgress):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, publish.ErrScheduleFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ports.ErrIdempotencyUnavailable), errors.Is(err, publish.ErrScheduleUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// idempotencyKey 请求字段中的幂等键，为空时使用 idempotency-key metadata
+func idempotencyKey(ctx context.Context, key string) string {
+	if key != "" {
+		return key
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return firstMD(md, "idempotency-key")
+}
+
+// timing 转换请求中成对的时间点与时长参数（deliverAt / delay、expiresAt / ttl），0 表示未设置
+func timing(unixMs, ms int64) (*time.Time, publish.Duration) {
+	var at *time.Time
+	if unixMs != 0 {
+		t := time.UnixMilli(unixMs)
+		at = &t
+	}
+	return at, publish.Duration(time.Duration(ms) * time.Millisecond)
 }
 
 // PublishByTopic 实现
-func (s *Server) PublishByTopic(ctx context.Context, req *PublishByTopicRequest) (*Empty, error) {
-	s.Hub.Broadcast(req.Topic, []byte(req.Message))
-	return &Empty{}, nil
+func (s *Server) PublishByTopic(ctx context.Context, req *pb.PublishByTopicRequest) (*pb.PublishResponse, error) {
+	name, hub, err := s.publishTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	at, delay := timing(req.DeliverAtUnixMs, req.DelayMs)
+	expiresAt, ttl := timing(req.ExpiresAtUnixMs, req.TtlMs)
+	t := publish.Target{Tenant: name, Hub: hub, Scheduler: s.Scheduler, Idempotency: s.Idempotency}
+	res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindTopic, Topic: req.Topic,
+		Message: req.Message, DeliverAt: at, Delay: delay,
+		ExpiresAt: expiresAt, TTL: ttl, Priority: req.Priority,
+		IdempotencyKey: idempotencyKey(ctx, req.IdempotencyKey)})
+	if err != nil {
+		return nil, publishStatus(err)
+	}
+	return toPublishResponse(res), nil
 }
 
 // PublishByUserId 实现
-func (s *Server) PublishByUserId(ctx context.Context, req *PublishByUserIdRequest) (*Empty, error) {
-	s.Hub.PublishByUserId(req.UserId, req.Message)
-	return &Empty{}, nil
+func (s *Server) PublishByUserId(ctx context.Context, req *pb.PublishByUserIdRequest) (*pb.PublishResponse, error) {
+	name, hub, err := s.publishTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	at, delay := timing(req.DeliverAtUnixMs, req.DelayMs)
+	expiresAt, ttl := timing(req.ExpiresAtUnixMs, req.TtlMs)
+	t := publish.Target{Tenant: name, Hub: hub, Inbox: s.Inbox, Scheduler: s.Scheduler, Idempotency: s.Idempotency}
+	res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindUser, UserId: req.UserId,
+		Message: req.Message, Persistent: req.Persistent, DeliverAt: at, Delay: delay,
+		ExpiresAt: expiresAt, TTL: ttl, Priority: req.Priority,
+		IdempotencyKey: idempotencyKey(ctx, req.IdempotencyKey)})
+	if err != nil {
+		return nil, publishStatus(err)
+	}
+	return toPublishResponse(res), nil
 }
 
 // PublishByClientType 实现
-func (s *Server) PublishByClientType(ctx context.Context, req *PublishByClientTypeRequest) (*Empty, error) {
-	s.Hub.PublishByClientType(req.ClientType, req.Message)
-	return &Empty{}, nil
+func (s *Server) PublishByClientType(ctx context.Context, req *pb.PublishByClientTypeRequest) (*pb.PublishResponse, error) {
+	name, hub, err := s.publishTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	at, delay := timing(req.DeliverAtUnixMs, req.DelayMs)
+	expiresAt, ttl := timing(req.ExpiresAtUnixMs, req.TtlMs)
+	t := publish.Target{Tenant: name, Hub: hub, Scheduler: s.Scheduler, Idempotency: s.Idempotency}
+	res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindClientType, ClientType: req.ClientType,
+		Message: req.Message, DeliverAt: at, Delay: delay,
+		ExpiresAt: expiresAt, TTL: ttl, Priority: req.Priority,
+		IdempotencyKey: idempotencyKey(ctx, req.IdempotencyKey)})
+	if err != nil {
+		return nil, publishStatus(err)
+	}
+	return toPublishResponse(res), nil
 }
 
 // PublishToClient 实现
-func (s *Server) PublishToClient(ctx context.Context, req *PublishToClientRequest) (*Empty, error) {
-	s.Hub.PublishToClient(req.ClientType, req.UserId, req.Message)
-	return &Empty{}, nil
+func (s *Server) PublishToClient(ctx context.Context, req *pb.PublishToClientRequest) (*pb.PublishResponse, error) {
+	name, hub, err := s.publishTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	at, delay := timing(req.DeliverAtUnixMs, req.DelayMs)
+	expiresAt, ttl := timing(req.ExpiresAtUnixMs, req.TtlMs)
+	t := publish.Target{Tenant: name, Hub: hub, Inbox: s.Inbox, Scheduler: s.Scheduler, Idempotency: s.Idempotency}
+	res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindClient, ClientType: req.ClientType,
+		UserId: req.UserId, Message: req.Message, Persistent: req.Persistent, DeliverAt: at, Delay: delay,
+		ExpiresAt: expiresAt, TTL: ttl, Priority: req.Priority,
+		IdempotencyKey: idempotencyKey(ctx, req.IdempotencyKey)})
+	if err != nil {
+		return nil, publishStatus(err)
+	}
+	return toPublishResponse(res), nil
+}
+
+// PublishBatch 实现，被限流时返回已接受的条数；中途失败（如定时消息保存失败）时同样返回已接受的条数，
+// 并在 error / errorCode 中给出原因，与 HTTP 接口一致。
+// 条目未带 idempotencyKey 时使用 "<idempotency-key metadata>/<下标>"
+func (s *Server) PublishBatch(ctx context.Context, req *pb.PublishBatchRequest) (*pb.PublishBatchResponse, error) {
+	name, err := s.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hub, err := s.Tenants.Hub(name)
+	if err != nil {
+		return nil, tenantError(err)
+	}
+
+	batchKey := idempotencyKey(ctx, "")
+	items := make([]publish.Request, 0, len(req.Items))
+	for i, item := range req.Items {
+		key := item.IdempotencyKey
+		if key == "" && batchKey != "" {
+			key = batchKey + "/" + strconv.Itoa(i)
+		}
+		at, delay := timing(item.DeliverAtUnixMs, item.DelayMs)
+		expiresAt, ttl := timing(item.ExpiresAtUnixMs, item.TtlMs)
+		items = append(items, publish.Request{
+			Kind:           item.Kind,
+			Topic:          item.Topic,
+			UserId:         item.UserId,
+			ClientType:     item.ClientType,
+			Message:        item.Message,
+			Persistent:     item.Persistent,
+			DeliverAt:      at,
+			Delay:          delay,
+			ExpiresAt:      expiresAt,
+			TTL:            ttl,
+			Priority:       item.Priority,
+			IdempotencyKey: key,
+		})
+	}
+	t := publish.Target{Tenant: name, Hub: hub, Inbox: s.Inbox, Scheduler: s.Scheduler, Idempotency: s.Idempotency}
+	results, err := publish.Batch(t, items, func() bool { return s.Tenants.AllowPublish(name) })
+	if err != nil && results == nil {
+		return nil, publishStatus(err)
+	}
+	resp := &pb.PublishBatchResponse{Accepted: int32(len(results)), Ids: make([]string, 0, len(results))}
+	scheduled, duplicate := false, false
+	for _, res := range results {
+		resp.Ids = append(resp.Ids, res.EventID)
+		scheduled = scheduled || res.ScheduledID != ""
+		duplicate = duplicate || res.Duplicate
+	}
+	if scheduled {
+		for _, res := range results {
+			resp.ScheduledIds = append(resp.ScheduledIds, res.ScheduledID)
+		}
+	}
+	if duplicate {
+		for _, res := range results {
+			resp.Duplicates = append(resp.Duplicates, res.Duplicate)
+		}
+	}
+	if err != nil {
+		st := status.Convert(publishStatus(err))
+		resp.Error, resp.ErrorCode = st.Message(), int32(st.Code())
+	}
+	return resp, nil
+}
+
+// Ack 实现，确认模式下确认事件
+func (s *Server) Ack(ctx context.Context, req *pb.AckRequest) (*pb.AckResponse, error) {
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AckResponse{Acked: int32(hub.Ack(req.UserId, req.ClientType, req.Ids))}, nil
+}
+
+// DeliveryStatus 实现，按事件 ID 查询投递状态
+func (s *Server) DeliveryStatus(ctx context.Context, req *pb.DeliveryStatusRequest) (*pb.DeliveryStatusResponse, error) {
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := hub.DeliveryStatus(req.Id)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "事件不存在或状态已过期")
+	}
+	return &pb.DeliveryStatusResponse{
+		Id:         st.EventID,
+		TimeUnixMs: st.Time.UnixMilli(),
+		Matched:    int32(st.Matched),
+		Delivered:  int32(st.Delivered),
+		Pending:    int32(st.Pending),
+		Acked:      int32(st.Acked),
+		Failed:     int32(st.Failed),
+		Expired:    int32(st.Expired),
+	}, nil
+}
+
+// SetSnapshot 实现，与发布共用限流
+func (s *Server) SetSnapshot(ctx context.Context, req *pb.SetSnapshotRequest) (*pb.Empty, error) {
+	hub, err := s.publishHub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = hub.SetSnapshot(req.Topic, req.Key, req.Message)
+	if errors.Is(err, ports.ErrTooManySnapshots) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+// ClearSnapshot 实现
+func (s *Server) ClearSnapshot(ctx context.Context, req *pb.ClearSnapshotRequest) (*pb.ClearSnapshotResponse, error) {
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ClearSnapshotResponse{Removed: int32(hub.ClearSnapshot(req.Topic, req.Key))}, nil
+}
+
+// GetSnapshot 实现
+func (s *Server) GetSnapshot(ctx context.Context, req *pb.GetSnapshotRequest) (*pb.GetSnapshotResponse, error) {
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := hub.Snapshot(req.Topic)
+	resp := &pb.GetSnapshotResponse{Entries: make([]*pb.SnapshotEntry, 0, len(entries))}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &pb.SnapshotEntry{Key: e.Key, Message: e.Message, TimeUnixMs: e.Time.UnixMilli()})
+	}
+	return resp, nil
+}
+
+// Presence 实现
+func (s *Server) Presence(ctx context.Context, req *pb.PresenceRequest) (*pb.PresenceResponse, error) {
+	if err := topic.ValidateTopic(req.Topic); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := hub.Presence(req.Topic)
+	resp := &pb.PresenceResponse{Users: make([]*pb.PresenceUser, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, toPresenceUser(u))
+	}
+	return resp, nil
+}
+
+// IsOnline 实现
+func (s *Server) IsOnline(ctx context.Context, req *pb.IsOnlineRequest) (*pb.IsOnlineResponse, error) {
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, online := hub.IsOnline(req.UserId)
+	return &pb.IsOnlineResponse{Online: online, User: toPresenceUser(user)}, nil
+}
+
+func toPresenceUser(u ports.PresenceUser) *pb.PresenceUser {
+	return &pb.PresenceUser{UserId: u.UserId, Connections: int32(u.Connections), ClientTypes: u.ClientTypes}
+}
+
+// ListScheduled 实现
+func (s *Server) ListScheduled(ctx context.Context, req *pb.ListScheduledRequest) (*pb.ListScheduledResponse, error) {
+	if s.Scheduler == nil {
+		return nil, status.Error(codes.Unimplemented, publish.ErrScheduleDisabled.Error())
+	}
+	name, err := s.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := s.Scheduler.List(name)
+	resp := &pb.ListScheduledResponse{Items: make([]*pb.ScheduledMessage, 0, len(list))}
+	for _, sc := range list {
+		r := sc.Request
+		resp.Items = append(resp.Items, &pb.ScheduledMessage{
+			Id:              sc.ID,
+			DeliverAtUnixMs: sc.DeliverAt.UnixMilli(),
+			CreatedAtUnixMs: sc.CreatedAt.UnixMilli(),
+			Request: &pb.PublishRequest{Kind: r.Kind, Topic: r.Topic, UserId: r.UserId,
+				ClientType: r.ClientType, Message: r.Message, Persistent: r.Persistent,
+				ExpiresAtUnixMs: unixMs(r.ExpiresAt), TtlMs: time.Duration(r.TTL).Milliseconds(),
+				Priority: r.Priority},
+		})
+	}
+	return resp, nil
+}
+
+// CancelScheduled 实现
+func (s *Server) CancelScheduled(ctx context.Context, req *pb.CancelScheduledRequest) (*pb.CancelScheduledResponse, error) {
+	if s.Scheduler == nil {
+		return nil, status.Error(codes.Unimplemented, publish.ErrScheduleDisabled.Error())
+	}
+	name, err := s.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CancelScheduledResponse{Cancelled: s.Scheduler.Cancel(name, req.Id)}, nil
+}
+
+// unixMs nil 转换为 0
+func unixMs(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func toPublishResponse(res ports.Published) *pb.PublishResponse {
+	resp := &pb.PublishResponse{Id: res.EventID, Matched: int32(res.Matched), ScheduledId: res.ScheduledID, Duplicate: res.Duplicate}
+	if res.DeliverAt != nil {
+		resp.DeliverAtUnixMs = res.DeliverAt.UnixMilli()
+	}
+	return resp
 }
 
 // Status 实现
-func (s *Server) Status(ctx context.Context, req *StatusRequest) (*StatusResponse, error) {
-	_ = s.Hub.Stats()
+func (s *Server) Status(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	hub, err := s.hub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := hub.Stats()
 
 	// 构建响应
-	response := &StatusResponse{}
-	//
-	// for _, stat := range stats {
-	// 	response.Stats = append(response.Stats, &ClientStat{
-	// 		ClientId: stat.ClientID,
-	// 		Status:   stat.Type,
-	// 	})
-	// }
+	response := &pb.StatusResponse{Stats: make([]*pb.ClientStat, 0, len(stats))}
+	for _, stat := range stats {
+		response.Stats = append(response.Stats, &pb.ClientStat{
+			ClientId:   strconv.FormatInt(stat.ClientID, 10),
+			Status:     "connected",
+			UserId:     stat.UserID,
+			ClientType: stat.Type,
+			Transport:  stat.Transport,
+		})
+	}
 
 	return response, nil
 }
@@ -0,0 +1,21 @@
+package grpcapi
+
+import (
+	"context"
+	"sse/pkg/health"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthServer 标准 gRPC 健康检查，状态与 /readyz 一致
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	hc *health.Health
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if s.hc.Ready().Ready {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+}
@@ -0,0 +1,20 @@
+package grpcapi
+
+import (
+	"context"
+	"sse/pkg/metrics"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// metricsInterceptor 统计每个方法的请求数、状态码与耗时
+func metricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	metrics.GRPCDuration.With(info.FullMethod).Observe(time.Since(start).Seconds())
+	metrics.GRPCRequests.With(info.FullMethod, status.Code(err).String()).Inc()
+	return resp, err
+}
@@ -1,26 +1,41 @@
-package __
+package grpcapi
 
 import (
 	"google.golang.org/grpc"
-	"log"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log/slog"
 	"net"
+	"os"
+	"sse/internal/adapters/tenant"
+	"sse/internal/app/publish"
 	"sse/internal/ports"
+	"sse/pkg/health"
+	pb "sse/pkg/ssepb"
 	"sync"
 )
 
-func Run(wg *sync.WaitGroup, hub ports.Hub) {
+// Run 监听并启动 gRPC 服务，返回的 *grpc.Server 用于优雅关闭
+func Run(wg *sync.WaitGroup, tenants ports.Tenants, resolver *tenant.Resolver, inbox ports.Inbox, scheduler publish.Scheduler, idempotency ports.Idempotency, hc *health.Health, log *slog.Logger) *grpc.Server {
+	hc.Expect("grpc")
 	lis, err := net.Listen("tcp", ":50051")
 	if err != nil {
-		log.Fatalf("failed to listen on port 50051: %v", err)
+		log.Error("failed to listen on port 50051", "err", err)
+		os.Exit(1)
 	}
-	grpcServer := grpc.NewServer()
-	RegisterMessageServiceServer(grpcServer, &Server{Hub: hub})
+	hc.Set("grpc", nil)
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(metricsInterceptor))
+	pb.RegisterMessageServiceServer(grpcServer, &Server{Tenants: tenants, Resolver: resolver, Inbox: inbox, Scheduler: scheduler, Idempotency: idempotency})
+	healthpb.RegisterHealthServer(grpcServer, &healthServer{hc: hc})
 	// 启动gRPC服务器的goroutine
 	go func() {
 		defer wg.Done()
-		log.Println("gRPC server is running on :50051")
+		log.Info("gRPC server is running on :50051")
 		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatalf("failed to serve gRPC: %s", err)
+			hc.Set("grpc", err)
+			log.Error("failed to serve gRPC", "err", err)
+			os.Exit(1)
 		}
 	}()
+	return grpcServer
 }
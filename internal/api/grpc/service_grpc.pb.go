@@ -1,273 +0,0 @@
-// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
-// versions:
-// - protoc-gen-go-grpc v1.5.1
-// - protoc             v3.19.4
-// source: service.proto
-
-package __
-
-import (
-	context "context"
-	grpc "google.golang.org/grpc"
-	codes "google.golang.org/grpc/codes"
-	status "google.golang.org/grpc/status"
-)
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.64.0 or later.
-const _ = grpc.SupportPackageIsVersion9
-
-const (
-	MessageService_PublishByTopic_FullMethodName      = "/grpc.MessageService/PublishByTopic"
-	MessageService_PublishByUserId_FullMethodName     = "/grpc.MessageService/PublishByUserId"
-	MessageService_PublishByClientType_FullMethodName = "/grpc.MessageService/PublishByClientType"
-	MessageService_PublishToClient_FullMethodName     = "/grpc.MessageService/PublishToClient"
-	MessageService_Status_FullMethodName              = "/grpc.MessageService/Status"
-)
-
-// MessageServiceClient is the client API for MessageService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type MessageServiceClient interface {
-	PublishByTopic(ctx context.Context, in *PublishByTopicRequest, opts ...grpc.CallOption) (*Empty, error)
-	PublishByUserId(ctx context.Context, in *PublishByUserIdRequest, opts ...grpc.CallOption) (*Empty, error)
-	PublishByClientType(ctx context.Context, in *PublishByClientTypeRequest, opts ...grpc.CallOption) (*Empty, error)
-	PublishToClient(ctx context.Context, in *PublishToClientRequest, opts ...grpc.CallOption) (*Empty, error)
-	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
-}
-
-type messageServiceClient struct {
-	cc grpc.ClientConnInterface
-}
-
-func NewMessageServiceClient(cc grpc.ClientConnInterface) MessageServiceClient {
-	return &messageServiceClient{cc}
-}
-
-func (c *messageServiceClient) PublishByTopic(ctx context.Context, in *PublishByTopicRequest, opts ...grpc.CallOption) (*Empty, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, MessageService_PublishByTopic_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *messageServiceClient) PublishByUserId(ctx context.Context, in *PublishByUserIdRequest, opts ...grpc.CallOption) (*Empty, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, MessageService_PublishByUserId_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *messageServiceClient) PublishByClientType(ctx context.Context, in *PublishByClientTypeRequest, opts ...grpc.CallOption) (*Empty, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, MessageService_PublishByClientType_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *messageServiceClient) PublishToClient(ctx context.Context, in *PublishToClientRequest, opts ...grpc.CallOption) (*Empty, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, MessageService_PublishToClient_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *messageServiceClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(StatusResponse)
-	err := c.cc.Invoke(ctx, MessageService_Status_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-// MessageServiceServer is the server API for MessageService service.
-// All implementations must embed UnimplementedMessageServiceServer
-// for forward compatibility.
-type MessageServiceServer interface {
-	PublishByTopic(context.Context, *PublishByTopicRequest) (*Empty, error)
-	PublishByUserId(context.Context, *PublishByUserIdRequest) (*Empty, error)
-	PublishByClientType(context.Context, *PublishByClientTypeRequest) (*Empty, error)
-	PublishToClient(context.Context, *PublishToClientRequest) (*Empty, error)
-	Status(context.Context, *StatusRequest) (*StatusResponse, error)
-	mustEmbedUnimplementedMessageServiceServer()
-}
-
-// UnimplementedMessageServiceServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedMessageServiceServer struct{}
-
-func (UnimplementedMessageServiceServer) PublishByTopic(context.Context, *PublishByTopicRequest) (*Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method PublishByTopic not implemented")
-}
-func (UnimplementedMessageServiceServer) PublishByUserId(context.Context, *PublishByUserIdRequest) (*Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method PublishByUserId not implemented")
-}
-func (UnimplementedMessageServiceServer) PublishByClientType(context.Context, *PublishByClientTypeRequest) (*Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method PublishByClientType not implemented")
-}
-func (UnimplementedMessageServiceServer) PublishToClient(context.Context, *PublishToClientRequest) (*Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method PublishToClient not implemented")
-}
-func (UnimplementedMessageServiceServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
-}
-func (UnimplementedMessageServiceServer) mustEmbedUnimplementedMessageServiceServer() {}
-func (UnimplementedMessageServiceServer) testEmbeddedByValue()                        {}
-
-// UnsafeMessageServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to MessageServiceServer will
-// result in compilation errors.
-type UnsafeMessageServiceServer interface {
-	mustEmbedUnimplementedMessageServiceServer()
-}
-
-func RegisterMessageServiceServer(s grpc.ServiceRegistrar, srv MessageServiceServer) {
-	// If the following call pancis, it indicates UnimplementedMessageServiceServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
-	}
-	s.RegisterService(&MessageService_ServiceDesc, srv)
-}
-
-func _MessageService_PublishByTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PublishByTopicRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MessageServiceServer).PublishByTopic(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MessageService_PublishByTopic_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MessageServiceServer).PublishByTopic(ctx, req.(*PublishByTopicRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-func _MessageService_PublishByUserId_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PublishByUserIdRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MessageServiceServer).PublishByUserId(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MessageService_PublishByUserId_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MessageServiceServer).PublishByUserId(ctx, req.(*PublishByUserIdRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-func _MessageService_PublishByClientType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PublishByClientTypeRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MessageServiceServer).PublishByClientType(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MessageService_PublishByClientType_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MessageServiceServer).PublishByClientType(ctx, req.(*PublishByClientTypeRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-func _MessageService_PublishToClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PublishToClientRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MessageServiceServer).PublishToClient(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MessageService_PublishToClient_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MessageServiceServer).PublishToClient(ctx, req.(*PublishToClientRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-func _MessageService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StatusRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MessageServiceServer).Status(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MessageService_Status_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MessageServiceServer).Status(ctx, req.(*StatusRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-// MessageService_ServiceDesc is the grpc.ServiceDesc for MessageService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var MessageService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "grpc.MessageService",
-	HandlerType: (*MessageServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "PublishByTopic",
-			Handler:    _MessageService_PublishByTopic_Handler,
-		},
-		{
-			MethodName: "PublishByUserId",
-			Handler:    _MessageService_PublishByUserId_Handler,
-		},
-		{
-			MethodName: "PublishByClientType",
-			Handler:    _MessageService_PublishByClientType_Handler,
-		},
-		{
-			MethodName: "PublishToClient",
-			Handler:    _MessageService_PublishToClient_Handler,
-		},
-		{
-			MethodName: "Status",
-			Handler:    _MessageService_Status_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "service.proto",
-}
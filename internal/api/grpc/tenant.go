@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"sse/internal/adapters/tenant"
+	"sse/internal/ports"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 从 metadata 读取第一个值
+func firstMD(md metadata.MD, key string) string {
+	if v := md.Get(strings.ToLower(key)); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// resolveTenant 从请求 metadata 解析租户；gRPC 没有路径前缀，path 模式退化为读取租户请求头
+func (s *Server) resolveTenant(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var (
+		name string
+		err  error
+	)
+	switch s.Resolver.Mode {
+	case tenant.ModeHeader, tenant.ModePath:
+		name = firstMD(md, s.Resolver.Header)
+	case tenant.ModeHost:
+		name, err = s.Resolver.FromHost(firstMD(md, ":authority"))
+	case tenant.ModeToken:
+		name, err = s.Resolver.FromToken(firstMD(md, "authorization"))
+	}
+
+	if errors.Is(err, tenant.ErrInvalidToken) {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	if name == "" {
+		name = ports.DefaultTenant
+	}
+	return name, nil
+}
+
+// tenantError 把 Tenants.Hub 的错误转换为 gRPC 状态
+func tenantError(err error) error {
+	if errors.Is(err, ports.ErrTooManyTenants) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.NotFound, err.Error())
+}
+
+// hub 获取请求所属租户的 Hub
+func (s *Server) hub(ctx context.Context) (ports.Hub, error) {
+	name, err := s.resolveTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hub, err := s.Tenants.Hub(name)
+	if err != nil {
+		return nil, tenantError(err)
+	}
+	return hub, nil
+}
+
+// publishHub 获取请求所属租户的 Hub，并执行租户发布限流
+func (s *Server) publishHub(ctx context.Context) (ports.Hub, error) {
+	_, hub, err := s.publishTarget(ctx)
+	return hub, err
+}
+
+// publishTarget 与 publishHub 相同，另外返回租户名
+func (s *Server) publishTarget(ctx context.Context) (string, ports.Hub, error) {
+	name, err := s.resolveTenant(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	hub, err := s.Tenants.Hub(name)
+	if err != nil {
+		return "", nil, tenantError(err)
+	}
+	if !s.Tenants.AllowPublish(name) {
+		return "", nil, status.Error(codes.ResourceExhausted, "发布频率超过配额")
+	}
+	return name, hub, nil
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sse/internal/ports"
+	"strings"
+)
+
+type AckBody struct {
+	Ids []string `json:"ids"`
+}
+
+type AckResult struct {
+	// 实际确认的条数，已确认或已放弃的事件不计入
+	Acked int `json:"acked"`
+}
+
+// ackMode 连接参数 ack=1 / true 时开启确认模式
+func ackMode(r *http.Request) bool {
+	v := r.URL.Query().Get("ack")
+	return v == "1" || v == "true"
+}
+
+// SseAck 确认模式下确认事件；userId 与 clientType 与建立连接时一致，
+// ids 来自下发内容中的 eventId，可放在请求体或逗号分隔的 ids 查询参数中
+func SseAck(tenants ports.Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+			return
+		}
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+		userId, _ := parseUserID(r)
+		clientType, err := parseClientType(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body AckBody
+		if v := r.URL.Query().Get("ids"); v != "" {
+			for _, id := range strings.Split(v, ",") {
+				body.Ids = append(body.Ids, strings.TrimSpace(id))
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(AckResult{Acked: hub.Ack(userId, clientType, body.Ids)})
+	}
+}
+
+// Delivery 按发布时返回的事件 ID 查询投递状态
+func Delivery(tenants ports.Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id 查询参数不存在", http.StatusBadRequest)
+			return
+		}
+		st, ok := hub.DeliveryStatus(id)
+		if !ok {
+			http.Error(w, "事件不存在或状态已过期", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(st)
+	}
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sse/internal/ports"
+	"sse/pkg/config"
+	"strconv"
+	"strings"
+)
+
+// requireAdmin 校验 /admin/* 的 Bearer token，token 为空时不校验
+func requireAdmin(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "未授权", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminConfig 以 YAML 返回当前生效的配置（含热更新，已隐藏敏感信息）
+func AdminConfig() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		if err := config.Current().Print(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// adminTenant 读取 /admin/* 的 tenant 查询参数，缺省为默认租户
+func adminTenant(r *http.Request) string {
+	if name := r.URL.Query().Get("tenant"); name != "" {
+		return name
+	}
+	return ports.DefaultTenant
+}
+
+type KickResult struct {
+	Kicked []int64 `json:"kicked"`
+}
+
+// AdminKick 断开指定租户下的客户端：clientId 断开单个连接，userId 断开该用户的全部连接
+func AdminKick(tenants ports.Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+			return
+		}
+		hub, err := tenants.Hub(adminTenant(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		var ids []int64
+		q := r.URL.Query()
+		switch {
+		case q.Get("clientId") != "":
+			id, err := strconv.ParseInt(q.Get("clientId"), 10, 64)
+			if err != nil {
+				http.Error(w, "无效的 clientId", http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, id)
+		case q.Get("userId") != "":
+			userId, err := parseUserID(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, s := range hub.Stats() {
+				if s.UserID == userId {
+					ids = append(ids, s.ClientID)
+				}
+			}
+		default:
+			http.Error(w, "需要 clientId 或 userId 查询参数", http.StatusBadRequest)
+			return
+		}
+
+		result := KickResult{Kicked: make([]int64, 0, len(ids))}
+		for _, id := range ids {
+			if hub.Kick(id) {
+				result.Kicked = append(result.Kicked, id)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// AdminHistory 按 topic 读取已持久化的消息，after 为上次读到的最大 ID
+func AdminHistory(store ports.History) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if store == nil {
+			http.Error(w, "未启用持久化", http.StatusNotImplemented)
+			return
+		}
+		q := r.URL.Query()
+		name := q.Get("topic")
+		if name == "" {
+			http.Error(w, "topic 查询参数不存在", http.StatusBadRequest)
+			return
+		}
+		var after uint64
+		if v := q.Get("after"); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				http.Error(w, "无效的 after", http.StatusBadRequest)
+				return
+			}
+			after = n
+		}
+		limit := 100
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "无效的 limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(store.Range(adminTenant(r), name, after, limit))
+	}
+}
+
+// AdminWebhookQueue 列出等待投递或重试中的 webhook 回调
+func AdminWebhookQueue(hooks ports.Webhooks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if hooks == nil {
+			http.Error(w, "未启用 webhook", http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(hooks.Pending())
+	}
+}
+
+// AdminWebhookDead GET 列出死信，DELETE ?id= 删除一条死信
+func AdminWebhookDead(hooks ports.Webhooks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if hooks == nil {
+			http.Error(w, "未启用 webhook", http.StatusNotImplemented)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(hooks.DeadLetters())
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				http.Error(w, "id 查询参数不存在", http.StatusBadRequest)
+				return
+			}
+			if !hooks.Discard(id) {
+				http.Error(w, "死信不存在", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "仅支持 GET 与 DELETE", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminWebhookRetry 把 ?id= 指定的死信放回队列立即重投
+func AdminWebhookRetry(hooks ports.Webhooks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if hooks == nil {
+			http.Error(w, "未启用 webhook", http.StatusNotImplemented)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+			return
+		}
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id 查询参数不存在", http.StatusBadRequest)
+			return
+		}
+		if !hooks.Redrive(id) {
+			http.Error(w, "死信不存在", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sse/internal/ports"
+	"strconv"
+	"strings"
+)
+
+type InboxAckBody struct {
+	Ids []uint64 `json:"ids"`
+}
+
+type InboxResult struct {
+	// 确认或清空时实际删除的条数
+	Removed int `json:"removed"`
+}
+
+// Inbox GET 列出用户未确认的消息，DELETE 清空用户的收件箱
+func Inbox(inbox ports.Inbox) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if inbox == nil {
+			http.Error(w, "未启用离线收件箱", http.StatusNotImplemented)
+			return
+		}
+		userId, err := parseUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(inbox.List(tenantFrom(r), userId))
+		case http.MethodDelete:
+			_ = json.NewEncoder(w).Encode(InboxResult{Removed: inbox.Clear(tenantFrom(r), userId)})
+		default:
+			http.Error(w, "仅支持 GET 与 DELETE", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// InboxAck 确认收件箱中的消息；ids 来自下发内容中的 inboxId，可放在请求体或逗号分隔的 ids 查询参数中
+func InboxAck(inbox ports.Inbox) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if inbox == nil {
+			http.Error(w, "未启用离线收件箱", http.StatusNotImplemented)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+			return
+		}
+		userId, err := parseUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body InboxAckBody
+		if v := r.URL.Query().Get("ids"); v != "" {
+			for _, s := range strings.Split(v, ",") {
+				id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+				if err != nil {
+					http.Error(w, "无效的 ids", http.StatusBadRequest)
+					return
+				}
+				body.Ids = append(body.Ids, id)
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(InboxResult{Removed: inbox.Ack(tenantFrom(r), userId, body.Ids)})
+	}
+}
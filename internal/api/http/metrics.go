@@ -0,0 +1,66 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sse/pkg/metrics"
+	"strconv"
+	"time"
+)
+
+// statusRecorder 记录响应状态码，同时透传 SSE 依赖的 Flusher / CloseNotifier 与 WebSocket 依赖的 Hijacker
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) CloseNotify() <-chan bool {
+	if cn, ok := s.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("底层 ResponseWriter 不支持 Hijack")
+	}
+	// 升级成功即视为 101
+	s.code = http.StatusSwitchingProtocols
+	return hj.Hijack()
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// metricsMiddleware 按路由模式统计请求数与耗时，未匹配的路径归为 other 以限制基数
+func metricsMiddleware(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := "other"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			path = pattern
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		start := time.Now()
+		mux.ServeHTTP(rec, r)
+
+		metrics.HTTPDuration.With(path).Observe(time.Since(start).Seconds())
+		metrics.HTTPRequests.With(path, strconv.Itoa(rec.code)).Inc()
+	})
+}
@@ -0,0 +1,320 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sse/internal/ports"
+	"sse/pkg/heartbeat"
+	"sse/pkg/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PollOptions 长轮询参数，零值字段使用默认值
+type PollOptions struct {
+	// 单次轮询无事件时的最长挂起时间
+	Timeout time.Duration
+	// 两次轮询之间会话的保留时间，超时后从 Hub 移除
+	SessionTTL time.Duration
+	// 每个会话缓存的最大事件数，超出时丢弃最旧的
+	BufferSize int
+}
+
+func (o PollOptions) withDefaults() PollOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = 25 * time.Second
+	}
+	if o.SessionTTL <= 0 {
+		o.SessionTTL = time.Minute
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 1000
+	}
+	return o
+}
+
+// pollEvent 长轮询返回的事件，字段与 SSE 事件一致
+type pollEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  string `json:"data"`
+
+	seq uint64
+}
+
+// PollResponse 一次轮询的结果；下次轮询携带 session 与 cursor，cursor 及之前的事件视为已确认
+type PollResponse struct {
+	Session string      `json:"session"`
+	Cursor  uint64      `json:"cursor"`
+	Events  []pollEvent `json:"events"`
+	// 会话已被服务端关闭（踢出或慢客户端），客户端需要重新建立会话
+	Closed bool `json:"closed,omitempty"`
+}
+
+// pollSession 在两次轮询之间持有 Hub 客户端并缓存事件
+type pollSession struct {
+	id     string
+	tenant string
+	hub    ports.Hub
+	client *ports.Client
+	log    *slog.Logger
+
+	mu     sync.Mutex
+	events []pollEvent
+	seq    uint64
+	// 有新事件或会话关闭时关闭并替换，唤醒等待中的轮询
+	notify   chan struct{}
+	closed   bool
+	expired  bool
+	active   int
+	lastSeen time.Time
+}
+
+// drain 持续把 Hub 投递的消息搬到会话缓存，Hub 关闭通道后标记会话关闭
+func (s *pollSession) drain(client *ports.Client, events <-chan []byte, limit int) {
+	for msg := range events {
+		event, msg, ok := client.Take(msg)
+		// 过期的消息不下发
+		if !ok {
+			continue
+		}
+		if event == "" {
+			event = "message"
+		}
+		// 长轮询本身就是保活，心跳不下发
+		if heartbeat.IsPayload(msg) {
+			continue
+		}
+		s.mu.Lock()
+		s.seq++
+		s.events = append(s.events, pollEvent{ID: strconv.FormatUint(s.seq, 10), Event: event, Data: string(msg), seq: s.seq})
+		if over := len(s.events) - limit; over > 0 {
+			s.events = s.events[over:]
+			metrics.MessagesDropped.With(metrics.DropPollBuf).Add(float64(over))
+		}
+		s.wake()
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	expired := s.expired
+	s.wake()
+	s.mu.Unlock()
+
+	reason := metrics.ReasonServerClose
+	if expired {
+		reason = metrics.ReasonExpired
+	}
+	metrics.Disconnects.With(reason).Inc()
+	s.log.Info("轮询会话结束", "reason", reason)
+}
+
+// wake 唤醒等待者，调用方需持有 mu
+func (s *pollSession) wake() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// wait 确认 cursor 及之前的事件，然后等待新事件、会话关闭、超时或请求取消
+func (s *pollSession) wait(ctx context.Context, cursor uint64, timeout time.Duration) PollResponse {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active++
+	defer func() {
+		s.active--
+		s.lastSeen = time.Now()
+	}()
+
+	i := 0
+	for i < len(s.events) && s.events[i].seq <= cursor {
+		i++
+	}
+	s.events = s.events[i:]
+
+	for len(s.events) == 0 && !s.closed {
+		notify := s.notify
+		s.mu.Unlock()
+		done := false
+		select {
+		case <-notify:
+		case <-timer.C:
+			done = true
+		case <-ctx.Done():
+			done = true
+		}
+		s.mu.Lock()
+		if done {
+			break
+		}
+	}
+
+	resp := PollResponse{Session: s.id, Cursor: cursor, Events: append([]pollEvent{}, s.events...), Closed: s.closed}
+	if n := len(resp.Events); n > 0 {
+		resp.Cursor = resp.Events[n-1].seq
+	}
+	return resp
+}
+
+// pollSessions 会话表，定期清理长时间未轮询的会话
+type pollSessions struct {
+	opts PollOptions
+	log  *slog.Logger
+
+	mu       sync.Mutex
+	sessions map[string]*pollSession
+}
+
+func newPollSessions(opts PollOptions, log *slog.Logger) *pollSessions {
+	ps := &pollSessions{opts: opts.withDefaults(), log: log, sessions: make(map[string]*pollSession)}
+	go ps.janitor()
+	return ps
+}
+
+func (ps *pollSessions) create(tenant string, hub ports.Hub, client *ports.Client, log *slog.Logger) *pollSession {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	s := &pollSession{
+		id:       hex.EncodeToString(b[:]),
+		tenant:   tenant,
+		hub:      hub,
+		client:   client,
+		notify:   make(chan struct{}),
+		lastSeen: time.Now(),
+	}
+	s.log = log.With("session", s.id)
+	go s.drain(client, client.SendCh, ps.opts.BufferSize)
+
+	ps.mu.Lock()
+	ps.sessions[s.id] = s
+	ps.mu.Unlock()
+	return s
+}
+
+func (ps *pollSessions) get(id string) *pollSession {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.sessions[id]
+}
+
+func (ps *pollSessions) delete(id string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	delete(ps.sessions, id)
+}
+
+// janitor 移除超过 SessionTTL 未轮询的会话，Hub 关闭通道后 drain 协程随之退出
+func (ps *pollSessions) janitor() {
+	ticker := time.NewTicker(ps.opts.SessionTTL / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		var expired []*pollSession
+		ps.mu.Lock()
+		for id, s := range ps.sessions {
+			s.mu.Lock()
+			if s.closed || s.active == 0 && time.Since(s.lastSeen) > ps.opts.SessionTTL {
+				s.expired = !s.closed
+				expired = append(expired, s)
+				delete(ps.sessions, id)
+			}
+			s.mu.Unlock()
+		}
+		ps.mu.Unlock()
+
+		for _, s := range expired {
+			s.hub.Remove(s.client)
+		}
+	}
+}
+
+// Poll 长轮询：首次请求按 SSE 的参数建立会话，之后携带 session 与 cursor 续取
+func Poll(sessions *pollSessions, tenants ports.Tenants, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		q := r.URL.Query()
+		var cursor uint64
+		if v := q.Get("cursor"); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				http.Error(w, "无效的 cursor", http.StatusBadRequest)
+				return
+			}
+			cursor = n
+		}
+		timeout := sessions.opts.Timeout
+		if v := q.Get("timeout"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				timeout = min(time.Duration(n)*time.Second, timeout)
+			}
+		}
+
+		name := tenantFrom(r)
+		var s *pollSession
+		if id := q.Get("session"); id != "" {
+			// 会话不跨租户复用
+			if s = sessions.get(id); s == nil || s.tenant != name {
+				http.Error(w, "会话不存在或已过期", http.StatusNotFound)
+				return
+			}
+		} else {
+			if s, ok = newPollSession(w, r, sessions, hub, logger); !ok {
+				return
+			}
+		}
+
+		resp := s.wait(r.Context(), cursor, timeout)
+		if resp.Closed {
+			sessions.delete(s.id)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// newPollSession 按 userId / clientType / topics 注册轮询客户端并建立会话
+func newPollSession(w http.ResponseWriter, r *http.Request, sessions *pollSessions, hub ports.Hub, logger *slog.Logger) (*pollSession, bool) {
+	userId, _ := parseUserID(r)
+	clientType, err := parseClientType(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	topics, err := parseTopics(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	client, err := hub.NewClient(userId, clientType, topics, filter, ports.TransportPoll)
+	if err != nil {
+		rejectConnect(w, err)
+		return nil, false
+	}
+	metrics.Connects.With(clientType).Inc()
+	if ackMode(r) {
+		hub.EnableAck(client)
+	}
+
+	log := logger.With("tenant", tenantFrom(r), "clientId", client.ID, "userId", userId,
+		"clientType", clientType, "transport", ports.TransportPoll)
+	return sessions.create(tenantFrom(r), hub, client, log), true
+}
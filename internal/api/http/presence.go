@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sse/internal/ports"
+	"sse/pkg/topic"
+)
+
+type PresenceTopicResult struct {
+	Topic string               `json:"topic"`
+	Users []ports.PresenceUser `json:"users"`
+}
+
+type PresenceUserResult struct {
+	Online bool `json:"online"`
+	ports.PresenceUser
+}
+
+// Presence 在线状态：GET ?topic= 列出精确订阅了该主题的在线用户（不含通配订阅），
+// GET ?userId= 查询用户是否在线；同一用户的多个设备合并为一条
+func Presence(tenants ports.Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "仅支持 GET", http.StatusMethodNotAllowed)
+			return
+		}
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+		q := r.URL.Query()
+
+		var result any
+		switch {
+		case q.Get("topic") != "":
+			name := q.Get("topic")
+			if err := topic.ValidateTopic(name); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result = PresenceTopicResult{Topic: name, Users: hub.Presence(name)}
+		case q.Has("userId"):
+			userId, err := parseUserID(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			user, online := hub.IsOnline(userId)
+			result = PresenceUserResult{Online: online, PresenceUser: user}
+		default:
+			http.Error(w, "需要 topic 或 userId 查询参数", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
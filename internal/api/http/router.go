@@ -2,12 +2,21 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"sse/internal/adapters/tenant"
+	"sse/internal/app/publish"
 	"sse/internal/ports"
+	"sse/pkg/filter"
+	"sse/pkg/health"
+	"sse/pkg/heartbeat"
+	"sse/pkg/metrics"
+	"sse/pkg/topic"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // 解析用户 ID
@@ -37,11 +46,31 @@ func parseTopics(r *http.Request) ([]string, error) {
 	topics := strings.Split(topicsStr, ",")
 	for i := range topics {
 		topics[i] = strings.TrimSpace(topics[i])
+		if err := topic.ValidatePattern(topics[i]); err != nil {
+			return nil, err
+		}
 	}
 	return topics, nil
 }
-func Sse(hub ports.Hub) http.HandlerFunc {
+
+// parseFilter 读取 filter 查询参数（可选），编译失败返回错误；编译结果有缓存，Hub 中不会重复编译
+func parseFilter(r *http.Request) (string, error) {
+	src := r.URL.Query().Get("filter")
+	if src == "" {
+		return "", nil
+	}
+	if _, err := filter.Compile(src); err != nil {
+		return "", err
+	}
+	return src, nil
+}
+func Sse(tenants ports.Tenants, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+
 		// 设置 CORS 头
 		w.Header().Set("Access-Control-Allow-Origin", "*") // 允许所有来源，您也可以指定特定来源
 		w.Header().Set("Access-Control-Allow-Methods", "GET")
@@ -66,113 +95,308 @@ func Sse(hub ports.Hub) http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		filter, err := parseFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		client := hub.NewClient(userId, clientType, topics)
-
-		// 尝试获取 CloseNotifier
-		cn, ok := w.(http.CloseNotifier)
-		if !ok {
-			// 如果不支持 CloseNotifier，则直接处理消息
-			go handleClientMessages(w, hub, client)
-			<-client.Done // 等待 Done 通道
+		client, err := hub.NewClient(userId, clientType, topics, filter, ports.TransportSSE)
+		if err != nil {
+			rejectConnect(w, err)
 			return
 		}
+		metrics.Connects.With(clientType).Inc()
+		if ackMode(r) {
+			hub.EnableAck(client)
+		}
+		// 立即写出响应头，客户端无需等到第一条消息即可确认连接已建立
+		w.WriteHeader(http.StatusOK)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		log := logger.With("tenant", tenantFrom(r), "clientId", client.ID, "userId", userId, "clientType", clientType)
 
-		// 获取 CloseNotify() 通道
-		closeNotify := cn.CloseNotify()
+		// 创建 goroutine 处理消息，写循环退出后回传写错误
+		// 通道在启动前取出：客户端断开时 hub.Remove 会并发地置空句柄上的 SendCh
+		events := client.SendCh
+		writeErr := make(chan error, 1)
+		go func() {
+			writeErr <- handleClientMessages(w, hub, client, events, log)
+		}()
 
-		// 创建 goroutine 处理消息
-		go handleClientMessages(w, hub, client)
+		// 尝试获取 CloseNotifier，不支持时只等待 Done 通道
+		var closeNotify <-chan bool
+		if cn, ok := w.(http.CloseNotifier); ok {
+			closeNotify = cn.CloseNotify()
+		}
 
 		// 监听 CloseNotifier 通道
+		reason := metrics.ReasonServerClose
 		select {
 		case <-closeNotify:
-			log.Printf("客户端 %d 断开连接 (CloseNotifier)\n", client.ID)
+			reason = metrics.ReasonClientGone
 			hub.Remove(client)
+			<-writeErr
 		case <-client.Done:
-			log.Printf("客户端 %d 断开连接 (client.Done)\n", client.ID)
+			if err := <-writeErr; err != nil {
+				reason = metrics.ReasonWriteError
+			}
 		}
+		metrics.Disconnects.With(reason).Inc()
+		log.Info("客户端断开连接", "reason", reason)
+	}
+}
+
+// rejectConnect 回写 Hub.NewClient 的错误：超过连接配额返回 429，其余为参数错误
+func rejectConnect(w http.ResponseWriter, err error) {
+	status, reason := http.StatusBadRequest, "too_many_topics"
+	switch {
+	case errors.Is(err, ports.ErrTooManyConnections):
+		status, reason = http.StatusTooManyRequests, "too_many_connections"
+	case errors.Is(err, ports.ErrTooManyUserConnections):
+		status, reason = http.StatusTooManyRequests, "user_limit"
 	}
+	metrics.ConnectRejects.With(reason).Inc()
+	http.Error(w, err.Error(), status)
 }
 
-// 处理消息发送
-func handleClientMessages(w http.ResponseWriter, hub ports.Hub, client *ports.Client) {
-	defer func() {
-		log.Printf("客户端 %d 断开连接时处理\n", client.ID)
-		hub.Remove(client) // 确保在断开时移除客户端
-	}()
+// 处理消息发送，返回导致退出的写错误；通道被关闭时返回 nil
+func handleClientMessages(w http.ResponseWriter, hub ports.Hub, client *ports.Client, events <-chan []byte, log *slog.Logger) error {
+	defer hub.Remove(client) // 确保在断开时移除客户端
 
-	for msg := range client.SendCh { // 读取消息的通道
+	for msg := range events { // 读取消息的通道
+		event, msg, ok := client.Take(msg)
+		// 过期的消息不下发
+		if !ok {
+			continue
+		}
+		start := time.Now()
 		message := fmt.Sprintf("data: %s\n\n", msg) // 格式化消息
+		if event != "" {
+			message = "event: " + event + "\n" + message
+		}
 		if _, err := w.Write([]byte(message)); err != nil {
-			log.Printf("发送消息时发生错误，客户端 %d: %v\n", client.ID, err)
-			break // 发送出错后退出
+			log.Warn("发送消息时发生错误", "err", err)
+			return err // 发送出错后退出
 		}
 		if f, ok := w.(http.Flusher); ok {
 			f.Flush() // 确保消息立即发送到客户端
 		}
+		metrics.WriteLatency.With().Observe(time.Since(start).Seconds())
 	}
+	return nil
 }
-func RegisterRoutes(hub ports.Hub) {
-	http.HandleFunc("/sse", Sse(hub))
-	http.HandleFunc("/publishByTopic", PublishByTopic(hub))
-	http.HandleFunc("/publishByUserId", PublishByUserId(hub))
-	http.HandleFunc("/publishByClientType", PublishByClientType(hub))
-	http.HandleFunc("/publishToClient", PublishToClient(hub))
-	http.HandleFunc("/status", Status(hub))
+
+// Deps 路由依赖
+type Deps struct {
+	Tenants  ports.Tenants
+	Resolver *tenant.Resolver
+	// topic 消息历史，nil 表示未启用持久化
+	History ports.History
+	// 用户收件箱，nil 表示未启用
+	Inbox ports.Inbox
+	// 离线消息的 webhook 队列，nil 表示未启用
+	Webhooks ports.Webhooks
+	// 定时发布，nil 表示未启用
+	Scheduler publish.Scheduler
+	// 发布幂等键记录，nil 表示未启用
+	Idempotency ports.Idempotency
+	Health      *health.Health
+	// 心跳，WebSocket 的 ping 间隔与之一致；为 nil 时使用默认间隔
+	Heartbeat *heartbeat.Heartbeat
+	Logger    *slog.Logger
+	// 长轮询参数
+	Poll PollOptions
+	// /admin/* 的 Bearer token，空表示不校验
+	AdminToken string
+}
+
+// RegisterRoutes 注册路由，返回带租户解析的 Handler；/metrics、探针与 /admin/* 不区分租户
+func RegisterRoutes(deps Deps) http.Handler {
+	root := http.NewServeMux()
+	root.HandleFunc("/metrics", metrics.Handler())
+	root.HandleFunc("/healthz", deps.Health.LiveHandler())
+	root.HandleFunc("/readyz", deps.Health.ReadyHandler())
+
+	admin := http.NewServeMux()
+	admin.HandleFunc("/admin/config", AdminConfig())
+	admin.HandleFunc("/admin/kick", AdminKick(deps.Tenants))
+	admin.HandleFunc("/admin/history", AdminHistory(deps.History))
+	admin.HandleFunc("/admin/webhooks/queue", AdminWebhookQueue(deps.Webhooks))
+	admin.HandleFunc("/admin/webhooks/dead", AdminWebhookDead(deps.Webhooks))
+	admin.HandleFunc("/admin/webhooks/dead/retry", AdminWebhookRetry(deps.Webhooks))
+	root.Handle("/admin/", requireAdmin(deps.AdminToken, admin))
+
+	tenants := deps.Tenants
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", Sse(tenants, deps.Logger))
+	mux.HandleFunc("/sse/ack", SseAck(tenants))
+	mux.HandleFunc("/delivery", Delivery(tenants))
+	mux.HandleFunc("/ws", WebSocket(tenants, deps.Heartbeat, deps.Logger))
+	mux.HandleFunc("/poll", Poll(newPollSessions(deps.Poll, deps.Logger), tenants, deps.Logger))
+	mux.HandleFunc("/publishByTopic", PublishByTopic(tenants, deps.Scheduler, deps.Idempotency))
+	mux.HandleFunc("/publishByUserId", PublishByUserId(tenants, deps.Inbox, deps.Scheduler, deps.Idempotency))
+	mux.HandleFunc("/publishByClientType", PublishByClientType(tenants, deps.Scheduler, deps.Idempotency))
+	mux.HandleFunc("/publishToClient", PublishToClient(tenants, deps.Inbox, deps.Scheduler, deps.Idempotency))
+	mux.HandleFunc("/publishBatch", PublishBatch(tenants, deps.Inbox, deps.Scheduler, deps.Idempotency))
+	mux.HandleFunc("/scheduled", Scheduled(deps.Scheduler))
+	mux.HandleFunc("/snapshot", Snapshot(tenants))
+	mux.HandleFunc("/presence", Presence(tenants))
+	mux.HandleFunc("/inbox", Inbox(deps.Inbox))
+	mux.HandleFunc("/inbox/ack", InboxAck(deps.Inbox))
+	mux.HandleFunc("/status", Status(tenants))
+	mux.HandleFunc("/status/tenants", TenantStatus(tenants))
+	root.Handle("/", TenantMiddleware(deps.Resolver, metricsMiddleware(mux)))
+	return root
+}
+
+// DeliveryOptions 定时发布、有效期与优先级参数，嵌入各发布请求体；时间为 RFC3339，时长如 "10m"。
+// deliverAt 与 delay、expiresAt 与 ttl 各只能设置一项；priority 为 high / normal / low
+type DeliveryOptions struct {
+	DeliverAt *time.Time       `json:"deliverAt,omitempty"`
+	Delay     publish.Duration `json:"delay,omitempty"`
+	ExpiresAt *time.Time       `json:"expiresAt,omitempty"`
+	TTL       publish.Duration `json:"ttl,omitempty"`
+	Priority  string           `json:"priority,omitempty"`
 }
 
 type PublishToClientMessageBody struct {
 	ClientType string `json:"clientType"`
 	UserId     int64  `json:"userId"`
 	Message    string `json:"message"`
+	// 同时存入用户收件箱，确认前每次连接都会补发
+	Persistent bool `json:"persistent"`
+	DeliveryOptions
+}
+
+// IdempotencyHeader 发布请求的幂等键，窗口内同一租户重复的键不再投递，返回首次发布的结果并带上 duplicate；
+// 首次发布仍在处理中时返回 409，幂等键存储不可用时返回 503
+const IdempotencyHeader = "Idempotency-Key"
+
+// writePublished 返回事件 ID 与命中的在线客户端数，事件 ID 可用于 /delivery 查询投递状态；
+// 定时消息返回 scheduledId 与投递时间，可用于 /scheduled 取消
+func writePublished(w http.ResponseWriter, res ports.Published) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// publishError 写出 publish.Dispatch / Batch 返回的错误
+func publishError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), publishStatus(err))
 }
 
-func PublishToClient(hub ports.Hub) http.HandlerFunc {
+func publishStatus(err error) int {
+	switch {
+	case errors.Is(err, publish.ErrInboxDisabled), errors.Is(err, publish.ErrScheduleDisabled):
+		return http.StatusNotImplemented
+	case errors.Is(err, publish.ErrInProgress):
+		return http.StatusConflict
+	case errors.Is(err, publish.ErrScheduleFull):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, ports.ErrIdempotencyUnavailable), errors.Is(err, publish.ErrScheduleUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func PublishToClient(tenants ports.Tenants, inbox ports.Inbox, scheduler publish.Scheduler, idempotency ports.Idempotency) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok || !allowPublish(w, r, tenants) {
+			return
+		}
 		var body PublishToClientMessageBody
 		err := json.NewDecoder(r.Body).Decode(&body)
 		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		hub.PublishToClient(body.ClientType, body.UserId, body.Message)
+		t := publish.Target{Tenant: tenantFrom(r), Hub: hub, Inbox: inbox, Scheduler: scheduler, Idempotency: idempotency}
+		res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindClient, ClientType: body.ClientType,
+			UserId: body.UserId, Message: body.Message, Persistent: body.Persistent,
+			DeliverAt: body.DeliverAt, Delay: body.Delay,
+			ExpiresAt: body.ExpiresAt, TTL: body.TTL, Priority: body.Priority,
+			IdempotencyKey: r.Header.Get(IdempotencyHeader)})
+		if err != nil {
+			publishError(w, err)
+			return
+		}
+		writePublished(w, res)
 	}
 }
 
 type PublishByClientTypeMessageBody struct {
 	ClientType string `json:"clientType"`
 	Message    string `json:"message"`
+	DeliveryOptions
 }
 
-func PublishByClientType(hub ports.Hub) http.HandlerFunc {
+func PublishByClientType(tenants ports.Tenants, scheduler publish.Scheduler, idempotency ports.Idempotency) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok || !allowPublish(w, r, tenants) {
+			return
+		}
 		var body PublishByClientTypeMessageBody
 		err := json.NewDecoder(r.Body).Decode(&body)
 		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t := publish.Target{Tenant: tenantFrom(r), Hub: hub, Scheduler: scheduler, Idempotency: idempotency}
+		res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindClientType, ClientType: body.ClientType,
+			Message: body.Message, DeliverAt: body.DeliverAt, Delay: body.Delay,
+			ExpiresAt: body.ExpiresAt, TTL: body.TTL, Priority: body.Priority,
+			IdempotencyKey: r.Header.Get(IdempotencyHeader)})
+		if err != nil {
+			publishError(w, err)
 			return
 		}
-		hub.PublishByClientType(body.ClientType, body.Message)
+		writePublished(w, res)
 	}
 }
 
 type PublishByUserIdMessageBody struct {
 	UserId  int64  `json:"userId"`
 	Message string `json:"message"`
+	// 同时存入用户收件箱，确认前每次连接都会补发
+	Persistent bool `json:"persistent"`
+	DeliveryOptions
 }
 
-func PublishByUserId(hub ports.Hub) http.HandlerFunc {
+func PublishByUserId(tenants ports.Tenants, inbox ports.Inbox, scheduler publish.Scheduler, idempotency ports.Idempotency) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok || !allowPublish(w, r, tenants) {
+			return
+		}
 		var body PublishByUserIdMessageBody
 		err := json.NewDecoder(r.Body).Decode(&body)
 		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t := publish.Target{Tenant: tenantFrom(r), Hub: hub, Inbox: inbox, Scheduler: scheduler, Idempotency: idempotency}
+		res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindUser, UserId: body.UserId,
+			Message: body.Message, Persistent: body.Persistent, DeliverAt: body.DeliverAt, Delay: body.Delay,
+			ExpiresAt: body.ExpiresAt, TTL: body.TTL, Priority: body.Priority,
+			IdempotencyKey: r.Header.Get(IdempotencyHeader)})
+		if err != nil {
+			publishError(w, err)
 			return
 		}
-		hub.PublishByUserId(body.UserId, body.Message)
+		writePublished(w, res)
 	}
 }
 
-func Status(hub ports.Hub) http.HandlerFunc {
+func Status(tenants ports.Tenants) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+
 		// 获取客户端状态
 		stats := hub.Stats()
 
@@ -187,18 +411,129 @@ func Status(hub ports.Hub) http.HandlerFunc {
 	}
 }
 
+// TenantStatus 返回当前租户的连接数与配额，不暴露其他租户的信息
+func TenantStatus(tenants ports.Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := hubFor(w, r, tenants); !ok {
+			return
+		}
+
+		stats := ports.TenantStats{Tenant: tenantFrom(r)}
+		for _, s := range tenants.Stats() {
+			if s.Tenant == stats.Tenant {
+				stats = s
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 type PublishByTopicMessageBody struct {
 	Topic   string `json:"topic"`
 	Message string `json:"message"`
+	DeliveryOptions
 }
 
-func PublishByTopic(hub ports.Hub) http.HandlerFunc {
+func PublishByTopic(tenants ports.Tenants, scheduler publish.Scheduler, idempotency ports.Idempotency) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok || !allowPublish(w, r, tenants) {
+			return
+		}
 		var body PublishByTopicMessageBody
 		err := json.NewDecoder(r.Body).Decode(&body)
 		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t := publish.Target{Tenant: tenantFrom(r), Hub: hub, Scheduler: scheduler, Idempotency: idempotency}
+		res, err := publish.Dispatch(t, publish.Request{Kind: publish.KindTopic, Topic: body.Topic,
+			Message: body.Message, DeliverAt: body.DeliverAt, Delay: body.Delay,
+			ExpiresAt: body.ExpiresAt, TTL: body.TTL, Priority: body.Priority,
+			IdempotencyKey: r.Header.Get(IdempotencyHeader)})
+		if err != nil {
+			publishError(w, err)
+			return
+		}
+		writePublished(w, res)
+	}
+}
+
+type PublishBatchMessageBody struct {
+	Items []publish.Request `json:"items"`
+}
+
+type PublishBatchResult struct {
+	Accepted int `json:"accepted"`
+	// 已接受条目的事件 ID，与 items 顺序一致；定时条目为空
+	Ids []string `json:"ids"`
+	// 已接受条目的定时消息 ID，与 items 顺序一致；立即投递的条目为空，没有定时条目时省略
+	ScheduledIds []string `json:"scheduledIds,omitempty"`
+	// 已接受条目的幂等键是否重复，与 items 顺序一致；没有重复条目时省略
+	Duplicates []bool `json:"duplicates,omitempty"`
+	// 定时消息保存失败时中途停止的原因
+	Error string `json:"error,omitempty"`
+}
+
+// PublishBatch 批量发布；逐条限流，被限流时返回 429 与已接受的条数，定时消息保存失败时同样返回已接受的条数。
+// 条目可各自带 idempotencyKey；请求带 Idempotency-Key 时，未带键的条目使用 "<Idempotency-Key>/<下标>"
+func PublishBatch(tenants ports.Tenants, inbox ports.Inbox, scheduler publish.Scheduler, idempotency ports.Idempotency) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
 			return
 		}
-		hub.Broadcast(body.Topic, []byte(body.Message))
+		var body PublishBatchMessageBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if key := r.Header.Get(IdempotencyHeader); key != "" {
+			for i := range body.Items {
+				if body.Items[i].IdempotencyKey == "" {
+					body.Items[i].IdempotencyKey = key + "/" + strconv.Itoa(i)
+				}
+			}
+		}
+
+		name := tenantFrom(r)
+		t := publish.Target{Tenant: name, Hub: hub, Inbox: inbox, Scheduler: scheduler, Idempotency: idempotency}
+		results, err := publish.Batch(t, body.Items, func() bool { return tenants.AllowPublish(name) })
+		if err != nil && results == nil {
+			publishError(w, err)
+			return
+		}
+
+		result := PublishBatchResult{Accepted: len(results), Ids: make([]string, 0, len(results))}
+		scheduled, duplicate := false, false
+		for _, res := range results {
+			result.Ids = append(result.Ids, res.EventID)
+			scheduled = scheduled || res.ScheduledID != ""
+			duplicate = duplicate || res.Duplicate
+		}
+		if scheduled {
+			for _, res := range results {
+				result.ScheduledIds = append(result.ScheduledIds, res.ScheduledID)
+			}
+		}
+		if duplicate {
+			for _, res := range results {
+				result.Duplicates = append(result.Duplicates, res.Duplicate)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			result.Error = err.Error()
+			w.WriteHeader(publishStatus(err))
+		} else if result.Accepted < len(body.Items) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+		_ = json.NewEncoder(w).Encode(result)
 	}
 }
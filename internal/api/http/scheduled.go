@@ -0,0 +1,41 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sse/internal/app/publish"
+)
+
+type ScheduledCancelResult struct {
+	Cancelled bool `json:"cancelled"`
+}
+
+// Scheduled 定时消息：GET 列出当前租户等待投递的消息，DELETE ?id= 取消一条
+func Scheduled(scheduler publish.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if scheduler == nil {
+			http.Error(w, publish.ErrScheduleDisabled.Error(), http.StatusNotImplemented)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(scheduler.List(tenantFrom(r)))
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				http.Error(w, "id 查询参数不存在", http.StatusBadRequest)
+				return
+			}
+			if !scheduler.Cancel(tenantFrom(r), id) {
+				http.Error(w, "定时消息不存在或已投递", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(ScheduledCancelResult{Cancelled: true})
+		default:
+			http.Error(w, "仅支持 GET 与 DELETE", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sse/internal/ports"
+)
+
+type SnapshotBody struct {
+	Topic string `json:"topic"`
+	// 空表示整个主题的快照
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+type SnapshotClearResult struct {
+	Removed int `json:"removed"`
+}
+
+// Snapshot 主题快照：GET ?topic= 读取，PUT / POST 设置，DELETE ?topic=&key= 清除（key 为空时清除整个主题）
+func Snapshot(tenants ports.Tenants) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+		q := r.URL.Query()
+
+		switch r.Method {
+		case http.MethodGet:
+			if q.Get("topic") == "" {
+				http.Error(w, "topic 查询参数不存在", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(hub.Snapshot(q.Get("topic")))
+		case http.MethodPut, http.MethodPost:
+			if !allowPublish(w, r, tenants) {
+				return
+			}
+			var body SnapshotBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			err := hub.SetSnapshot(body.Topic, body.Key, body.Message)
+			if errors.Is(err, ports.ErrTooManySnapshots) {
+				http.Error(w, err.Error(), http.StatusInsufficientStorage)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			if q.Get("topic") == "" {
+				http.Error(w, "topic 查询参数不存在", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(SnapshotClearResult{Removed: hub.ClearSnapshot(q.Get("topic"), q.Get("key"))})
+		default:
+			http.Error(w, "仅支持 GET、PUT、POST 与 DELETE", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sse/internal/adapters/tenant"
+	"sse/internal/ports"
+)
+
+type tenantKey struct{}
+
+// tenantFrom 读取中间件写入的租户
+func tenantFrom(r *http.Request) string {
+	if t, ok := r.Context().Value(tenantKey{}).(string); ok {
+		return t
+	}
+	return ports.DefaultTenant
+}
+
+// hubFor 获取请求所属租户的 Hub，失败时直接写出错误响应
+func hubFor(w http.ResponseWriter, r *http.Request, tenants ports.Tenants) (ports.Hub, bool) {
+	hub, err := tenants.Hub(tenantFrom(r))
+	if errors.Is(err, ports.ErrTooManyTenants) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return hub, true
+}
+
+// allowPublish 租户发布限流，超限时写出 429
+func allowPublish(w http.ResponseWriter, r *http.Request, tenants ports.Tenants) bool {
+	if !tenants.AllowPublish(tenantFrom(r)) {
+		http.Error(w, "发布频率超过配额", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// TenantMiddleware 解析请求所属租户并写入 context，path 模式下去掉路径中的租户前缀
+func TenantMiddleware(resolver *tenant.Resolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			name string
+			err  error
+		)
+
+		switch resolver.Mode {
+		case tenant.ModeHeader:
+			name = r.Header.Get(resolver.Header)
+		case tenant.ModeHost:
+			name, err = resolver.FromHost(r.Host)
+		case tenant.ModePath:
+			var rest string
+			name, rest, err = resolver.FromPath(r.URL.Path)
+			if err == nil {
+				r2 := r.Clone(r.Context())
+				r2.URL.Path = rest
+				r2.URL.RawPath = ""
+				r = r2
+			}
+		case tenant.ModeToken:
+			// EventSource 无法设置请求头，允许通过 token 查询参数传递
+			tok := r.Header.Get("Authorization")
+			if tok == "" {
+				tok = r.URL.Query().Get("token")
+			}
+			name, err = resolver.FromToken(tok)
+		}
+
+		if errors.Is(err, tenant.ErrInvalidToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if name == "" {
+			name = ports.DefaultTenant
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, name)))
+	})
+}
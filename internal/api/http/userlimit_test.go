@@ -0,0 +1,91 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sse/internal/adapters/hub"
+	"sse/internal/adapters/tenant"
+	apihttp "sse/internal/api/http"
+	"sse/internal/ports"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSseServer 以给定的租户配额运行 Sse 处理器
+func newSseServer(t *testing.T, q ports.Quota) string {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newHub := func(_ string, quota ports.Quota) ports.Hub {
+		return hub.NewShardedHub(quota, &hub.Settings{}, log)
+	}
+	tenants := tenant.NewRegistry(newHub, q, nil, false, 0, 0)
+	srv := httptest.NewServer(apihttp.Sse(tenants, log))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/sse?topics=news&userId=1&clientType="
+}
+
+// open 建立 SSE 连接，返回响应；测试结束时断开
+func open(t *testing.T, url string) *http.Response {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestSseUserLimitRejected(t *testing.T) {
+	url := newSseServer(t, ports.Quota{MaxConnsPerUser: 1})
+	if resp := open(t, url+"web"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("首个连接返回 %d", resp.StatusCode)
+	}
+	resp := open(t, url+"ios")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("超过用户连接数返回 %d，期望 429", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), ports.ErrTooManyUserConnections.Error()) {
+		t.Fatalf("响应体 %s", body)
+	}
+}
+
+func TestSseSingleSessionReplaced(t *testing.T) {
+	url := newSseServer(t, ports.Quota{SingleSession: true})
+	old := open(t, url+"web")
+	if old.StatusCode != http.StatusOK {
+		t.Fatalf("首个连接返回 %d", old.StatusCode)
+	}
+
+	lines := make(chan []string, 1)
+	go func() {
+		var got []string
+		sc := bufio.NewScanner(old.Body)
+		for sc.Scan() {
+			got = append(got, sc.Text())
+		}
+		lines <- got
+	}()
+
+	if resp := open(t, url+"web"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("新连接返回 %d", resp.StatusCode)
+	}
+	// 旧连接收到 event: replaced 后被服务端关闭
+	select {
+	case got := <-lines:
+		text := strings.Join(got, "\n")
+		if !strings.Contains(text, "event: "+ports.EventReplaced+"\ndata: {\"reason\":\"single_session\"}") {
+			t.Fatalf("旧连接收到 %q", text)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("旧连接未被关闭")
+	}
+}
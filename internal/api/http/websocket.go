@@ -0,0 +1,241 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sse/internal/ports"
+	"sse/pkg/heartbeat"
+	"sse/pkg/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket 帧类型
+const (
+	// 下行
+	frameEvent        = "event"
+	frameSubscribed   = "subscribed"
+	frameUnsubscribed = "unsubscribed"
+	frameError        = "error"
+	// 上行
+	frameSubscribe   = "subscribe"
+	frameUnsubscribe = "unsubscribe"
+	frameAck         = "ack"
+)
+
+const (
+	// 单帧写超时
+	wsWriteWait = 10 * time.Second
+	// 未注入心跳时的 ping 间隔
+	wsDefaultPing = 15 * time.Second
+	// 上行帧的最大长度
+	wsMaxFrame = 64 * 1024
+)
+
+// wsFrame WebSocket 上下行的 JSON 帧；event 帧的 id / event / data 与 SSE 事件字段一致
+type wsFrame struct {
+	Type   string   `json:"type"`
+	ID     string   `json:"id,omitempty"`
+	Event  string   `json:"event,omitempty"`
+	Data   string   `json:"data,omitempty"`
+	Topics []string `json:"topics,omitempty"`
+	// subscribe 帧：这些主题的内容过滤表达式
+	Filter string `json:"filter,omitempty"`
+	// ack 帧：确认模式下要确认的 eventId
+	Ids   []string `json:"ids,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// 与 SSE 一样允许任意来源
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// WebSocket 以 WebSocket 传输注册到同一个 Hub；topics 可为空，之后通过 subscribe 帧订阅
+func WebSocket(tenants ports.Tenants, hb *heartbeat.Heartbeat, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hub, ok := hubFor(w, r, tenants)
+		if !ok {
+			return
+		}
+
+		userId, _ := parseUserID(r)
+		clientType, err := parseClientType(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// topics 可以为空，之后通过 subscribe 帧订阅
+		topics, err := parseTopics(r)
+		if err != nil && r.URL.Query().Has("topics") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter, err := parseFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// 先占用配额再升级，超限时仍能返回普通的 HTTP 状态码
+		client, err := hub.NewClient(userId, clientType, topics, filter, ports.TransportWS)
+		if err != nil {
+			rejectConnect(w, err)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade 已回写错误响应
+			hub.Remove(client)
+			return
+		}
+		metrics.Connects.With(clientType).Inc()
+		if ackMode(r) {
+			hub.EnableAck(client)
+		}
+		log := logger.With("tenant", tenantFrom(r), "clientId", client.ID, "userId", userId,
+			"clientType", clientType, "transport", ports.TransportWS)
+
+		replies := make(chan wsFrame, 16)
+		readErr := make(chan error, 1)
+		go func() {
+			readErr <- wsReadLoop(conn, hub, client, func(ids []string) int { return hub.Ack(userId, clientType, ids) }, replies, hb, log)
+		}()
+
+		reason := wsWriteLoop(conn, client, replies, readErr, hb, log)
+		hub.Remove(client)
+		_ = conn.Close()
+		metrics.Disconnects.With(reason).Inc()
+		log.Info("客户端断开连接", "reason", reason)
+	}
+}
+
+// wsPingInterval 与 SSE 心跳共用间隔，热更新后下一次 ping 生效
+func wsPingInterval(hb *heartbeat.Heartbeat) time.Duration {
+	if hb == nil || hb.Interval() <= 0 {
+		return wsDefaultPing
+	}
+	return hb.Interval()
+}
+
+// wsWriteLoop 串行写出事件、回复与 ping，返回断开原因
+func wsWriteLoop(conn *websocket.Conn, client *ports.Client, replies <-chan wsFrame, readErr <-chan error, hb *heartbeat.Heartbeat, log *slog.Logger) string {
+	interval := wsPingInterval(hb)
+	ping := time.NewTicker(interval)
+	defer ping.Stop()
+
+	var seq uint64
+	write := func(f wsFrame) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(f)
+	}
+
+	for {
+		select {
+		case msg, ok := <-client.SendCh:
+			if !ok {
+				// 通道被 Hub 关闭：被踢出或判定为慢客户端
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteWait))
+				return metrics.ReasonServerClose
+			}
+			event, msg, ok := client.Take(msg)
+			// 过期的消息不下发
+			if !ok {
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			// 心跳由 ping 帧代替
+			if heartbeat.IsPayload(msg) {
+				continue
+			}
+			start := time.Now()
+			seq++
+			if err := write(wsFrame{Type: frameEvent, ID: strconv.FormatUint(seq, 10), Event: event, Data: string(msg)}); err != nil {
+				log.Warn("发送消息时发生错误", "err", err)
+				return metrics.ReasonWriteError
+			}
+			metrics.WriteLatency.With().Observe(time.Since(start).Seconds())
+
+		case f := <-replies:
+			if err := write(f); err != nil {
+				return metrics.ReasonWriteError
+			}
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				metrics.HeartbeatFailures.With().Inc()
+				return metrics.ReasonWriteError
+			}
+			// 心跳间隔热更新后按新间隔重新计时
+			if next := wsPingInterval(hb); next != interval {
+				interval = next
+				ping.Reset(interval)
+			}
+
+		case <-readErr:
+			return metrics.ReasonClientGone
+		}
+	}
+}
+
+// wsReadLoop 处理上行帧；超过两个 ping 间隔没有收到任何帧或 pong 视为断开
+func wsReadLoop(conn *websocket.Conn, hub ports.Hub, client *ports.Client, ack func(ids []string) int, replies chan<- wsFrame, hb *heartbeat.Heartbeat, log *slog.Logger) error {
+	conn.SetReadLimit(wsMaxFrame)
+	// 每次收到上行帧或 pong 时顺延读超时
+	deadline := func() { _ = conn.SetReadDeadline(time.Now().Add(2*wsPingInterval(hb) + wsWriteWait)) }
+	deadline()
+	conn.SetPongHandler(func(string) error {
+		deadline()
+		return nil
+	})
+
+	for {
+		var f wsFrame
+		if err := conn.ReadJSON(&f); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				log.Debug("读取上行帧失败", "err", err)
+			}
+			return err
+		}
+		deadline()
+
+		reply := wsFrame{Type: frameSubscribed}
+		switch f.Type {
+		case frameSubscribe:
+			if err := hub.Subscribe(client, f.Topics, f.Filter); err != nil {
+				reply = wsFrame{Type: frameError, Error: err.Error(), Topics: f.Topics}
+			} else {
+				reply.Topics = f.Topics
+			}
+		case frameUnsubscribe:
+			hub.Unsubscribe(client, f.Topics)
+			reply = wsFrame{Type: frameUnsubscribed, Topics: f.Topics}
+		case frameAck:
+			// 确认模式下按 eventId 确认，不回复
+			ids := f.Ids
+			if f.ID != "" {
+				ids = append(ids, f.ID)
+			}
+			log.Debug("客户端确认", "ids", ids, "acked", ack(ids))
+			continue
+		default:
+			reply = wsFrame{Type: frameError, Error: "未知的帧类型 " + strconv.Quote(f.Type)}
+		}
+
+		select {
+		case replies <- reply:
+		default:
+			// 回复积压说明写循环已阻塞，丢弃回复而不是阻塞读循环
+		}
+	}
+}
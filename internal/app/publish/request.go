@@ -0,0 +1,269 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"sse/internal/ports"
+	"sse/pkg/metrics"
+	"sse/pkg/topic"
+	"strings"
+	"time"
+)
+
+// 发布目标类型
+const (
+	KindTopic      = ports.TargetTopic
+	KindUser       = ports.TargetUser
+	KindClientType = ports.TargetClientType
+	KindClient     = ports.TargetClient
+)
+
+var (
+	// ErrInvalidRequest 发布请求缺少目标所需的字段
+	ErrInvalidRequest = errors.New("无效的发布请求")
+	// ErrInboxDisabled 请求了 persistent 但未启用收件箱
+	ErrInboxDisabled = errors.New("未启用离线收件箱，不支持 persistent")
+	// ErrScheduleDisabled 请求了 deliverAt / delay 但未启用定时发布
+	ErrScheduleDisabled = errors.New("未启用定时发布，不支持 deliverAt / delay")
+	// ErrScheduleFull 等待投递的定时消息数达到上限
+	ErrScheduleFull = errors.New("定时消息数超过上限")
+	// ErrScheduleUnavailable 定时消息无法写入快照
+	ErrScheduleUnavailable = errors.New("定时消息暂时无法保存")
+	// ErrExpired 投递时消息已过期
+	ErrExpired = errors.New("消息已过期")
+	// ErrInProgress 同一幂等键的首次发布尚未完成
+	ErrInProgress = errors.New("相同 idempotencyKey 的发布正在处理中")
+)
+
+// MaxIdempotencyKey 幂等键的最大长度
+const MaxIdempotencyKey = 255
+
+// Request 通用发布请求，按 Kind 路由到 Hub 的对应方法
+type Request struct {
+	Kind       string `json:"kind"`
+	Topic      string `json:"topic,omitempty"`
+	UserId     int64  `json:"userId,omitempty"`
+	ClientType string `json:"clientType,omitempty"`
+	Message    string `json:"message"`
+	// 仅 kind=user / client：同时存入用户收件箱，确认前每次连接都会补发
+	Persistent bool `json:"persistent,omitempty"`
+	// 定时发布：在 DeliverAt 或 Delay 之后投递，只能设置一项；都为空或时间已过时立即投递
+	DeliverAt *time.Time `json:"deliverAt,omitempty"`
+	Delay     Duration   `json:"delay,omitempty"`
+	// 有效期：过期后仍在客户端通道、确认窗口、历史或收件箱中的消息不再下发，只能设置一项；
+	// ttl 从投递时开始计算，定时消息即到期投递时
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	TTL       Duration   `json:"ttl,omitempty"`
+	// 优先级：high / normal / low，空表示 normal；每个连接按优先级分通道排队，高优先级先写出
+	Priority string `json:"priority,omitempty"`
+	// 幂等键：窗口内同一租户重复的键不再投递，返回首次发布的结果；未启用幂等去重时忽略
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// Validate 检查目标字段是否齐全
+func (r Request) Validate() error {
+	switch r.Kind {
+	case KindTopic:
+		if r.Topic == "" {
+			return fmt.Errorf("%w: kind=topic 需要 topic", ErrInvalidRequest)
+		}
+		if err := topic.ValidateTopic(r.Topic); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		if strings.HasPrefix(r.Topic, topic.SystemPrefix) {
+			return fmt.Errorf("%w: 不能发布到系统主题 %q", ErrInvalidRequest, r.Topic)
+		}
+	case KindUser:
+	case KindClientType:
+		if r.ClientType == "" {
+			return fmt.Errorf("%w: kind=clientType 需要 clientType", ErrInvalidRequest)
+		}
+	case KindClient:
+		if r.ClientType == "" {
+			return fmt.Errorf("%w: kind=client 需要 clientType", ErrInvalidRequest)
+		}
+	default:
+		return fmt.Errorf("%w: 未知的 kind %q", ErrInvalidRequest, r.Kind)
+	}
+	if r.Persistent && r.Kind != KindUser && r.Kind != KindClient {
+		return fmt.Errorf("%w: persistent 只支持 kind=user / client", ErrInvalidRequest)
+	}
+	if r.DeliverAt != nil && r.Delay != 0 {
+		return fmt.Errorf("%w: deliverAt 与 delay 只能设置一项", ErrInvalidRequest)
+	}
+	if r.Delay < 0 {
+		return fmt.Errorf("%w: delay 不能为负数", ErrInvalidRequest)
+	}
+	if r.ExpiresAt != nil && r.TTL != 0 {
+		return fmt.Errorf("%w: expiresAt 与 ttl 只能设置一项", ErrInvalidRequest)
+	}
+	if r.TTL < 0 {
+		return fmt.Errorf("%w: ttl 不能为负数", ErrInvalidRequest)
+	}
+	switch r.Priority {
+	case "", ports.PriorityHigh, ports.PriorityNormal, ports.PriorityLow:
+	default:
+		return fmt.Errorf("%w: 未知的 priority %q", ErrInvalidRequest, r.Priority)
+	}
+	if len(r.IdempotencyKey) > MaxIdempotencyKey {
+		return fmt.Errorf("%w: idempotencyKey 超过 %d 字节", ErrInvalidRequest, MaxIdempotencyKey)
+	}
+	return nil
+}
+
+// When 返回定时发布的投递时间；未设置或时间已过时返回 false，表示立即投递
+func (r Request) When(now time.Time) (time.Time, bool) {
+	var at time.Time
+	switch {
+	case r.DeliverAt != nil:
+		at = *r.DeliverAt
+	case r.Delay > 0:
+		at = now.Add(time.Duration(r.Delay))
+	default:
+		return time.Time{}, false
+	}
+	return at, at.After(now)
+}
+
+// Expiry 在 now 投递时的过期时间，零值表示不过期
+func (r Request) Expiry(now time.Time) time.Time {
+	switch {
+	case r.ExpiresAt != nil:
+		return *r.ExpiresAt
+	case r.TTL > 0:
+		return now.Add(time.Duration(r.TTL))
+	}
+	return time.Time{}
+}
+
+// Immediate 去掉定时参数的副本，供到期后投递；幂等键已在保存时占用，到期投递不再检查
+func (r Request) Immediate() Request {
+	r.DeliverAt, r.Delay = nil, 0
+	r.IdempotencyKey = ""
+	return r
+}
+
+// Target 发布的目标租户，inbox / scheduler / idempotency 为 nil 表示未启用收件箱 / 定时发布 / 幂等去重
+type Target struct {
+	Tenant      string
+	Hub         ports.Hub
+	Inbox       ports.Inbox
+	Scheduler   Scheduler
+	Idempotency ports.Idempotency
+}
+
+// check 校验请求本身及其对收件箱、定时发布的要求；投递时已过期返回 ErrExpired
+func (t Target) check(r Request) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	at, scheduled := r.When(now)
+	if !scheduled {
+		at = now
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(at) {
+		if scheduled {
+			return fmt.Errorf("%w: expiresAt 早于投递时间", ErrInvalidRequest)
+		}
+		return ErrExpired
+	}
+	if r.Persistent && t.Inbox == nil {
+		return ErrInboxDisabled
+	}
+	if (r.DeliverAt != nil || r.Delay != 0) && t.Scheduler == nil {
+		return ErrScheduleDisabled
+	}
+	return nil
+}
+
+// Dispatch 校验请求并投递到 Hub；persistent 的消息先存入收件箱，下发内容带上 inboxId；
+// 定时发布的请求交给 Scheduler，到期后再投递；幂等键重复时不投递，返回首次发布的结果
+func Dispatch(t Target, r Request) (ports.Published, error) {
+	if err := t.check(r); err != nil {
+		return ports.Published{}, err
+	}
+	return t.once(r)
+}
+
+// once 按幂等键去重后投递，投递失败时释放幂等键以便重试
+func (t Target) once(r Request) (ports.Published, error) {
+	if r.IdempotencyKey == "" || t.Idempotency == nil {
+		return t.dispatch(r)
+	}
+	prev, claimed, err := t.Idempotency.Claim(t.Tenant, r.IdempotencyKey)
+	if err != nil {
+		return ports.Published{}, err
+	}
+	if !claimed {
+		metrics.PublishDuplicates.With().Inc()
+		if prev == nil {
+			return ports.Published{}, ErrInProgress
+		}
+		prev.Duplicate = true
+		return *prev, nil
+	}
+	res, err := t.dispatch(r)
+	if err != nil {
+		t.Idempotency.Release(t.Tenant, r.IdempotencyKey)
+		return ports.Published{}, err
+	}
+	t.Idempotency.Complete(t.Tenant, r.IdempotencyKey, res)
+	return res, nil
+}
+
+func (t Target) dispatch(r Request) (ports.Published, error) {
+	if at, ok := r.When(time.Now()); ok {
+		s, err := t.Scheduler.Schedule(t.Tenant, r.Immediate(), at)
+		if err != nil {
+			return ports.Published{}, err
+		}
+		return ports.Published{ScheduledID: s.ID, DeliverAt: &s.DeliverAt}, nil
+	}
+	return t.deliver(r), nil
+}
+
+func (t Target) deliver(r Request) ports.Published {
+	expiresAt := r.Expiry(time.Now())
+	opts := ports.PublishOptions{ExpiresAt: expiresAt, Priority: r.Priority}
+	message := r.Message
+	if r.Persistent {
+		clientType := ""
+		if r.Kind == KindClient {
+			clientType = r.ClientType
+		}
+		message = t.Inbox.Put(t.Tenant, r.UserId, clientType, r.Message, expiresAt).Payload()
+	}
+	switch r.Kind {
+	case KindTopic:
+		return t.Hub.Broadcast(r.Topic, []byte(message), opts)
+	case KindUser:
+		return t.Hub.PublishByUserId(r.UserId, message, opts)
+	case KindClientType:
+		return t.Hub.PublishByClientType(r.ClientType, message, opts)
+	default:
+		return t.Hub.PublishToClient(r.ClientType, r.UserId, message, opts)
+	}
+}
+
+// Batch 按顺序校验并投递，allow 返回 false 时停止（限流），返回已投递条目的结果；
+// 定时消息保存失败时同样停止，并返回已投递条目的结果与错误
+func Batch(t Target, items []Request, allow func() bool) ([]ports.Published, error) {
+	for i, item := range items {
+		if err := t.check(item); err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+	}
+	results := make([]ports.Published, 0, len(items))
+	for i, item := range items {
+		if !allow() {
+			break
+		}
+		res, err := t.once(item)
+		if err != nil {
+			return results, fmt.Errorf("items[%d]: %w", i, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -0,0 +1,47 @@
+package publish
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Scheduled 一条等待投递的定时消息
+type Scheduled struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	DeliverAt time.Time `json:"deliverAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	// 到期后按此请求投递，不含定时参数
+	Request Request `json:"request"`
+}
+
+// Scheduler 保存定时发布的请求，到期后投递
+type Scheduler interface {
+	// 保存请求，超过数量上限时返回 ErrScheduleFull，超过最长延迟时返回 ErrInvalidRequest，无法落盘时返回 ErrScheduleUnavailable
+	Schedule(tenant string, r Request, at time.Time) (Scheduled, error)
+	// 租户等待投递的定时消息，按投递时间排序
+	List(tenant string) []Scheduled
+	// 取消租户的定时消息，不存在或已投递时返回 false
+	Cancel(tenant, id string) bool
+}
+
+// Duration JSON 中以 "90s"、"10m"、"1h30m" 表示的时长，用于 delay 与 ttl，错误信息不指明字段
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("%w: 时长应为 \"10m\" 形式的字符串", ErrInvalidRequest)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%w: 时长 %q 不合法，应为 \"10m\" 形式", ErrInvalidRequest, s)
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,379 @@
+package schedule
+
+import (
+	"cmp"
+	"container/heap"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sse/internal/app/publish"
+	"sse/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// Options 定时发布参数，零值字段使用默认值
+type Options struct {
+	// 等待投递的定时消息上限
+	MaxPending int
+	// 投递时间距当前的最长间隔
+	MaxDelay time.Duration
+	// 快照文件，为空表示不落盘，重启后丢失；保存定时消息时同步写入，取消与投递的变化每秒写入
+	File string
+	// 投递失败（租户、收件箱暂时不可用等）时的最多尝试次数，之后丢弃
+	MaxAttempts int
+	// 首次重试的等待时间，之后每次翻倍
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxPending <= 0 {
+		o.MaxPending = 100000
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 30 * 24 * time.Hour
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Minute
+	}
+	return o
+}
+
+// Clock 调度使用的时间来源，可替换为假时钟来驱动调度
+type Clock interface {
+	Now() time.Time
+	// After 在 d 之后向返回的通道发送当前时间
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// entry 堆中的一条定时消息，同一时间按保存顺序投递
+type entry struct {
+	publish.Scheduled
+	seq   uint64
+	index int
+	// 已失败的投递次数与下次重试时间；重试次数不写入快照，重启后从头计算
+	attempts int
+	retryAt  time.Time
+}
+
+// due 下次投递时间：重试中为 retryAt，否则为 DeliverAt
+func (e *entry) due() time.Time {
+	if e.attempts > 0 {
+		return e.retryAt
+	}
+	return e.DeliverAt
+}
+
+// queue 按投递时间排序的最小堆
+type queue []*entry
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if a, b := q[i].due(), q[j].due(); !a.Equal(b) {
+		return a.Before(b)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+func (q *queue) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+func (q *queue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Scheduler 实现 publish.Scheduler：定时消息保存在最小堆中，调度协程在最早的投递时间醒来，
+// 到期的消息交给 fire 投递，投递失败时退避后放回堆中重试；快照在 Load 时恢复。
+// 新消息写入快照后 Schedule 才返回，投递中的消息在投递结束前仍保留在快照中，崩溃重启后会再次投递，保证至少投递一次
+type Scheduler struct {
+	opts  Options
+	fire  func(publish.Scheduled) error
+	clock Clock
+	log   *slog.Logger
+
+	mu    sync.Mutex
+	queue queue
+	byID  map[string]*entry
+	// 已从堆中取出、正在投递的消息，不能再取消
+	inflight map[string]*entry
+	seq      uint64
+	// 队列变化后尚未写入快照
+	dirty bool
+	// 串行写快照，避免较早的快照覆盖较新的
+	saveMu sync.Mutex
+
+	wake chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New 创建定时发布调度器，需调用 Start 后才开始投递
+// fire: 投递一条到期的定时消息
+// clock: 时间来源，nil 表示系统时钟
+func New(opts Options, fire func(publish.Scheduled) error, clock Clock, log *slog.Logger) *Scheduler {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &Scheduler{
+		opts:     opts.withDefaults(),
+		fire:     fire,
+		clock:    clock,
+		log:      log,
+		byID:     make(map[string]*entry),
+		inflight: make(map[string]*entry),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Schedule(tenant string, r publish.Request, at time.Time) (publish.Scheduled, error) {
+	now := s.clock.Now()
+	if at.Sub(now) > s.opts.MaxDelay {
+		return publish.Scheduled{}, fmt.Errorf("%w: 投递时间不能晚于 %s 之后", publish.ErrInvalidRequest, s.opts.MaxDelay)
+	}
+
+	s.mu.Lock()
+	if len(s.queue) >= s.opts.MaxPending {
+		s.mu.Unlock()
+		return publish.Scheduled{}, publish.ErrScheduleFull
+	}
+	sc := publish.Scheduled{ID: newID(), Tenant: tenant, DeliverAt: at, CreatedAt: now, Request: r}
+	first := s.push(sc)
+	s.mu.Unlock()
+
+	// 写入快照后再返回，避免已确认的消息在崩溃时丢失
+	if err := s.Save(); err != nil {
+		s.mu.Lock()
+		e, queued := s.byID[sc.ID]
+		if queued {
+			heap.Remove(&s.queue, e.index)
+			delete(s.byID, sc.ID)
+			s.gauge()
+		}
+		s.mu.Unlock()
+		// 已开始投递的消息不再撤回
+		if queued {
+			s.log.Warn("写入定时消息快照失败", "err", err)
+			return publish.Scheduled{}, fmt.Errorf("%w: %v", publish.ErrScheduleUnavailable, err)
+		}
+	}
+
+	// 新消息排在最前时需要提前唤醒调度协程
+	if first {
+		s.notify()
+	}
+	return sc, nil
+}
+
+// push 加入堆，返回是否排在最前；调用方需持有 mu
+func (s *Scheduler) push(sc publish.Scheduled) bool {
+	s.seq++
+	e := &entry{Scheduled: sc, seq: s.seq}
+	heap.Push(&s.queue, e)
+	s.byID[sc.ID] = e
+	s.dirty = true
+	s.gauge()
+	return e.index == 0
+}
+
+func (s *Scheduler) List(tenant string) []publish.Scheduled {
+	s.mu.Lock()
+	entries := make([]*entry, 0, len(s.queue))
+	for _, e := range s.queue {
+		if e.Tenant == tenant {
+			entries = append(entries, e)
+		}
+	}
+	s.mu.Unlock()
+	return sorted(entries)
+}
+
+// sorted 按投递时间排序，同一时间按保存顺序
+func sorted(entries []*entry) []publish.Scheduled {
+	slices.SortFunc(entries, func(a, b *entry) int {
+		if c := a.DeliverAt.Compare(b.DeliverAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.seq, b.seq)
+	})
+	out := make([]publish.Scheduled, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.Scheduled)
+	}
+	return out
+}
+
+func (s *Scheduler) Cancel(tenant, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.byID[id]
+	if !ok || e.Tenant != tenant {
+		return false
+	}
+	heap.Remove(&s.queue, e.index)
+	delete(s.byID, id)
+	s.dirty = true
+	s.gauge()
+	return true
+}
+
+// Start 启动调度协程
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Close 停止调度，等待调度协程结束后写入最后一次快照；未投递的消息在下次启动时恢复
+func (s *Scheduler) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.Save()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 投递到期的消息，并每秒把有变化的队列写入快照
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+	flush := time.NewTicker(time.Second)
+	defer flush.Stop()
+
+	for {
+		wait := s.fireDue()
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		case <-s.clock.After(wait):
+		case <-flush.C:
+			if err := s.Save(); err != nil {
+				s.log.Warn("写入定时消息快照失败", "err", err)
+			}
+		}
+	}
+}
+
+// fireDue 按投递时间顺序投递全部到期的消息，返回距离下一条到期的时间
+func (s *Scheduler) fireDue() time.Duration {
+	now := s.clock.Now()
+	s.mu.Lock()
+	var due []*entry
+	for len(s.queue) > 0 && !s.queue[0].due().After(now) {
+		e := heap.Pop(&s.queue).(*entry)
+		delete(s.byID, e.ID)
+		s.inflight[e.ID] = e
+		due = append(due, e)
+	}
+	next := time.Hour
+	if len(s.queue) > 0 {
+		next = min(next, s.queue[0].due().Sub(now))
+	}
+	if len(due) > 0 {
+		s.gauge()
+	}
+	s.mu.Unlock()
+
+	// 投递不持有锁，投递过程中可以继续保存新的定时消息
+	for _, e := range due {
+		err := s.fire(e.Scheduled)
+		switch {
+		case err == nil:
+			s.finish(e)
+			metrics.ScheduledFired.With(metrics.ScheduledDelivered).Inc()
+			s.log.Debug("定时消息已投递", "id", e.ID, "tenant", e.Tenant, "late", now.Sub(e.DeliverAt))
+		case errors.Is(err, publish.ErrExpired):
+			s.finish(e)
+			metrics.ScheduledFired.With(metrics.ScheduledExpired).Inc()
+			metrics.MessagesExpired.With(metrics.ExpiredSchedule).Inc()
+			s.log.Debug("定时消息已过期，不再投递", "id", e.ID, "tenant", e.Tenant)
+		case errors.Is(err, publish.ErrInvalidRequest) || errors.Is(err, publish.ErrInboxDisabled) ||
+			e.attempts+1 >= s.opts.MaxAttempts:
+			// 请求本身无法投递或已用完重试次数
+			s.finish(e)
+			metrics.ScheduledFired.With(metrics.ScheduledFailed).Inc()
+			s.log.Warn("定时消息投递失败，已丢弃", "id", e.ID, "tenant", e.Tenant, "attempts", e.attempts+1, "err", err)
+		default:
+			metrics.ScheduledFired.With(metrics.ScheduledRetried).Inc()
+			wait := s.retry(e)
+			s.log.Warn("定时消息投递失败，稍后重试", "id", e.ID, "tenant", e.Tenant, "attempts", e.attempts, "wait", wait, "err", err)
+		}
+	}
+	return next
+}
+
+// finish 投递结束（成功或不再重试）后从快照中移除
+func (s *Scheduler) finish(e *entry) {
+	s.mu.Lock()
+	delete(s.inflight, e.ID)
+	s.dirty = true
+	s.mu.Unlock()
+}
+
+// retry 记一次失败并按退避时间放回堆中，返回等待时间
+func (s *Scheduler) retry(e *entry) time.Duration {
+	e.attempts++
+	wait := s.backoff(e.attempts)
+	e.retryAt = s.clock.Now().Add(wait)
+
+	s.mu.Lock()
+	delete(s.inflight, e.ID)
+	heap.Push(&s.queue, e)
+	s.byID[e.ID] = e
+	s.dirty = true
+	s.gauge()
+	first := e.index == 0
+	s.mu.Unlock()
+	if first {
+		s.notify()
+	}
+	return wait
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (s *Scheduler) backoff(attempts int) time.Duration {
+	wait := s.opts.InitialBackoff
+	for i := 1; i < attempts && wait < s.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, s.opts.MaxBackoff)
+}
+
+// gauge 更新队列长度指标，调用方需持有 mu
+func (s *Scheduler) gauge() {
+	metrics.ScheduledPending.With().Set(float64(len(s.queue)))
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
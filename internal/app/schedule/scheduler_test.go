@@ -0,0 +1,485 @@
+package schedule
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sse/internal/app/publish"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟；After 每次注册时向 registered 发送等待时长，测试据此确认调度协程已进入等待
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+
+	registered chan time.Duration
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), registered: make(chan time.Duration, 64)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	}
+	select {
+	case c.registered <- d:
+	default:
+	}
+	return ch
+}
+
+// Advance 推进时钟，唤醒到期的 After
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			kept = append(kept, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = kept
+}
+
+// fired 记录 fire 收到的定时消息，errs 依次作为前几次 fire 的返回值
+type fired struct {
+	mu   sync.Mutex
+	got  []publish.Scheduled
+	errs []error
+	ch   chan publish.Scheduled
+}
+
+func newFired(errs ...error) *fired {
+	return &fired{errs: errs, ch: make(chan publish.Scheduled, 64)}
+}
+
+func (f *fired) fire(sc publish.Scheduled) error {
+	f.mu.Lock()
+	f.got = append(f.got, sc)
+	var err error
+	if len(f.errs) > 0 {
+		err, f.errs = f.errs[0], f.errs[1:]
+	}
+	f.mu.Unlock()
+	f.ch <- sc
+	return err
+}
+
+func (f *fired) ids() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, 0, len(f.got))
+	for _, sc := range f.got {
+		out = append(out, sc.ID)
+	}
+	return out
+}
+
+func newTestScheduler(opts Options, f *fired, clock Clock) *Scheduler {
+	return New(opts, f.fire, clock, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func request(msg string) publish.Request {
+	return publish.Request{Kind: publish.KindTopic, Topic: "news", Message: msg}
+}
+
+func mustSchedule(t *testing.T, s *Scheduler, tenant string, msg string, at time.Time) publish.Scheduled {
+	t.Helper()
+	sc, err := s.Schedule(tenant, request(msg), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func equalIDs(got []string, want ...publish.Scheduled) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSchedulerFiresAtDeliverAt(t *testing.T) {
+	clock := newFakeClock()
+	f := newFired()
+	s := newTestScheduler(Options{}, f, clock)
+	s.Start()
+	defer s.Close()
+	<-clock.registered
+
+	sc := mustSchedule(t, s, "acme", "hi", clock.Now().Add(10*time.Second))
+	// 新消息排在最前，调度协程被唤醒后按它的投递时间重新等待
+	if d := <-clock.registered; d != 10*time.Second {
+		t.Fatalf("等待 %v，期望 10s", d)
+	}
+
+	clock.Advance(9 * time.Second)
+	select {
+	case got := <-f.ch:
+		t.Fatalf("投递时间之前收到 %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case got := <-f.ch:
+		if got.ID != sc.ID || got.Tenant != "acme" || got.Request.Message != "hi" {
+			t.Fatalf("投递 %+v，期望 %+v", got, sc)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("到期后未投递")
+	}
+	if n := len(s.List("acme")); n != 0 {
+		t.Fatalf("投递后仍有 %d 条", n)
+	}
+}
+
+func TestSchedulerSameTimeInOrder(t *testing.T) {
+	clock := newFakeClock()
+	f := newFired()
+	s := newTestScheduler(Options{}, f, clock)
+
+	at := clock.Now().Add(time.Minute)
+	later := mustSchedule(t, s, "acme", "later", at.Add(time.Second))
+	a := mustSchedule(t, s, "acme", "a", at)
+	b := mustSchedule(t, s, "acme", "b", at)
+	c := mustSchedule(t, s, "acme", "c", at)
+
+	clock.Advance(time.Minute)
+	if next := s.fireDue(); next != time.Second {
+		t.Fatalf("距下一条 %v，期望 1s", next)
+	}
+	// 同一时间的消息按保存顺序投递
+	if !equalIDs(f.ids(), a, b, c) {
+		t.Fatalf("投递顺序 %v", f.ids())
+	}
+	clock.Advance(time.Second)
+	s.fireDue()
+	if !equalIDs(f.ids(), a, b, c, later) {
+		t.Fatalf("投递顺序 %v", f.ids())
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	clock := newFakeClock()
+	f := newFired()
+	s := newTestScheduler(Options{}, f, clock)
+
+	sc := mustSchedule(t, s, "acme", "a", clock.Now().Add(time.Minute))
+	other := mustSchedule(t, s, "other", "b", clock.Now().Add(time.Minute))
+
+	// 其他租户既看不到也取消不了
+	if s.Cancel("other", sc.ID) {
+		t.Fatal("其他租户取消成功")
+	}
+	if got := s.List("other"); len(got) != 1 || got[0].ID != other.ID {
+		t.Fatalf("other 的定时消息 %+v", got)
+	}
+	if !s.Cancel("acme", sc.ID) {
+		t.Fatal("取消失败")
+	}
+	if s.Cancel("acme", sc.ID) || s.Cancel("acme", "missing") {
+		t.Fatal("重复取消或不存在的 id 应返回 false")
+	}
+
+	clock.Advance(time.Minute)
+	s.fireDue()
+	if !equalIDs(f.ids(), other) {
+		t.Fatalf("投递了 %v", f.ids())
+	}
+	// 已投递的消息不能再取消
+	if s.Cancel("other", other.ID) {
+		t.Fatal("已投递的消息取消成功")
+	}
+}
+
+func TestSchedulerList(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(Options{}, newFired(), clock)
+	now := clock.Now()
+
+	c := mustSchedule(t, s, "acme", "c", now.Add(30*time.Second))
+	a := mustSchedule(t, s, "acme", "a", now.Add(10*time.Second))
+	mustSchedule(t, s, "other", "x", now.Add(5*time.Second))
+	b1 := mustSchedule(t, s, "acme", "b1", now.Add(20*time.Second))
+	b2 := mustSchedule(t, s, "acme", "b2", now.Add(20*time.Second))
+
+	got := s.List("acme")
+	ids := make([]string, 0, len(got))
+	for _, sc := range got {
+		ids = append(ids, sc.ID)
+	}
+	if !equalIDs(ids, a, b1, b2, c) {
+		t.Fatalf("列表顺序 %+v", got)
+	}
+	if got[0].CreatedAt != now || got[0].Request.Message != "a" {
+		t.Fatalf("列表内容 %+v", got[0])
+	}
+	if len(s.List("none")) != 0 {
+		t.Fatal("没有定时消息的租户应返回空列表")
+	}
+}
+
+func TestSchedulerLimits(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestScheduler(Options{MaxPending: 2, MaxDelay: time.Hour}, newFired(), clock)
+	now := clock.Now()
+
+	if _, err := s.Schedule("acme", request("a"), now.Add(time.Hour+time.Second)); !errors.Is(err, publish.ErrInvalidRequest) {
+		t.Fatalf("超过最长延迟返回 %v", err)
+	}
+	mustSchedule(t, s, "acme", "a", now.Add(time.Hour))
+	mustSchedule(t, s, "other", "b", now.Add(time.Minute))
+	// 上限按全部租户计算
+	if _, err := s.Schedule("third", request("c"), now.Add(time.Minute)); !errors.Is(err, publish.ErrScheduleFull) {
+		t.Fatalf("超过数量上限返回 %v", err)
+	}
+
+	// 投递后腾出位置
+	clock.Advance(time.Minute)
+	s.fireDue()
+	mustSchedule(t, s, "third", "c", now.Add(2*time.Minute))
+}
+
+func TestSchedulerRetry(t *testing.T) {
+	clock := newFakeClock()
+	unavailable := errors.New("租户暂时不可用")
+	f := newFired(unavailable, unavailable)
+	s := newTestScheduler(Options{InitialBackoff: time.Second, MaxAttempts: 5}, f, clock)
+
+	sc := mustSchedule(t, s, "acme", "a", clock.Now().Add(time.Minute))
+	clock.Advance(time.Minute)
+	// 第一次失败后等待 1s，第二次失败后等待 2s
+	if next := s.fireDue(); next != time.Hour {
+		t.Fatalf("距下一条 %v", next)
+	}
+	if got := s.List("acme"); len(got) != 1 || got[0].ID != sc.ID || !got[0].DeliverAt.Equal(sc.DeliverAt) {
+		t.Fatalf("失败后的队列 %+v", got)
+	}
+	clock.Advance(time.Second)
+	s.fireDue()
+	clock.Advance(time.Second)
+	s.fireDue()
+	if n := len(f.ids()); n != 2 {
+		t.Fatalf("退避结束前投递了 %d 次", n)
+	}
+	clock.Advance(time.Second)
+	s.fireDue()
+	if !equalIDs(f.ids(), sc, sc, sc) {
+		t.Fatalf("投递 %v", f.ids())
+	}
+	if n := len(s.List("acme")); n != 0 {
+		t.Fatalf("成功后仍有 %d 条", n)
+	}
+}
+
+func TestSchedulerRetryGivesUp(t *testing.T) {
+	clock := newFakeClock()
+	unavailable := errors.New("租户暂时不可用")
+	f := newFired(unavailable, unavailable, unavailable)
+	s := newTestScheduler(Options{InitialBackoff: time.Second, MaxAttempts: 3}, f, clock)
+
+	mustSchedule(t, s, "acme", "a", clock.Now())
+	for range 5 {
+		s.fireDue()
+		clock.Advance(time.Minute)
+	}
+	if n := len(f.ids()); n != 3 {
+		t.Fatalf("投递了 %d 次，期望 3 次", n)
+	}
+	if n := len(s.List("acme")); n != 0 {
+		t.Fatalf("重试次数用完后仍有 %d 条", n)
+	}
+}
+
+func TestSchedulerNoRetry(t *testing.T) {
+	for _, err := range []error{publish.ErrExpired, publish.ErrInvalidRequest, publish.ErrInboxDisabled} {
+		t.Run(err.Error(), func(t *testing.T) {
+			clock := newFakeClock()
+			f := newFired(err)
+			s := newTestScheduler(Options{}, f, clock)
+
+			mustSchedule(t, s, "acme", "a", clock.Now())
+			s.fireDue()
+			if n := len(s.List("acme")); n != 0 {
+				t.Fatalf("不应重试，队列中仍有 %d 条", n)
+			}
+		})
+	}
+}
+
+func TestSchedulerSaveLoad(t *testing.T) {
+	clock := newFakeClock()
+	file := filepath.Join(t.TempDir(), "schedule.json")
+	s1 := newTestScheduler(Options{File: file}, newFired(), clock)
+
+	at := clock.Now().Add(time.Minute)
+	a := mustSchedule(t, s1, "acme", "a", at)
+	b := mustSchedule(t, s1, "acme", "b", at)
+	o := mustSchedule(t, s1, "other", "o", at.Add(-time.Second))
+	cancelled := mustSchedule(t, s1, "acme", "x", at)
+	s1.Cancel("acme", cancelled.ID)
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟重启：新的调度器从快照恢复
+	f := newFired()
+	s2 := newTestScheduler(Options{File: file}, f, clock)
+	if err := s2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got := s2.List("acme")
+	if len(got) != 2 {
+		t.Fatalf("恢复了 %+v", got)
+	}
+	for i, want := range []publish.Scheduled{a, b} {
+		if got[i].ID != want.ID || !got[i].DeliverAt.Equal(want.DeliverAt) || got[i].Request != want.Request {
+			t.Fatalf("第 %d 条恢复为 %+v，期望 %+v", i, got[i], want)
+		}
+	}
+	// 重复 Load 不会重复加入
+	if err := s2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s2.List("acme")); n != 2 {
+		t.Fatalf("重复 Load 后有 %d 条", n)
+	}
+
+	clock.Advance(time.Minute)
+	s2.fireDue()
+	if !equalIDs(f.ids(), o, a, b) {
+		t.Fatalf("恢复后的投递顺序 %v", f.ids())
+	}
+}
+
+func TestSchedulerLoadMissingFile(t *testing.T) {
+	s := newTestScheduler(Options{File: filepath.Join(t.TempDir(), "none.json")}, newFired(), newFakeClock())
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 保存定时消息时同步写入快照，不等 Close
+func TestSchedulerScheduleSaves(t *testing.T) {
+	clock := newFakeClock()
+	file := filepath.Join(t.TempDir(), "schedule.json")
+	s1 := newTestScheduler(Options{File: file}, newFired(), clock)
+	a := mustSchedule(t, s1, "acme", "a", clock.Now().Add(time.Minute))
+
+	// 模拟崩溃：不调用 Close，直接从快照恢复
+	s2 := newTestScheduler(Options{File: file}, newFired(), clock)
+	if err := s2.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s2.List("acme"); len(got) != 1 || got[0].ID != a.ID {
+		t.Fatalf("恢复了 %+v", got)
+	}
+}
+
+func TestSchedulerScheduleSaveFails(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	// 快照目录是一个普通文件，无法写入
+	if err := os.WriteFile(filepath.Join(dir, "f"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := newFired()
+	s := newTestScheduler(Options{File: filepath.Join(dir, "f", "schedule.json")}, f, clock)
+
+	if _, err := s.Schedule("acme", request("a"), clock.Now().Add(time.Minute)); !errors.Is(err, publish.ErrScheduleUnavailable) {
+		t.Fatalf("无法写入快照时返回 %v", err)
+	}
+	if n := len(s.List("acme")); n != 0 {
+		t.Fatalf("保存失败的消息仍在队列中: %d 条", n)
+	}
+	clock.Advance(time.Minute)
+	s.fireDue()
+	if n := len(f.ids()); n != 0 {
+		t.Fatalf("投递了保存失败的消息 %v", f.ids())
+	}
+}
+
+// 投递中的消息在投递结束前仍保留在快照中，崩溃后重新投递
+func TestSchedulerSaveInFlight(t *testing.T) {
+	clock := newFakeClock()
+	file := filepath.Join(t.TempDir(), "schedule.json")
+	started := make(chan struct{})
+	release := make(chan error)
+	fire := func(publish.Scheduled) error {
+		close(started)
+		return <-release
+	}
+	s := New(Options{File: file}, fire, clock, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	a := mustSchedule(t, s, "acme", "a", clock.Now().Add(time.Minute))
+	b := mustSchedule(t, s, "acme", "b", clock.Now().Add(2*time.Minute))
+
+	loaded := func() []publish.Scheduled {
+		t.Helper()
+		r := newTestScheduler(Options{File: file}, newFired(), clock)
+		if err := r.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return r.List("acme")
+	}
+
+	clock.Advance(time.Minute)
+	done := make(chan struct{})
+	go func() {
+		s.fireDue()
+		close(done)
+	}()
+	<-started
+	// 投递中的消息不能取消，但快照仍包含它
+	if s.Cancel("acme", a.ID) {
+		t.Fatal("投递中的消息取消成功")
+	}
+	s.Cancel("acme", b.ID)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded(); len(got) != 1 || got[0].ID != a.ID {
+		t.Fatalf("投递中写入的快照 %+v", got)
+	}
+
+	release <- nil
+	<-done
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded(); len(got) != 0 {
+		t.Fatalf("投递后快照仍有 %+v", got)
+	}
+}
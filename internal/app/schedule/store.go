@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sse/internal/app/publish"
+)
+
+// Load 从 File 恢复上次退出时的定时消息，文件不存在时不做任何事；已过期的消息在 Start 后立即投递
+func (s *Scheduler) Load() error {
+	if s.opts.File == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.opts.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var items []publish.Scheduled
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sc := range items {
+		if _, ok := s.byID[sc.ID]; !ok {
+			s.push(sc)
+		}
+	}
+	s.dirty = false
+	return nil
+}
+
+// Save 队列有变化时写入 File，包含正在投递的消息，按投递顺序排列，Load 时同一时间的消息保持原有顺序；
+// 先写临时文件再改名，避免留下写了一半的快照
+func (s *Scheduler) Save() error {
+	if s.opts.File == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.mu.Lock()
+	// 并发保存时，已由前一次写入的变化不再重复写
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	entries := slices.Clone(s.queue)
+	for _, e := range s.inflight {
+		entries = append(entries, e)
+	}
+	data, err := json.Marshal(sorted(entries))
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.opts.File), 0o755)
+	var tmp *os.File
+	if err == nil {
+		tmp, err = os.CreateTemp(filepath.Dir(s.opts.File), filepath.Base(s.opts.File)+".*")
+	}
+	if err == nil {
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), s.opts.File)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		// 下次再试
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package ports
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeliveryStatus 一个事件的投递情况；确认模式按用户与客户端类型计数，同一用户的多个同类连接只计一次
+type DeliveryStatus struct {
+	EventID string    `json:"id"`
+	Time    time.Time `json:"time"`
+	// 命中的在线客户端数
+	Matched int `json:"matched"`
+	// 普通连接：已写入发送通道的数量
+	Delivered int `json:"delivered"`
+	// 确认模式：等待确认、已确认、放弃（重发次数用尽或确认窗口已满）、过期仍未确认的数量
+	Pending int `json:"pending"`
+	Acked   int `json:"acked"`
+	Failed  int `json:"failed"`
+	Expired int `json:"expired"`
+}
+
+// AckPayload 确认模式下发给客户端的内容，客户端按 eventId 确认；带过期时间时同时下发 expiresAt
+func AckPayload(eventID string, message []byte, expiresAt time.Time) []byte {
+	b, _ := json.Marshal(struct {
+		EventID   string     `json:"eventId"`
+		Message   string     `json:"message"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	}{eventID, string(message), Expiry(expiresAt)})
+	return b
+}
+
+// Expiry 把表示不过期的零值转换为 nil，用于 JSON 输出
+func Expiry(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Expired 过期时间非零且不晚于 now
+func Expired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
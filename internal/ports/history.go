@@ -0,0 +1,21 @@
+package ports
+
+import "time"
+
+// Record 一条已持久化的 topic 消息
+type Record struct {
+	ID      uint64    `json:"id"`
+	Topic   string    `json:"topic"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	// 过期时间，过期后不再出现在 Range 的结果中；nil 表示不过期
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// History 按租户与 topic 保存已广播的消息，供回放使用
+type History interface {
+	// 追加一条消息，返回分配了 ID 的记录；ID 在同一 History 内单调递增；expiresAt 零值表示不过期
+	Append(tenant, topic string, payload []byte, expiresAt time.Time) Record
+	// 返回 ID 大于 after 且未过期的记录，按 ID 升序，最多 limit 条（<=0 表示不限制）
+	Range(tenant, topic string, after uint64, limit int) []Record
+}
@@ -1,6 +1,9 @@
 package ports
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 type Client struct {
 	ID     int64
@@ -10,34 +13,129 @@ type Client struct {
 	SendCh chan []byte
 	// Done 在客户端关闭时关闭，上层可用户退出写循环
 	Done chan struct{}
+	// Resolve 把占位消息换成实际内容：合并主题取该键当前的最新值，快照带上事件类型，
+	// 已过期的消息返回 ok=false；nil 表示不需要，读取方通过 Take 使用
+	Resolve func(msg []byte) (event string, data []byte, ok bool)
+}
+
+// 下发时的事件类型，空表示默认的 message
+const (
+	EventSnapshot = "snapshot"
+	// EventReplaced 连接被同一用户的新连接替换，随后断开
+	EventReplaced = "replaced"
+)
+
+// Take 返回从 SendCh 读到的消息实际要下发的事件类型与内容，ok=false 表示消息已过期，跳过不下发
+func (c *Client) Take(msg []byte) (event string, data []byte, ok bool) {
+	if c.Resolve == nil {
+		return "", msg, true
+	}
+	return c.Resolve(msg)
+}
+
+// 发布目标类型
+const (
+	TargetTopic      = "topic"
+	TargetUser       = "user"
+	TargetClientType = "clientType"
+	TargetClient     = "client"
+)
+
+// 消息优先级，每个连接按优先级分通道排队，写出时按权重轮流取；空表示 PriorityNormal
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// PublishOptions 发布参数
+type PublishOptions struct {
+	// 过期时间，过期后仍在通道中或等待重发的消息不再下发，零值表示不过期
+	ExpiresAt time.Time
+	// 优先级，见 PriorityHigh 等
+	Priority string
+}
+
+// 客户端的传输方式
+const (
+	TransportSSE  = "sse"
+	TransportWS   = "ws"
+	TransportPoll = "poll"
+)
+
+// Published 一次发布的结果
+type Published struct {
+	// 事件 ID，可用于查询投递状态
+	EventID string `json:"id"`
+	// 命中的在线客户端数（含因通道已满被丢弃的），0 表示无人在线
+	Matched int `json:"matched"`
+	// 定时发布：已保存的定时消息 ID 与投递时间，此时 EventID 为空，到期投递时才分配
+	ScheduledID string     `json:"scheduledId,omitempty"`
+	DeliverAt   *time.Time `json:"deliverAt,omitempty"`
+	// 幂等键重复：本次未投递，返回的是首次发布的结果
+	Duplicate bool `json:"duplicate,omitempty"`
 }
 
 type HubStats struct {
-	ClientID int64
-	UserID   int64
-	Type     string
+	ClientID  int64
+	UserID    int64
+	Type      string
+	Transport string
 }
 
 type Hub interface {
-	// 新建一个客户端，buf为客户端写通道缓存
-	NewClient(userId int64, clientType string, topics []string) *Client
+	// 新建一个客户端，超过配额时返回 ErrTooManyConnections / ErrTooManyTopics / ErrTooManyUserConnections，
+	// 按配额需要为新连接让位的旧连接收到 EventReplaced 后断开；filter 为这些主题的内容过滤表达式（见 filter.Expr），空表示不过滤
+	NewClient(userId int64, clientType string, topics []string, filter string, transport string) (*Client, error)
+	// 追加订阅，超过配额时返回 ErrTooManyTopics；filter 替换这些主题原有的过滤条件
+	Subscribe(c *Client, topics []string, filter string) error
+	// 取消订阅
+	Unsubscribe(c *Client, topics []string)
 
 	// 广播消息到某个主题
-	Broadcast(topic string, payload []byte)
+	Broadcast(topic string, payload []byte, opts PublishOptions) Published
+
+	// 设置主题的快照，key 为空表示整个主题；订阅时先以 snapshot 事件下发命中的快照，再下发实时消息。
+	// 主题不合法时返回 topic.ErrInvalidPattern，超过条数上限时返回 ErrTooManySnapshots
+	SetSnapshot(topic, key, message string) error
+	// 清除快照，key 为空时清除该主题的全部快照；返回删除的条数
+	ClearSnapshot(topic, key string) int
+	// 主题当前的快照，整个主题的快照在前，其余按键排序
+	Snapshot(topic string) []SnapshotEntry
 
 	// 根据userId发送消息
-	PublishByUserId(userId int64, message string)
+	PublishByUserId(userId int64, message string, opts PublishOptions) Published
 	// 根据客户端类型发送消息
-	PublishByClientType(clientType string, message string)
+	PublishByClientType(clientType string, message string, opts PublishOptions) Published
 	// 发送到指定客户端
-	PublishToClient(clientType string, userId int64, message string)
-	// 根据
+	PublishToClient(clientType string, userId int64, message string, opts PublishOptions) Published
+	// 以普通优先级发送到单个客户端，通道已满时返回 false 且不断开客户端，用于补发积压消息；
+	// expiresAt 零值表示不过期
+	Send(clientID int64, message string, expiresAt time.Time) bool
 	// 移除连接
 	Remove(c *Client)
+	// 主动断开指定客户端，客户端不存在时返回 false
+	Kick(clientID int64) bool
+
+	// 切换为确认模式：之后的事件以 AckPayload 下发，未确认的会超时重发；
+	// 同一用户与客户端类型之前未确认的事件立即补发
+	EnableAck(c *Client)
+	// 确认模式下确认事件，返回实际确认的条数
+	Ack(userId int64, clientType string, eventIDs []string) int
+	// 查询事件的投递状态，只保留最近的事件，过旧或不存在时返回 false
+	DeliveryStatus(eventID string) (DeliveryStatus, bool)
+
+	// 精确订阅了该主题的在线用户（通配订阅不计入，与 $presence 事件一致），按用户 ID 排序
+	Presence(topic string) []PresenceUser
+	// 用户是否在线，在线时返回其全部设备的连接情况
+	IsOnline(userId int64) (PresenceUser, bool)
 
 	// 基础统计
 	Stats() []HubStats
 
+	// 当前连接数
+	Conns() int64
+
 	// 心跳消息
 	HeaderBeat(byte []byte)
 }
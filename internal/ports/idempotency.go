@@ -0,0 +1,18 @@
+package ports
+
+import "errors"
+
+// ErrIdempotencyUnavailable 幂等键存储暂时不可用，调用方稍后重试
+var ErrIdempotencyUnavailable = errors.New("幂等键存储暂时不可用")
+
+// Idempotency 按租户记录发布请求的幂等键与首次发布的结果，窗口内重复的请求不再投递；
+// 多实例部署时由共享的持久化存储实现，各实例看到同一份记录
+type Idempotency interface {
+	// Claim 占用幂等键：键不存在或已过窗口时占用并返回 claimed=true；
+	// 否则返回首次发布的结果，首次发布尚未完成时 prev 为 nil；存储出错时返回 ErrIdempotencyUnavailable
+	Claim(tenant, key string) (prev *Published, claimed bool, err error)
+	// Complete 记录占用者的发布结果，保留到窗口结束
+	Complete(tenant, key string, res Published)
+	// Release 发布失败时释放占用，之后的重试可以重新发布
+	Release(tenant, key string)
+}
@@ -0,0 +1,47 @@
+package ports
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// InboxItem 用户收件箱中一条待确认的消息
+type InboxItem struct {
+	ID uint64 `json:"id"`
+	// 仅投递给该类型的客户端，空表示该用户的全部客户端
+	ClientType string    `json:"clientType,omitempty"`
+	Message    string    `json:"message"`
+	Time       time.Time `json:"time"`
+	// 过期时间，过期后从收件箱删除且不再补发；nil 表示不过期
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Expiry 过期时间，零值表示不过期
+func (i InboxItem) Expiry() time.Time {
+	if i.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return *i.ExpiresAt
+}
+
+// Payload 下发给客户端的内容，带上 inboxId 供客户端确认
+func (i InboxItem) Payload() string {
+	b, _ := json.Marshal(struct {
+		InboxID   uint64     `json:"inboxId"`
+		Message   string     `json:"message"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	}{i.ID, i.Message, i.ExpiresAt})
+	return string(b)
+}
+
+// Inbox 按租户与用户保存需要确认的消息，用户下次连接时补发，确认后删除
+type Inbox interface {
+	// 存入一条消息，返回分配了 ID 的条目；ID 在同一 Inbox 内单调递增；expiresAt 零值表示不过期
+	Put(tenant string, userId int64, clientType, message string, expiresAt time.Time) InboxItem
+	// 按 ID 升序返回用户未确认且未过期的消息
+	List(tenant string, userId int64) []InboxItem
+	// 确认并删除指定消息，返回实际删除的条数
+	Ack(tenant string, userId int64, ids []uint64) int
+	// 清空用户的收件箱，返回删除的条数
+	Clear(tenant string, userId int64) int
+}
@@ -0,0 +1,29 @@
+package ports
+
+import "time"
+
+// PresenceTopicPrefix 在线状态事件的系统主题前缀，主题 chat.1 的事件发到 $presence.chat.1
+const PresenceTopicPrefix = "$presence."
+
+// 在线状态事件类型
+const (
+	PresenceJoin  = "presence.join"
+	PresenceLeave = "presence.leave"
+)
+
+// PresenceUser 一个用户的在线情况，同一用户的多个设备（连接）合并为一条
+type PresenceUser struct {
+	UserId int64 `json:"userId"`
+	// 在线的连接数
+	Connections int `json:"connections"`
+	// 各连接的客户端类型，去重后排序
+	ClientTypes []string `json:"clientTypes"`
+}
+
+// PresenceEvent 用户订阅主题的第一个连接建立、或最后一个连接断开并经过去抖后，发到 $presence.<topic> 的事件
+type PresenceEvent struct {
+	Type   string    `json:"type"`
+	Topic  string    `json:"topic"`
+	UserId int64     `json:"userId"`
+	Time   time.Time `json:"time"`
+}
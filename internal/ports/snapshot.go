@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTooManySnapshots 租户的快照条数达到上限
+var ErrTooManySnapshots = errors.New("快照条数超过上限")
+
+// SnapshotEntry 主题的一条快照；Key 为空表示整个主题的快照，否则为该键的快照
+type SnapshotEntry struct {
+	Key     string    `json:"key,omitempty"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
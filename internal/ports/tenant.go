@@ -0,0 +1,63 @@
+package ports
+
+import "errors"
+
+// DefaultTenant 未解析出租户时使用的默认租户
+const DefaultTenant = "default"
+
+var (
+	// ErrUnknownTenant 严格模式下租户未在配置中声明
+	ErrUnknownTenant = errors.New("未知的租户")
+	// ErrTooManyTenants 非严格模式下未声明的租户数达到上限
+	ErrTooManyTenants = errors.New("未声明的租户数超过上限")
+	// ErrTooManyConnections 租户连接数达到配额上限
+	ErrTooManyConnections = errors.New("连接数超过配额")
+	// ErrTooManyTopics 单个客户端订阅的主题数超过配额
+	ErrTooManyTopics = errors.New("订阅主题数超过配额")
+	// ErrTooManyUserConnections 用户（或用户的某种客户端类型）的连接数达到配额上限
+	ErrTooManyUserConnections = errors.New("用户连接数超过配额")
+)
+
+// 用户连接数超过配额时的处理
+const (
+	// UserLimitReject 拒绝新连接，返回 ErrTooManyUserConnections
+	UserLimitReject = "reject"
+	// UserLimitEvictOldest 断开该用户最早的连接，被断开的连接先收到 event: replaced
+	UserLimitEvictOldest = "evictOldest"
+)
+
+// Quota 租户配额，0 表示不限制
+type Quota struct {
+	MaxConns           int `json:"maxConns"`
+	MaxTopicsPerClient int `json:"maxTopicsPerClient"`
+	PublishQps         int `json:"publishQps"`
+	// 每个用户、每个用户与客户端类型的最大连接数；未带 userId（0）的匿名连接不受限制
+	MaxConnsPerUser     int `json:"maxConnsPerUser"`
+	MaxConnsPerUserType int `json:"maxConnsPerUserType"`
+	// 超过上述上限时的处理，见 UserLimitReject 等，空表示拒绝
+	OnUserLimit string `json:"onUserLimit,omitempty"`
+	// 每个用户的每种客户端类型只保留一个连接：新连接替换旧连接，旧连接收到 event: replaced
+	SingleSession bool `json:"singleSession"`
+}
+
+// TenantStats 单个租户的统计信息
+type TenantStats struct {
+	Tenant      string `json:"tenant"`
+	Connections int    `json:"connections"`
+	Quota       Quota  `json:"quota"`
+}
+
+// Tenants 租户注册表，每个租户拥有独立的 Hub，索引互不可见
+type Tenants interface {
+	// 获取租户对应的 Hub，首次访问时创建
+	Hub(tenant string) (Hub, error)
+
+	// 租户发布限流，返回 false 表示超过 publishQps
+	AllowPublish(tenant string) bool
+
+	// 遍历已创建的租户 Hub
+	Each(fn func(tenant string, hub Hub))
+
+	// 各租户统计
+	Stats() []TenantStats
+}
@@ -0,0 +1,48 @@
+package ports
+
+import "time"
+
+// Undelivered 发布时没有命中任何在线客户端的消息
+type Undelivered struct {
+	Tenant string `json:"tenant"`
+	// 发布时分配的事件 ID
+	EventID string `json:"eventId,omitempty"`
+	// 发布目标类型，TargetTopic / TargetUser / TargetClientType / TargetClient
+	Target     string    `json:"target"`
+	Topic      string    `json:"topic,omitempty"`
+	UserId     int64     `json:"userId,omitempty"`
+	ClientType string    `json:"clientType,omitempty"`
+	Message    string    `json:"message"`
+	Time       time.Time `json:"time"`
+	// 消息的过期时间，过期后不再回调或重试；nil 表示不过期
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// OfflineSink 接收无人在线的消息，实现不能阻塞发布路径
+type OfflineSink interface {
+	Offline(m Undelivered)
+}
+
+// WebhookDelivery 一次待投递或已放弃的 webhook 回调
+type WebhookDelivery struct {
+	ID        string      `json:"id"`
+	URL       string      `json:"url"`
+	Event     Undelivered `json:"event"`
+	Attempts  int         `json:"attempts"`
+	NextAt    time.Time   `json:"nextAt"`
+	LastError string      `json:"lastError,omitempty"`
+	// 产生该回调的订阅在配置中的下标，用于选择签名密钥；多个订阅可以共用同一地址
+	Subscription int `json:"subscription"`
+}
+
+// Webhooks webhook 重试队列与死信的管理接口
+type Webhooks interface {
+	// 等待投递或重试中的回调
+	Pending() []WebhookDelivery
+	// 超过重试次数或被接收方拒绝的回调
+	DeadLetters() []WebhookDelivery
+	// 把死信重新放回队列立即投递，id 不存在时返回 false
+	Redrive(id string) bool
+	// 删除死信，id 不存在时返回 false
+	Discard(id string) bool
+}
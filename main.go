@@ -1,16 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"sse/bootstrap"
-	apiGprc "sse/internal/api/grpc"
+	apiGrpc "sse/internal/api/grpc"
 	apiHttp "sse/internal/api/http"
+	"sse/internal/app/publish"
+	"sse/internal/ports"
 	"sse/pkg/config"
-	"sse/pkg/heartbeat"
 	"strconv"
 	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
 // TIP <p>To run your code, right-click the code and select <b>Run</b>.</p> <p>Alternatively, click
@@ -18,36 +28,178 @@ import (
 var server *http.Server
 
 func main() {
+	// sse config check [--config path]：校验并打印生效配置
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "check" {
+		os.Exit(configCheck(os.Args[3:]))
+	}
+
+	configPath := flag.String("config", "", "配置文件路径，默认读取 $SSE_CONFIG 或 ./config/config.yaml")
+	flag.Parse()
+	if err := config.Load(*configPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	cfg := config.Config
 
-	container := bootstrap.NewContainer()
-	apiHttp.RegisterRoutes(container.ShardedHub)
-	newHeartbeat := heartbeat.NewHeartbeat(cfg.Sse.HeartbeatSec, container.ShardedHub)
-	newHeartbeat.Start()
+	container, err := bootstrap.NewContainer()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log := container.Logger
+	hc := container.Health
+	handler := apiHttp.RegisterRoutes(apiHttp.Deps{
+		Tenants:     container.Tenants,
+		Resolver:    container.Resolver,
+		History:     container.History,
+		Inbox:       container.Inbox,
+		Webhooks:    webhooks(container),
+		Scheduler:   scheduler(container),
+		Idempotency: container.Idempotency,
+		Health:      hc,
+		Heartbeat:   container.Heartbeat,
+		Poll: apiHttp.PollOptions{
+			Timeout:    time.Duration(cfg.Sse.PollTimeoutSec) * time.Second,
+			SessionTTL: time.Duration(cfg.Sse.PollSessionTtlSec) * time.Second,
+			BufferSize: cfg.Sse.PollBufferSize,
+		},
+		Logger:     log,
+		AdminToken: cfg.Admin.Token,
+	})
+	container.Heartbeat.Start()
+	if container.Webhooks != nil {
+		container.Webhooks.Start()
+	}
+	if container.Scheduler != nil {
+		container.Scheduler.Start()
+	}
+
+	// 监听配置文件，热更新不中断已有连接
+	if err := config.Watch(*configPath, log); err != nil {
+		log.Warn("配置热更新未启用", "err", err)
+	}
 
-	// 使用WaitGroup来同步gRPC和HTTP服务器的启动
+	// 使用WaitGroup来同步gRPC和HTTP服务器的退出
 	var wg sync.WaitGroup
+	var grpcServer *grpc.Server
 	if cfg.Grpc.Enabled {
-		apiGprc.Run(&wg, container.ShardedHub)
 		wg.Add(1)
+		grpcServer = apiGrpc.Run(&wg, container.Tenants, container.Resolver, container.Inbox, scheduler(container), container.Idempotency, hc, log)
 	}
 
-	wg.Add(1)
+	// 先绑定端口，监听成功后才算 HTTP 就绪
+	hc.Expect("http")
+	ln, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.Server.Addr))
+	if err != nil {
+		log.Error("failed to listen HTTP", "err", err)
+		os.Exit(1)
+	}
+	hc.Set("http", nil)
+
+	server = &http.Server{
+		Handler:      handler,
+		ReadTimeout:  0,
+		WriteTimeout: 0,
+	}
 
 	// 启动HTTP服务器的goroutine
+	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		server = &http.Server{
-			Addr:         ":" + strconv.Itoa(cfg.Server.Addr), // 替换为您的HTTP服务器监听的端口
-			ReadTimeout:  0,
-			WriteTimeout: 0,
-		}
-		if err := server.ListenAndServe(); err != nil {
-			log.Fatalf("failed to serve HTTP: %s", err)
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			hc.Set("http", err)
+			log.Error("failed to serve HTTP", "err", err)
+			os.Exit(1)
 		}
 	}()
+	log.Info("Servers are running...", "addr", ln.Addr().String())
+
+	// 等待退出信号后优雅关闭
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	log.Info("收到退出信号，开始排空", "signal", (<-sig).String())
+	// 先停止定时投递，未到期与未投递的消息留到下次启动
+	if container.Scheduler != nil {
+		if err := container.Scheduler.Close(); err != nil {
+			log.Warn("写入定时消息快照失败", "err", err)
+		}
+	}
+	shutdown(cfg.Server.ShutdownGraceSec, cfg.Server.ShutdownTimeoutSec, container, grpcServer)
 
 	// 等待两个服务器完成
 	wg.Wait()
-	fmt.Println("Servers are running...")
+	// 连接都已断开，不会再有新的离线消息，写入最后的重试队列
+	if container.Webhooks != nil {
+		if err := container.Webhooks.Close(); err != nil {
+			log.Warn("写入 webhook 队列快照失败", "err", err)
+		}
+	}
+	log.Info("Servers stopped")
+}
+
+// webhooks 未启用时返回 nil 接口，避免 typed nil
+func webhooks(container *bootstrap.Container) ports.Webhooks {
+	if container.Webhooks == nil {
+		return nil
+	}
+	return container.Webhooks
+}
+
+// scheduler 未启用时返回 nil 接口，避免 typed nil
+func scheduler(container *bootstrap.Container) publish.Scheduler {
+	if container.Scheduler == nil {
+		return nil
+	}
+	return container.Scheduler
+}
+
+// configCheck 校验配置文件，成功时打印隐藏敏感信息后的生效配置
+func configCheck(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+	configPath := fs.String("config", "", "配置文件路径，默认读取 $SSE_CONFIG 或 ./config/config.yaml")
+	_ = fs.Parse(args)
+
+	cfg, path, err := config.Read(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("# 配置文件: %s（已叠加默认值与 %s_* 环境变量）\n", path, config.EnvPrefix)
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// shutdown 先让就绪探针失败，等待负载均衡摘除后再关闭监听器，超时后强制断开剩余连接
+func shutdown(graceSec, timeoutSec int, container *bootstrap.Container, grpcServer *grpc.Server) {
+	log := container.Logger
+
+	container.Health.Drain()
+	time.Sleep(time.Duration(graceSec) * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		go func() {
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+
+	// SSE 连接不会自行结束，超时后直接关闭底层连接，触发各连接的断开清理
+	if err := server.Shutdown(ctx); err != nil {
+		log.Warn("HTTP 排空超时，强制关闭剩余连接", "err", err)
+		_ = server.Close()
+	}
 }
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event 一条 SSE 事件
+type Event struct {
+	// id 字段；未设置时沿用上一条事件的 id（即 Last-Event-ID）
+	ID string
+	// event 字段，未设置时为 message
+	Event string
+	// data 字段，多行 data 以 \n 连接
+	Data string
+}
+
+// Parser 按 WHATWG HTML 规范解析 text/event-stream
+type Parser struct {
+	scanner *bufio.Scanner
+
+	lastID string
+	data   strings.Builder
+	event  string
+	// 最近一次 retry 字段的值，按规范立即生效，不随事件分发
+	retry time.Duration
+	// 是否已处理过第一行（用于去掉 UTF-8 BOM）
+	started bool
+}
+
+// NewParser 创建解析器
+func NewParser(r io.Reader) *Parser {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	s.Split(scanLines)
+	return &Parser{scanner: s}
+}
+
+// SetLastEventID 设置初始的 Last-Event-ID，重连时用于延续 id
+func (p *Parser) SetLastEventID(id string) {
+	p.lastID = id
+}
+
+// LastEventID 最近一次 id 字段的值
+func (p *Parser) LastEventID() string {
+	return p.lastID
+}
+
+// Retry 服务端通过 retry 字段建议的重连间隔，未设置时为 0
+func (p *Parser) Retry() time.Duration {
+	return p.retry
+}
+
+// Next 读取下一条事件；流结束时返回 io.EOF，未以空行结尾的半条事件按规范丢弃
+func (p *Parser) Next() (Event, error) {
+	for p.scanner.Scan() {
+		line := p.scanner.Text()
+		if !p.started {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			p.started = true
+		}
+
+		// 空行：分发事件
+		if line == "" {
+			if ev, ok := p.dispatch(); ok {
+				return ev, nil
+			}
+			continue
+		}
+		// 注释
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimPrefix(value, " ")
+		}
+		p.process(field, value)
+	}
+
+	if err := p.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+func (p *Parser) process(field, value string) {
+	switch field {
+	case "event":
+		p.event = value
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+	case "id":
+		// 含 NULL 的 id 按规范忽略
+		if !strings.ContainsRune(value, 0) {
+			p.lastID = value
+		}
+	case "retry":
+		if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+			p.retry = time.Duration(ms) * time.Millisecond
+		}
+	}
+	// 其他字段忽略
+}
+
+func (p *Parser) dispatch() (Event, bool) {
+	data := p.data.String()
+	event := p.event
+	p.data.Reset()
+	p.event = ""
+
+	// data 为空时不分发
+	if data == "" {
+		return Event{}, false
+	}
+	if event == "" {
+		event = "message"
+	}
+	return Event{
+		ID:    p.lastID,
+		Event: event,
+		Data:  strings.TrimSuffix(data, "\n"),
+	}, true
+}
+
+// scanLines 按 CRLF、LF、CR 三种换行切分
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// \r 后需要再看一个字节才能判断是否为 \r\n
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll 读出流中的全部事件
+func readAll(t *testing.T, p *Parser) []Event {
+	t.Helper()
+	var out []Event
+	for {
+		ev, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		out = append(out, ev)
+	}
+}
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []Event
+	}{
+		{
+			name:   "单行 data",
+			stream: "data: hello\n\n",
+			want:   []Event{{Event: "message", Data: "hello"}},
+		},
+		{
+			name:   "多行 data 以换行连接",
+			stream: "data: a\ndata: b\ndata:\ndata: c\n\n",
+			want:   []Event{{Event: "message", Data: "a\nb\n\nc"}},
+		},
+		{
+			name:   "冒号后只去掉一个空格",
+			stream: "data:no-space\n\ndata:  two\n\n",
+			want:   []Event{{Event: "message", Data: "no-space"}, {Event: "message", Data: " two"}},
+		},
+		{
+			name:   "event 与 id",
+			stream: "event: snapshot\nid: 7\ndata: x\n\ndata: y\n\n",
+			want:   []Event{{ID: "7", Event: "snapshot", Data: "x"}, {ID: "7", Event: "message", Data: "y"}},
+		},
+		{
+			name:   "空 id 清除 Last-Event-ID",
+			stream: "id: 1\ndata: a\n\nid\ndata: b\n\n",
+			want:   []Event{{ID: "1", Event: "message", Data: "a"}, {ID: "", Event: "message", Data: "b"}},
+		},
+		{
+			name:   "含 NULL 的 id 被忽略",
+			stream: "id: 1\ndata: a\n\nid: 2\x003\ndata: b\n\n",
+			want:   []Event{{ID: "1", Event: "message", Data: "a"}, {ID: "1", Event: "message", Data: "b"}},
+		},
+		{
+			name:   "注释与未知字段",
+			stream: ": keepalive\nfoo: bar\ndata: a\n: inside\n\n",
+			want:   []Event{{Event: "message", Data: "a"}},
+		},
+		{
+			name:   "没有 data 的事件不分发",
+			stream: "event: ping\n\nid: 3\n\ndata: a\n\n",
+			want:   []Event{{ID: "3", Event: "message", Data: "a"}},
+		},
+		{
+			name:   "未以空行结尾的半条事件丢弃",
+			stream: "data: a\n\ndata: partial",
+			want:   []Event{{Event: "message", Data: "a"}},
+		},
+		{
+			name:   "CRLF 换行",
+			stream: "event: e\r\ndata: a\r\ndata: b\r\n\r\n",
+			want:   []Event{{Event: "e", Data: "a\nb"}},
+		},
+		{
+			name:   "CR 换行",
+			stream: "data: a\rdata: b\r\rdata: c\r\r",
+			want:   []Event{{Event: "message", Data: "a\nb"}, {Event: "message", Data: "c"}},
+		},
+		{
+			name:   "混合换行",
+			stream: "data: a\r\ndata: b\rdata: c\n\r\n",
+			want:   []Event{{Event: "message", Data: "a\nb\nc"}},
+		},
+		{
+			name:   "开头的 BOM 被去掉",
+			stream: "\uFEFFdata: a\n\n",
+			want:   []Event{{Event: "message", Data: "a"}},
+		},
+		{
+			name:   "只去掉开头的 BOM",
+			stream: "data: a\n\n\uFEFFdata: b\n\n",
+			want:   []Event{{Event: "message", Data: "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readAll(t, NewParser(strings.NewReader(tt.stream)))
+			if len(got) != len(tt.want) {
+				t.Fatalf("得到 %d 条事件 %+v，期望 %d 条 %+v", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("第 %d 条为 %+v，期望 %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// oneByte 每次只返回一个字节，覆盖 \r\n 被拆在两次读取之间的情况
+type oneByte struct{ r io.Reader }
+
+func (o oneByte) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return o.r.Read(p[:1])
+}
+
+func TestParserSplitCRLF(t *testing.T) {
+	got := readAll(t, NewParser(oneByte{strings.NewReader("data: a\r\ndata: b\r\n\r\n")}))
+	if len(got) != 1 || got[0].Data != "a\nb" {
+		t.Fatalf("得到 %+v", got)
+	}
+}
+
+func TestParserRetry(t *testing.T) {
+	p := NewParser(strings.NewReader("retry: 1500\ndata: a\n\nretry: x\ndata: b\n\n"))
+	if _, err := p.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retry() != 1500*time.Millisecond {
+		t.Fatalf("retry 为 %v", p.Retry())
+	}
+	// 不是整数的 retry 忽略，保留之前的值
+	if _, err := p.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retry() != 1500*time.Millisecond {
+		t.Fatalf("retry 为 %v", p.Retry())
+	}
+}
+
+func TestParserLastEventID(t *testing.T) {
+	p := NewParser(strings.NewReader("data: a\n\nid: 9\n\n"))
+	p.SetLastEventID("5")
+	ev, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != "5" {
+		t.Fatalf("id 为 %q，期望沿用初始的 5", ev.ID)
+	}
+	if _, err := p.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("期望 EOF，得到 %v", err)
+	}
+	if p.LastEventID() != "9" {
+		t.Fatalf("LastEventID 为 %q", p.LastEventID())
+	}
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// State 连接状态
+type State int
+
+const (
+	// Connecting 正在建立连接（首次或重连）
+	Connecting State = iota
+	// Connected 已收到 200 text/event-stream 响应
+	Connected
+	// Disconnected 连接断开，随后会按退避重连
+	Disconnected
+	// Closed 不再重连：ctx 取消、服务端返回 204/4xx 或达到最大重试次数
+	Closed
+)
+
+func (s State) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Closed:
+		return "closed"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// StatusError 服务端返回了非 200 响应
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sse: 服务端返回 %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// ErrContentType 响应不是 text/event-stream
+var ErrContentType = errors.New("sse: 响应 Content-Type 不是 text/event-stream")
+
+// Options 订阅选项，零值可用
+type Options struct {
+	// 发起请求的 http.Client，默认为不设超时的客户端
+	HTTPClient *http.Client
+	// 附加请求头，如 Authorization、X-Tenant
+	Header http.Header
+	// 首次连接携带的 Last-Event-ID
+	LastEventID string
+
+	// 初始重连间隔，默认 1s；服务端下发 retry 后以服务端为准
+	InitialBackoff time.Duration
+	// 最大重连间隔，默认 30s
+	MaxBackoff time.Duration
+	// 连续失败的最大重试次数，0 表示无限重试
+	MaxRetries int
+
+	// 事件通道缓冲，默认 64
+	BufferSize int
+	// 连接状态变化回调，err 为导致断开的原因；回调在订阅协程中同步执行
+	OnStateChange func(state State, err error)
+}
+
+func (o *Options) setDefaults() {
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{}
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 64
+	}
+}
+
+// Subscribe 订阅 SSE 流，返回的通道在 ctx 取消或不再重连时关闭。
+// 断线后按指数退避加随机抖动自动重连，并携带最近一次的 Last-Event-ID。
+func Subscribe(ctx context.Context, url string, opts Options) (<-chan Event, error) {
+	opts.setDefaults()
+	if _, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil); err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event, opts.BufferSize)
+	s := &subscription{url: url, opts: opts, events: events, lastID: opts.LastEventID}
+	go s.run(ctx)
+	return events, nil
+}
+
+type subscription struct {
+	url    string
+	opts   Options
+	events chan<- Event
+
+	lastID string
+	// 服务端通过 retry 字段指定的重连间隔
+	retry time.Duration
+}
+
+func (s *subscription) run(ctx context.Context) {
+	defer close(s.events)
+
+	failures := 0
+	for {
+		s.notify(Connecting, nil)
+		connected, err := s.connect(ctx)
+		if ctx.Err() != nil {
+			s.notify(Closed, ctx.Err())
+			return
+		}
+		if !retryable(err) {
+			s.notify(Closed, err)
+			return
+		}
+		s.notify(Disconnected, err)
+
+		// 成功建立过连接则重新计算退避
+		if connected {
+			failures = 0
+		}
+		failures++
+		if s.opts.MaxRetries > 0 && failures > s.opts.MaxRetries {
+			s.notify(Closed, err)
+			return
+		}
+
+		timer := time.NewTimer(s.backoff(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.notify(Closed, ctx.Err())
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// connect 建立一次连接并持续读取事件，返回是否成功建立过连接以及断开原因
+func (s *subscription) connect(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	for k, v := range s.opts.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if s.lastID != "" {
+		req.Header.Set("Last-Event-ID", s.lastID)
+	}
+
+	resp, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, &StatusError{Code: resp.StatusCode}
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/event-stream" {
+		return false, ErrContentType
+	}
+	s.notify(Connected, nil)
+
+	p := NewParser(resp.Body)
+	p.SetLastEventID(s.lastID)
+	for {
+		ev, err := p.Next()
+		s.lastID = p.LastEventID()
+		if r := p.Retry(); r > 0 {
+			s.retry = r
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return true, err
+		}
+
+		select {
+		case s.events <- ev:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// backoff 第 n 次失败后的等待时间：base*2^(n-1)，上限 MaxBackoff，再叠加 ±50% 抖动
+func (s *subscription) backoff(n int) time.Duration {
+	base := s.opts.InitialBackoff
+	if s.retry > 0 {
+		base = s.retry
+	}
+	d := base
+	for i := 1; i < n && d < s.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.opts.MaxBackoff {
+		d = s.opts.MaxBackoff
+	}
+	return d/2 + rand.N(d)
+}
+
+func (s *subscription) notify(state State, err error) {
+	if s.opts.OnStateChange != nil {
+		s.opts.OnStateChange(state, err)
+	}
+}
+
+// retryable 按规范 204 表示不再重连；4xx 视为请求本身有误，408/429 除外
+func retryable(err error) bool {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return !errors.Is(err, ErrContentType)
+	}
+	switch {
+	case se.Code == http.StatusNoContent:
+		return false
+	case se.Code == http.StatusRequestTimeout, se.Code == http.StatusTooManyRequests:
+		return true
+	case se.Code >= 400 && se.Code < 500:
+		return false
+	}
+	return true
+}
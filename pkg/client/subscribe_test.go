@@ -0,0 +1,353 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sse/internal/adapters/hub"
+	"sse/internal/adapters/tenant"
+	apihttp "sse/internal/api/http"
+	"sse/internal/ports"
+	"sse/pkg/client"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer 用 httptest 运行项目自己的 Sse 处理器。
+// Sse 不下发 id 与 retry，为覆盖重连逻辑，每个连接建立后先写出一段只含 id（连接序号）与 retry 的前导
+type testServer struct {
+	hub *hub.ShardedHub
+	url string
+
+	mu sync.Mutex
+	// 每次请求携带的 Last-Event-ID 与到达时间
+	lastIDs []string
+	times   []time.Time
+	// 依次用于前几次请求的状态码，用完后交给 Sse 处理
+	statuses []int
+	// 写在前导中的 retry（毫秒），0 表示不写
+	retryMs int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ts := &testServer{hub: hub.NewShardedHub(ports.Quota{}, &hub.Settings{}, log)}
+	tenants := tenant.NewRegistry(func(string, ports.Quota) ports.Hub { return ts.hub }, ports.Quota{}, nil, false, 0, 0)
+	sse := apihttp.Sse(tenants, log)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ts.lastIDs = append(ts.lastIDs, r.Header.Get("Last-Event-ID"))
+		ts.times = append(ts.times, time.Now())
+		n := len(ts.lastIDs)
+		status := 0
+		if len(ts.statuses) > 0 {
+			status, ts.statuses = ts.statuses[0], ts.statuses[1:]
+		}
+		preamble := fmt.Sprintf("id: %d\n", n)
+		if ts.retryMs > 0 {
+			preamble += fmt.Sprintf("retry: %d\n", ts.retryMs)
+		}
+		ts.mu.Unlock()
+
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		sse(&preambleWriter{ResponseWriter: w, preamble: preamble + "\n"}, r)
+	}))
+	ts.url = srv.URL + "/sse?userId=1&clientType=web&topics=news"
+	t.Cleanup(srv.Close)
+	return ts
+}
+
+// requests 已收到的请求数
+func (ts *testServer) requests() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return len(ts.lastIDs)
+}
+
+// kickAll 断开所有连接，模拟服务端重启
+func (ts *testServer) kickAll() {
+	for _, s := range ts.hub.Stats() {
+		ts.hub.Kick(s.ClientID)
+	}
+}
+
+// preambleWriter 在 200 响应头之后写出前导
+type preambleWriter struct {
+	http.ResponseWriter
+	preamble string
+}
+
+func (p *preambleWriter) WriteHeader(code int) {
+	p.ResponseWriter.WriteHeader(code)
+	if code == http.StatusOK {
+		_, _ = io.WriteString(p.ResponseWriter, p.preamble)
+	}
+}
+
+func (p *preambleWriter) Flush() {
+	p.ResponseWriter.(http.Flusher).Flush()
+}
+
+// CloseNotify Sse 依赖它感知客户端断开
+func (p *preambleWriter) CloseNotify() <-chan bool {
+	return p.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// stateLog 记录 OnStateChange 回调
+type stateLog struct {
+	mu     sync.Mutex
+	states []client.State
+	errs   []error
+	ch     chan client.State
+}
+
+func newStateLog() *stateLog {
+	return &stateLog{ch: make(chan client.State, 64)}
+}
+
+func (l *stateLog) record(s client.State, err error) {
+	l.mu.Lock()
+	l.states = append(l.states, s)
+	l.errs = append(l.errs, err)
+	l.mu.Unlock()
+	l.ch <- s
+}
+
+// wait 等待进入状态 want
+func (l *stateLog) wait(t *testing.T, want client.State) {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case s := <-l.ch:
+			if s == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("等待 %v 超时，已记录 %v", want, l.snapshot())
+		}
+	}
+}
+
+func (l *stateLog) snapshot() []client.State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]client.State(nil), l.states...)
+}
+
+func recv(t *testing.T, events <-chan client.Event) client.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("事件通道已关闭")
+		}
+		return ev
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待事件超时")
+	}
+	return client.Event{}
+}
+
+func TestSubscribeTypedDelivery(t *testing.T) {
+	ts := newTestServer(t)
+	if err := ts.hub.SetSnapshot("news", "", "snap"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states := newStateLog()
+	events, err := client.Subscribe(ctx, ts.url, client.Options{OnStateChange: states.record})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states.wait(t, client.Connected)
+
+	// 订阅时先收到快照（带事件类型），再收到之后发布的消息
+	if ev := recv(t, events); ev.Event != ports.EventSnapshot || ev.Data != "snap" || ev.ID != "1" {
+		t.Fatalf("快照为 %+v", ev)
+	}
+	ts.hub.Broadcast("news", []byte("hello"), ports.PublishOptions{})
+	if ev := recv(t, events); ev.Event != "message" || ev.Data != "hello" || ev.ID != "1" {
+		t.Fatalf("消息为 %+v", ev)
+	}
+
+	cancel()
+	for range events {
+	}
+	states.wait(t, client.Closed)
+}
+
+func TestSubscribeReconnect(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states := newStateLog()
+	events, err := client.Subscribe(ctx, ts.url, client.Options{
+		LastEventID:    "0",
+		InitialBackoff: 20 * time.Millisecond,
+		OnStateChange:  states.record,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states.wait(t, client.Connected)
+	ts.hub.Broadcast("news", []byte("a"), ports.PublishOptions{})
+	recv(t, events)
+
+	ts.kickAll()
+	states.wait(t, client.Disconnected)
+	states.wait(t, client.Connected)
+	ts.hub.Broadcast("news", []byte("b"), ports.PublishOptions{})
+	if ev := recv(t, events); ev.Data != "b" || ev.ID != "2" {
+		t.Fatalf("重连后的消息为 %+v", ev)
+	}
+
+	ts.mu.Lock()
+	lastIDs := append([]string(nil), ts.lastIDs...)
+	ts.mu.Unlock()
+	// 首次连接携带 Options.LastEventID，重连携带上一个连接最后的 id
+	if len(lastIDs) != 2 || lastIDs[0] != "0" || lastIDs[1] != "1" {
+		t.Fatalf("Last-Event-ID 依次为 %q", lastIDs)
+	}
+
+	want := []client.State{client.Connecting, client.Connected, client.Disconnected, client.Connecting, client.Connected}
+	got := states.snapshot()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("状态依次为 %v，期望 %v", got, want)
+	}
+	states.mu.Lock()
+	disconnectErr := states.errs[2]
+	states.mu.Unlock()
+	if !errors.Is(disconnectErr, io.ErrUnexpectedEOF) {
+		t.Fatalf("断开原因为 %v", disconnectErr)
+	}
+}
+
+func TestSubscribeBackoff(t *testing.T) {
+	ts := newTestServer(t)
+	ts.statuses = []int{503, 503, 503, 503}
+	const base = 20 * time.Millisecond
+
+	states := newStateLog()
+	events, err := client.Subscribe(context.Background(), ts.url, client.Options{
+		InitialBackoff: base,
+		MaxBackoff:     time.Second,
+		MaxRetries:     3,
+		OnStateChange:  states.record,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range events {
+	}
+	// 连续失败 3 次后的第 4 次失败不再重试
+	if n := ts.requests(); n != 4 {
+		t.Fatalf("请求了 %d 次", n)
+	}
+	states.mu.Lock()
+	last, lastErr := states.states[len(states.states)-1], states.errs[len(states.errs)-1]
+	states.mu.Unlock()
+	var se *client.StatusError
+	if last != client.Closed || !errors.As(lastErr, &se) || se.Code != 503 {
+		t.Fatalf("最后状态为 %v %v", last, lastErr)
+	}
+
+	// 第 n 次重试前至少等待 base*2^(n-1) 的一半（抖动为 ±50%）
+	ts.mu.Lock()
+	times := ts.times
+	ts.mu.Unlock()
+	for i := 1; i < len(times); i++ {
+		least := base << (i - 1) / 2
+		if gap := times[i].Sub(times[i-1]); gap < least {
+			t.Errorf("第 %d 次重试间隔 %v，期望至少 %v", i, gap, least)
+		}
+	}
+}
+
+func TestSubscribeRetryOverride(t *testing.T) {
+	ts := newTestServer(t)
+	ts.retryMs = 300
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states := newStateLog()
+	_, err := client.Subscribe(ctx, ts.url, client.Options{
+		InitialBackoff: 10 * time.Millisecond,
+		OnStateChange:  states.record,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states.wait(t, client.Connected)
+	// 前导在响应头之后写出，稍等客户端读到 retry
+	time.Sleep(50 * time.Millisecond)
+	ts.kickAll()
+	states.wait(t, client.Connected)
+
+	ts.mu.Lock()
+	gap := ts.times[1].Sub(ts.times[0])
+	ts.mu.Unlock()
+	// 服务端的 retry 取代 InitialBackoff：踢出前的 50ms 加上至少 300ms 的一半
+	if gap < 50*time.Millisecond+150*time.Millisecond {
+		t.Fatalf("重连间隔 %v，未使用服务端的 retry", gap)
+	}
+}
+
+func TestSubscribeNoRetry(t *testing.T) {
+	for _, code := range []int{http.StatusNoContent, http.StatusBadRequest, http.StatusNotFound} {
+		t.Run(http.StatusText(code), func(t *testing.T) {
+			ts := newTestServer(t)
+			ts.statuses = []int{code}
+
+			states := newStateLog()
+			events, err := client.Subscribe(context.Background(), ts.url, client.Options{
+				InitialBackoff: 10 * time.Millisecond,
+				OnStateChange:  states.record,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range events {
+			}
+			if n := ts.requests(); n != 1 {
+				t.Fatalf("请求了 %d 次", n)
+			}
+			if got := states.snapshot(); fmt.Sprint(got) != fmt.Sprint([]client.State{client.Connecting, client.Closed}) {
+				t.Fatalf("状态依次为 %v", got)
+			}
+		})
+	}
+}
+
+func TestSubscribeRetryableStatus(t *testing.T) {
+	ts := newTestServer(t)
+	ts.statuses = []int{http.StatusTooManyRequests, http.StatusRequestTimeout}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states := newStateLog()
+	if _, err := client.Subscribe(ctx, ts.url, client.Options{
+		InitialBackoff: 10 * time.Millisecond,
+		OnStateChange:  states.record,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 429 与 408 之后继续重连，第三次交给 Sse 处理
+	states.wait(t, client.Connected)
+	if n := ts.requests(); n != 3 {
+		t.Fatalf("请求了 %d 次", n)
+	}
+}
@@ -1,95 +1,187 @@
 package config
 
-import (
-	"github.com/spf13/viper"
-	"os"
-)
-
-var Config = loadConfig()
+// Config 启动时加载的配置，由 Load 设置；热更新后的值通过 Current 获取
+var Config *config
 
 type config struct {
 	Server struct {
-		Addr int `yaml:"addr"`
-	} `yaml:"server"`
+		Addr               int `yaml:"addr" mapstructure:"addr"`
+		ShutdownGraceSec   int `yaml:"shutdownGraceSec" mapstructure:"shutdownGraceSec"`     // 就绪探针失败后等待负载均衡摘除的时间
+		ShutdownTimeoutSec int `yaml:"shutdownTimeoutSec" mapstructure:"shutdownTimeoutSec"` // 等待已有请求结束的最长时间，超时后强制关闭
+	} `yaml:"server" mapstructure:"server"`
 
 	Sse struct {
-		HeartbeatSec    int `yaml:"heartbeatSec"`    // 心跳时间
-		ClientChanSize  int `yaml:"clientChanSize"`  // 客户端通道大小
-		WriteTimeoutSec int `yaml:"writeTimeoutSec"` // 写超时时间
-	} `yaml:"sse"`
+		HeartbeatSec    int `yaml:"heartbeatSec" mapstructure:"heartbeatSec"`       // 心跳时间
+		ClientChanSize  int `yaml:"clientChanSize" mapstructure:"clientChanSize"`   // 客户端通道大小，已由 hub.lanes 取代
+		WriteTimeoutSec int `yaml:"writeTimeoutSec" mapstructure:"writeTimeoutSec"` // 写超时时间
+		// 长轮询
+		PollTimeoutSec    int `yaml:"pollTimeoutSec" mapstructure:"pollTimeoutSec"`       // 单次轮询最长挂起时间
+		PollSessionTtlSec int `yaml:"pollSessionTtlSec" mapstructure:"pollSessionTtlSec"` // 两次轮询间会话的保留时间
+		PollBufferSize    int `yaml:"pollBufferSize" mapstructure:"pollBufferSize"`       // 会话缓存的最大事件数
+	} `yaml:"sse" mapstructure:"sse"`
 
 	Hub struct {
-		Shards         int  `yaml:"shards"`         // 分片数
-		DropSlowClient bool `yaml:"dropSlowClient"` // 是否丢弃慢客户端
-	} `yaml:"hub"`
+		Shards         int  `yaml:"shards" mapstructure:"shards"`                 // 分片数
+		DropSlowClient bool `yaml:"dropSlowClient" mapstructure:"dropSlowClient"` // 是否丢弃慢客户端
+
+		Ack struct {
+			TimeoutSec  int `yaml:"timeoutSec" mapstructure:"timeoutSec"`   // 确认超时，超时后在心跳时重发
+			MaxAttempts int `yaml:"maxAttempts" mapstructure:"maxAttempts"` // 最多下发次数，之后记为失败
+			Window      int `yaml:"window" mapstructure:"window"`           // 每个用户与客户端类型最多未确认的事件数
+			StatusMax   int `yaml:"statusMax" mapstructure:"statusMax"`     // 每个租户保留投递状态的最近事件数
+		} `yaml:"ack" mapstructure:"ack"`
+
+		Conflate    []ConflateRule `yaml:"conflate" mapstructure:"conflate"`       // 合并主题
+		SnapshotMax int            `yaml:"snapshotMax" mapstructure:"snapshotMax"` // 每个租户最多保存的快照条数
+
+		// 每个连接的优先级通道
+		Lanes struct {
+			High   Lane `yaml:"high" mapstructure:"high"`
+			Normal Lane `yaml:"normal" mapstructure:"normal"`
+			Low    Lane `yaml:"low" mapstructure:"low"`
+		} `yaml:"lanes" mapstructure:"lanes"`
+
+		// 在线状态事件
+		Presence struct {
+			Topics     []string `yaml:"topics" mapstructure:"topics"`         // 发出 $presence.<topic> 事件的主题，空表示不发出
+			DebounceMs int      `yaml:"debounceMs" mapstructure:"debounceMs"` // 最后一个连接离开后等待该时间再发出 leave
+		} `yaml:"presence" mapstructure:"presence"`
+	} `yaml:"hub" mapstructure:"hub"`
 
 	Redis struct {
-		Addr   string `yaml:"addr"`   // Redis 地址
-		Passwd string `yaml:"passwd"` // Redis 密码
-		Db     int    `yaml:"db"`     // 数据库
+		Addr   string `yaml:"addr" mapstructure:"addr"`     // Redis 地址
+		Passwd string `yaml:"passwd" mapstructure:"passwd"` // Redis 密码
+		Db     int    `yaml:"db" mapstructure:"db"`         // 数据库
 
 		Streams struct {
-			Enabled bool `yaml:"enabled"` // 是否启用
-			Maxlen  int  `yaml:"maxlen"`  // 每个 topic 的保留条数
-		} `yaml:"streams"`
+			Enabled bool `yaml:"enabled" mapstructure:"enabled"` // 是否启用
+			Maxlen  int  `yaml:"maxlen" mapstructure:"maxlen"`   // 每个 topic 的保留条数
+		} `yaml:"streams" mapstructure:"streams"`
 
 		Pubsub struct {
-			Pattern     string `yaml:"pattern"`     // 订阅模式
-			PumpWorkers int    `yaml:"pumpWorkers"` // 并行解析/分发 worker 数
-		} `yaml:"pubsub"`
-	} `yaml:"redis"`
+			Pattern     string `yaml:"pattern" mapstructure:"pattern"`           // 订阅模式
+			PumpWorkers int    `yaml:"pump_workers" mapstructure:"pump_workers"` // 并行解析/分发 worker 数
+		} `yaml:"pubsub" mapstructure:"pubsub"`
+	} `yaml:"redis" mapstructure:"redis"`
 
 	Persistence struct {
-		Enabled bool   `yaml:"enabled"` // 启用持久化
-		Kind    string `yaml:"kind"`    // 持久化类型
-		Dsn     string `yaml:"dsn"`     // 数据源名称
+		Enabled bool   `yaml:"enabled" mapstructure:"enabled"` // 启用持久化
+		Kind    string `yaml:"kind" mapstructure:"kind"`       // 持久化类型
+		Dsn     string `yaml:"dsn" mapstructure:"dsn"`         // 数据源名称
 		Batch   struct {
-			Enabled         bool `yaml:"enabled"`         // 批处理是否启用
-			MaxItems        int  `yaml:"maxItems"`        // 最大条目数
-			MaxBytes        int  `yaml:"maxBytes"`        // 最大字节数
-			FlushIntervalMs int  `yaml:"flushIntervalMs"` // 刷新间隔
-		} `yaml:"batch"`
+			Enabled         bool `yaml:"enabled" mapstructure:"enabled"`                 // 批处理是否启用
+			MaxItems        int  `yaml:"maxItems" mapstructure:"maxItems"`               // 最大条目数
+			MaxBytes        int  `yaml:"maxBytes" mapstructure:"maxBytes"`               // 最大字节数
+			FlushIntervalMs int  `yaml:"flushIntervalMs" mapstructure:"flushIntervalMs"` // 刷新间隔
+		} `yaml:"batch" mapstructure:"batch"`
 
 		Topics struct {
-			Include []string `yaml:"include"` // 包含的主题
-			Exclude []string `yaml:"exclude"` // 排除的主题
-		} `yaml:"topics"`
+			Include []string `yaml:"include" mapstructure:"include"` // 包含的主题
+			Exclude []string `yaml:"exclude" mapstructure:"exclude"` // 排除的主题
+		} `yaml:"topics" mapstructure:"topics"`
 
 		Retention struct {
-			Days int `yaml:"days"` // 保留天数
-		} `yaml:"retention"`
-	} `yaml:"persistence"`
+			Days int `yaml:"days" mapstructure:"days"` // 保留天数
+		} `yaml:"retention" mapstructure:"retention"`
+
+		Inbox struct {
+			MaxItems    int `yaml:"maxItems" mapstructure:"maxItems"`       // 每个用户保留的未确认消息数
+			MaxAgeHours int `yaml:"maxAgeHours" mapstructure:"maxAgeHours"` // 未确认消息的保留时长
+		} `yaml:"inbox" mapstructure:"inbox"`
+	} `yaml:"persistence" mapstructure:"persistence"`
 
 	Publish struct {
-		DefaultMaxlen int `yaml:"defaultMaxlen"` // 默认最大长度
-		RateLimitQps  int `yaml:"rateLimitQps"`  // 限流 QPS
-	} `yaml:"publish"`
+		DefaultMaxlen int `yaml:"defaultMaxlen" mapstructure:"defaultMaxlen"`   // 默认最大长度
+		RateLimitQps  int `yaml:"rate_limit_qps" mapstructure:"rate_limit_qps"` // 限流 QPS
+
+		// 发布幂等键去重
+		Idempotency struct {
+			Enabled   bool `yaml:"enabled" mapstructure:"enabled"`
+			WindowSec int  `yaml:"windowSec" mapstructure:"windowSec"` // 重复的键在该时间内不再投递
+			MaxKeys   int  `yaml:"maxKeys" mapstructure:"maxKeys"`     // 最多保留的键数，超出丢弃最早的
+		} `yaml:"idempotency" mapstructure:"idempotency"`
+	} `yaml:"publish" mapstructure:"publish"`
+
+	Webhook struct {
+		Enabled          bool   `yaml:"enabled" mapstructure:"enabled"`                   // 无人在线的消息回调到 HTTP 地址
+		Secret           string `yaml:"secret" mapstructure:"secret"`                     // 默认 HMAC-SHA256 签名密钥
+		TimeoutSec       int    `yaml:"timeoutSec" mapstructure:"timeoutSec"`             // 单次回调超时
+		MaxAttempts      int    `yaml:"maxAttempts" mapstructure:"maxAttempts"`           // 最多尝试次数，之后转入死信
+		InitialBackoffMs int    `yaml:"initialBackoffMs" mapstructure:"initialBackoffMs"` // 首次重试等待，之后每次翻倍
+		MaxBackoffSec    int    `yaml:"maxBackoffSec" mapstructure:"maxBackoffSec"`       // 重试等待上限
+		Workers          int    `yaml:"workers" mapstructure:"workers"`                   // 并发回调数
+		QueueMax         int    `yaml:"queueMax" mapstructure:"queueMax"`                 // 重试队列上限
+		QueueFile        string `yaml:"queueFile" mapstructure:"queueFile"`               // 重试队列快照文件，空表示不落盘
+		DeadLetterMax    int    `yaml:"deadLetterMax" mapstructure:"deadLetterMax"`       // 死信保留条数
+
+		Subscriptions []struct {
+			Url         string   `yaml:"url" mapstructure:"url"`                 // 回调地址
+			Secret      string   `yaml:"secret" mapstructure:"secret"`           // 签名密钥，空时使用 webhook.secret
+			Tenants     []string `yaml:"tenants" mapstructure:"tenants"`         // 限定租户，空表示全部
+			Topics      []string `yaml:"topics" mapstructure:"topics"`           // 无人订阅的 topic，支持通配
+			ClientTypes []string `yaml:"clientTypes" mapstructure:"clientTypes"` // 无人在线的 clientType
+			Users       bool     `yaml:"users" mapstructure:"users"`             // 不在线用户的消息
+		} `yaml:"subscriptions" mapstructure:"subscriptions"`
+	} `yaml:"webhook" mapstructure:"webhook"`
+
+	Schedule struct {
+		Enabled       bool   `yaml:"enabled" mapstructure:"enabled"`             // 允许发布时指定 deliverAt / delay
+		MaxPending    int    `yaml:"maxPending" mapstructure:"maxPending"`       // 等待投递的定时消息上限
+		MaxDelayHours int    `yaml:"maxDelayHours" mapstructure:"maxDelayHours"` // 投递时间距发布时的最长间隔
+		File          string `yaml:"file" mapstructure:"file"`                   // 定时消息快照文件，空表示不落盘
+	} `yaml:"schedule" mapstructure:"schedule"`
 
 	Grpc struct {
-		Enabled bool `yaml:"enabled"`
-	} `yaml:"grpc"`
+		Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	} `yaml:"grpc" mapstructure:"grpc"`
+
+	Admin struct {
+		Token string `yaml:"token" mapstructure:"token"` // /admin/* 接口的 Bearer token，空表示不校验
+	} `yaml:"admin" mapstructure:"admin"`
+
+	Log struct {
+		Level       string `yaml:"level" mapstructure:"level"`             // 日志级别：debug | info | warn | error
+		Format      string `yaml:"format" mapstructure:"format"`           // 输出格式：text | json
+		SampleEvery int    `yaml:"sampleEvery" mapstructure:"sampleEvery"` // 逐条投递日志每 N 条输出 1 条
+	} `yaml:"log" mapstructure:"log"`
+
+	Tenant struct {
+		Resolver    string `yaml:"resolver" mapstructure:"resolver"`       // 租户解析方式：header | host | path | token，空表示单租户
+		Header      string `yaml:"header" mapstructure:"header"`           // resolver=header 时读取的请求头
+		TokenClaim  string `yaml:"tokenClaim" mapstructure:"tokenClaim"`   // resolver=token 时读取的 claim
+		TokenSecret string `yaml:"tokenSecret" mapstructure:"tokenSecret"` // 校验 HS256 token 的密钥
+		Strict      bool   `yaml:"strict" mapstructure:"strict"`           // 只允许 tenants 中声明的租户
+		Quota       Quota  `yaml:"quota" mapstructure:"quota"`             // 默认配额
+
+		Tenants []struct {
+			Name  string `yaml:"name" mapstructure:"name"`   // 租户名
+			Quota Quota  `yaml:"quota" mapstructure:"quota"` // 租户配额
+		} `yaml:"tenants" mapstructure:"tenants"`
+		// 非严格模式下最多创建的未声明租户数，0 表示不限制
+		MaxUndeclared int `yaml:"maxUndeclared" mapstructure:"maxUndeclared"`
+	} `yaml:"tenant" mapstructure:"tenant"`
 }
 
-func loadConfig() *config {
-	vip := viper.New()
-
-	path, err := os.Getwd()
-	if err != nil {
-		return nil
-	}
-
-	vip.AddConfigPath(path + "/config")
-	vip.SetConfigName("config")
-	vip.SetConfigType("yaml")
+// Quota 租户配额，0 表示不限制
+type Quota struct {
+	MaxConns            int    `yaml:"maxConns" mapstructure:"maxConns"`                       // 最大连接数
+	MaxTopicsPerClient  int    `yaml:"maxTopicsPerClient" mapstructure:"maxTopicsPerClient"`   // 单个客户端最多订阅的主题数
+	PublishQps          int    `yaml:"publishQps" mapstructure:"publishQps"`                   // 发布限流 QPS
+	MaxConnsPerUser     int    `yaml:"maxConnsPerUser" mapstructure:"maxConnsPerUser"`         // 每个用户的最大连接数
+	MaxConnsPerUserType int    `yaml:"maxConnsPerUserType" mapstructure:"maxConnsPerUserType"` // 每个用户每种客户端类型的最大连接数
+	OnUserLimit         string `yaml:"onUserLimit" mapstructure:"onUserLimit"`                 // 超过用户上限时：reject 拒绝新连接 / evictOldest 断开最早的连接
+	SingleSession       bool   `yaml:"singleSession" mapstructure:"singleSession"`             // 每个用户每种客户端类型只保留最新的连接
+}
 
-	if err := vip.ReadInConfig(); err != nil {
-		panic(err)
-	}
-	var cfg config
-	err = vip.Unmarshal(&cfg)
-	if err != nil {
-		panic(err)
-	}
+// Lane 连接的一个优先级通道
+type Lane struct {
+	Size   int    `yaml:"size" mapstructure:"size"`     // 通道容量
+	Weight int    `yaml:"weight" mapstructure:"weight"` // 写出权重，每轮最多连续写出的条数
+	OnFull string `yaml:"onFull" mapstructure:"onFull"` // 通道满时的处理：drop | dropOldest | disconnect，空表示按 dropSlowClient
+}
 
-	return &cfg
+// ConflateRule 合并主题，慢客户端只收到每个键的最新值
+type ConflateRule struct {
+	Topic string `yaml:"topic" mapstructure:"topic"` // 主题规则，语法同订阅
+	Key   string `yaml:"key" mapstructure:"key"`     // 合并键所在的消息字段，空表示整个主题只保留最新一条
 }
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sse/pkg/topic"
+	"strings"
+)
+
+// ValidationError 汇总所有配置错误，一次性报告
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "配置校验失败:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// 错误收集器
+type problems []string
+
+func (p *problems) addf(key, format string, args ...any) {
+	*p = append(*p, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (p *problems) positive(key string, v int) {
+	if v <= 0 {
+		p.addf(key, "必须大于 0，当前为 %d", v)
+	}
+}
+
+func (p *problems) nonNegative(key string, v int) {
+	if v < 0 {
+		p.addf(key, "不能为负数，当前为 %d", v)
+	}
+}
+
+func (p *problems) oneOf(key, v string, allowed ...string) {
+	if !slices.Contains(allowed, v) {
+		p.addf(key, "必须是 %s 之一，当前为 %q", strings.Join(allowed, " | "), v)
+	}
+}
+
+// patterns 检查主题规则的语法，见 topic.ValidatePattern
+func (p *problems) patterns(key string, patterns []string) {
+	for i, pattern := range patterns {
+		if err := topic.ValidatePattern(pattern); err != nil {
+			p.addf(fmt.Sprintf("%s[%d]", key, i), "%v", err)
+		}
+	}
+}
+
+// Validate 校验配置，返回 *ValidationError 列出全部问题
+func (c *config) Validate() error {
+	var p problems
+
+	if c.Server.Addr <= 0 || c.Server.Addr > 65535 {
+		p.addf("server.addr", "端口必须在 1-65535 之间，当前为 %d", c.Server.Addr)
+	}
+	p.nonNegative("server.shutdownGraceSec", c.Server.ShutdownGraceSec)
+	p.nonNegative("server.shutdownTimeoutSec", c.Server.ShutdownTimeoutSec)
+
+	p.positive("sse.heartbeatSec", c.Sse.HeartbeatSec)
+	p.positive("sse.clientChanSize", c.Sse.ClientChanSize)
+	p.nonNegative("sse.writeTimeoutSec", c.Sse.WriteTimeoutSec)
+	p.positive("sse.pollTimeoutSec", c.Sse.PollTimeoutSec)
+	p.positive("sse.pollSessionTtlSec", c.Sse.PollSessionTtlSec)
+	p.positive("sse.pollBufferSize", c.Sse.PollBufferSize)
+
+	p.positive("hub.shards", c.Hub.Shards)
+	p.positive("hub.ack.timeoutSec", c.Hub.Ack.TimeoutSec)
+	p.positive("hub.ack.maxAttempts", c.Hub.Ack.MaxAttempts)
+	p.positive("hub.ack.window", c.Hub.Ack.Window)
+	p.positive("hub.ack.statusMax", c.Hub.Ack.StatusMax)
+	p.positive("hub.snapshotMax", c.Hub.SnapshotMax)
+	lanes := []struct {
+		name string
+		lane Lane
+	}{{"high", c.Hub.Lanes.High}, {"normal", c.Hub.Lanes.Normal}, {"low", c.Hub.Lanes.Low}}
+	for _, l := range lanes {
+		p.positive("hub.lanes."+l.name+".size", l.lane.Size)
+		p.positive("hub.lanes."+l.name+".weight", l.lane.Weight)
+		p.oneOf("hub.lanes."+l.name+".onFull", l.lane.OnFull, "", "drop", "dropOldest", "disconnect")
+	}
+	p.patterns("hub.presence.topics", c.Hub.Presence.Topics)
+	p.nonNegative("hub.presence.debounceMs", c.Hub.Presence.DebounceMs)
+	for i, r := range c.Hub.Conflate {
+		p.patterns(fmt.Sprintf("hub.conflate[%d].topic", i), []string{r.Topic})
+	}
+
+	if c.Redis.Streams.Enabled {
+		if c.Redis.Addr == "" {
+			p.addf("redis.addr", "启用 streams 时不能为空")
+		}
+		p.positive("redis.streams.maxlen", c.Redis.Streams.Maxlen)
+	}
+	p.positive("redis.pubsub.pump_workers", c.Redis.Pubsub.PumpWorkers)
+
+	if c.Persistence.Enabled {
+		p.oneOf("persistence.kind", c.Persistence.Kind, "memory", "mysql", "postgres")
+		if c.Persistence.Kind != "memory" && c.Persistence.Dsn == "" {
+			p.addf("persistence.dsn", "启用持久化时不能为空")
+		}
+		if c.Persistence.Batch.Enabled {
+			p.positive("persistence.batch.maxItems", c.Persistence.Batch.MaxItems)
+			p.positive("persistence.batch.maxBytes", c.Persistence.Batch.MaxBytes)
+			p.positive("persistence.batch.flushIntervalMs", c.Persistence.Batch.FlushIntervalMs)
+		}
+		p.patterns("persistence.topics.include", c.Persistence.Topics.Include)
+		p.patterns("persistence.topics.exclude", c.Persistence.Topics.Exclude)
+		p.nonNegative("persistence.retention.days", c.Persistence.Retention.Days)
+		p.positive("persistence.inbox.maxItems", c.Persistence.Inbox.MaxItems)
+		p.nonNegative("persistence.inbox.maxAgeHours", c.Persistence.Inbox.MaxAgeHours)
+	}
+
+	p.nonNegative("publish.defaultMaxlen", c.Publish.DefaultMaxlen)
+	p.nonNegative("publish.rate_limit_qps", c.Publish.RateLimitQps)
+	if c.Publish.Idempotency.Enabled {
+		p.positive("publish.idempotency.windowSec", c.Publish.Idempotency.WindowSec)
+		p.positive("publish.idempotency.maxKeys", c.Publish.Idempotency.MaxKeys)
+	}
+
+	if c.Webhook.Enabled {
+		p.positive("webhook.timeoutSec", c.Webhook.TimeoutSec)
+		p.positive("webhook.maxAttempts", c.Webhook.MaxAttempts)
+		p.positive("webhook.initialBackoffMs", c.Webhook.InitialBackoffMs)
+		p.positive("webhook.maxBackoffSec", c.Webhook.MaxBackoffSec)
+		p.positive("webhook.workers", c.Webhook.Workers)
+		p.positive("webhook.queueMax", c.Webhook.QueueMax)
+		p.positive("webhook.deadLetterMax", c.Webhook.DeadLetterMax)
+		if len(c.Webhook.Subscriptions) == 0 {
+			p.addf("webhook.subscriptions", "启用 webhook 时不能为空")
+		}
+		for i, s := range c.Webhook.Subscriptions {
+			key := fmt.Sprintf("webhook.subscriptions[%d]", i)
+			if u, err := url.Parse(s.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				p.addf(key+".url", "必须是 http(s) 地址，当前为 %q", s.Url)
+			}
+			p.patterns(key+".topics", s.Topics)
+			if len(s.Topics) == 0 && len(s.ClientTypes) == 0 && !s.Users {
+				p.addf(key, "topics、clientTypes、users 至少设置一项")
+			}
+		}
+	}
+
+	if c.Schedule.Enabled {
+		p.positive("schedule.maxPending", c.Schedule.MaxPending)
+		p.positive("schedule.maxDelayHours", c.Schedule.MaxDelayHours)
+	}
+
+	p.oneOf("log.level", strings.ToLower(c.Log.Level), "debug", "info", "warn", "error")
+	p.oneOf("log.format", strings.ToLower(c.Log.Format), "text", "json")
+	p.nonNegative("log.sampleEvery", c.Log.SampleEvery)
+
+	p.oneOf("tenant.resolver", c.Tenant.Resolver, "", "header", "host", "path", "token")
+	if c.Tenant.Resolver == "header" && c.Tenant.Header == "" {
+		p.addf("tenant.header", "resolver=header 时不能为空")
+	}
+	if c.Tenant.Resolver == "token" {
+		if c.Tenant.TokenSecret == "" {
+			p.addf("tenant.tokenSecret", "resolver=token 时不能为空")
+		}
+		if c.Tenant.TokenClaim == "" {
+			p.addf("tenant.tokenClaim", "resolver=token 时不能为空")
+		}
+	}
+	p.nonNegative("tenant.maxUndeclared", c.Tenant.MaxUndeclared)
+	p.quota("tenant.quota", c.Tenant.Quota)
+	seen := make(map[string]bool, len(c.Tenant.Tenants))
+	for i, t := range c.Tenant.Tenants {
+		key := fmt.Sprintf("tenant.tenants[%d]", i)
+		switch {
+		case t.Name == "":
+			p.addf(key+".name", "不能为空")
+		case seen[t.Name]:
+			p.addf(key+".name", "租户 %q 重复", t.Name)
+		}
+		seen[t.Name] = true
+		p.quota(key+".quota", t.Quota)
+	}
+
+	if len(p) > 0 {
+		return &ValidationError{Problems: p}
+	}
+	return nil
+}
+
+func (p *problems) quota(key string, q Quota) {
+	p.nonNegative(key+".maxConns", q.MaxConns)
+	p.nonNegative(key+".maxTopicsPerClient", q.MaxTopicsPerClient)
+	p.nonNegative(key+".publishQps", q.PublishQps)
+	p.nonNegative(key+".maxConnsPerUser", q.MaxConnsPerUser)
+	p.nonNegative(key+".maxConnsPerUserType", q.MaxConnsPerUserType)
+	p.oneOf(key+".onUserLimit", q.OnUserLimit, "", "reject", "evictOldest")
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 当前生效的配置，热更新后替换
+var current atomic.Pointer[config]
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   []func()
+)
+
+// Current 返回当前生效的配置（含热更新），只读
+func Current() *config {
+	return current.Load()
+}
+
+// Subscribe 注册热更新回调，配置生效后按注册顺序调用，回调中通过 Current 读取新值
+func Subscribe(fn func()) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Watch 监听配置文件变化；可热更新的键立即生效，其余键的变化只告警，需重启生效
+func Watch(path string, log *slog.Logger) error {
+	path = resolvePath(path)
+
+	vip := newViper()
+	vip.SetConfigFile(path)
+	if err := vip.ReadInConfig(); err != nil {
+		return err
+	}
+
+	vip.OnConfigChange(func(e fsnotify.Event) {
+		next, err := decode(vip)
+		if err == nil {
+			err = next.Validate()
+		}
+		if err != nil {
+			log.Error("配置热更新失败，继续使用当前配置", "file", path, "err", err)
+			return
+		}
+
+		old := Current()
+		merged := old.withReloadable(next)
+		if ignored := diffKeys("", reflect.ValueOf(*merged), reflect.ValueOf(*next)); len(ignored) > 0 {
+			log.Warn("以下配置不支持热更新，重启后生效", "keys", ignored)
+		}
+		changed := diffKeys("", reflect.ValueOf(*old), reflect.ValueOf(*merged))
+		if len(changed) == 0 {
+			return
+		}
+
+		current.Store(merged)
+		log.Info("配置已热更新", "keys", changed)
+
+		subscribersMu.Lock()
+		fns := append([]func(){}, subscribers...)
+		subscribersMu.Unlock()
+		for _, fn := range fns {
+			fn()
+		}
+	})
+	vip.WatchConfig()
+	return nil
+}
+
+// withReloadable 复制当前配置，只替换可热更新的键
+func (c *config) withReloadable(next *config) *config {
+	cp := *c
+	cp.Sse.HeartbeatSec = next.Sse.HeartbeatSec
+	cp.Publish.RateLimitQps = next.Publish.RateLimitQps
+	cp.Hub.DropSlowClient = next.Hub.DropSlowClient
+	cp.Hub.Conflate = next.Hub.Conflate
+	cp.Hub.Presence.Topics = next.Hub.Presence.Topics
+	cp.Persistence.Topics = next.Persistence.Topics
+	cp.Log.Level = next.Log.Level
+	return &cp
+}
+
+// diffKeys 按 yaml 键路径列出两个配置中不同的字段
+func diffKeys(prefix string, a, b reflect.Value) []string {
+	if a.Kind() != reflect.Struct {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{prefix}
+	}
+
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		key := a.Type().Field(i).Tag.Get("yaml")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		keys = append(keys, diffKeys(key, a.Field(i), b.Field(i))...)
+	}
+	return keys
+}
@@ -0,0 +1,207 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalid 表达式不合法
+var ErrInvalid = errors.New("过滤表达式不合法")
+
+// MaxLen 表达式的最大长度（字节）
+const MaxLen = 1024
+
+func errorf(pos int, format string, args ...any) error {
+	return fmt.Errorf("%w: %s（第 %d 个字符）", ErrInvalid, fmt.Sprintf(format, args...), pos+1)
+}
+
+// Expr 订阅的内容过滤条件，按 JSON 消息的字段筛选事件，如 region == "eu" && amount > 100。
+// 只能读取字段并做比较与逻辑运算，语法见 parse.go；编译后不可变，可并发使用
+type Expr struct {
+	src  string
+	root node
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Match 对解码后的 JSON 消息求值；字段不存在时视为 null
+func (e *Expr) Match(doc any) bool {
+	return truthy(e.root.eval(doc))
+}
+
+// 编译结果按原文缓存，相同的过滤条件在各连接间共用
+var cache = struct {
+	sync.RWMutex
+	m map[string]*Expr
+}{m: make(map[string]*Expr)}
+
+// 缓存上限，超过后新的表达式照常编译但不再缓存
+const cacheMax = 4096
+
+// Compile 编译表达式，语法或类型错误返回 ErrInvalid
+func Compile(src string) (*Expr, error) {
+	cache.RLock()
+	e, ok := cache.m[src]
+	cache.RUnlock()
+	if ok {
+		return e, nil
+	}
+
+	if len(src) > MaxLen {
+		return nil, fmt.Errorf("%w: 超过 %d 字节", ErrInvalid, MaxLen)
+	}
+	root, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	e = &Expr{src: src, root: root}
+
+	cache.Lock()
+	if len(cache.m) < cacheMax {
+		cache.m[src] = e
+	}
+	cache.Unlock()
+	return e, nil
+}
+
+type node interface {
+	eval(doc any) any
+}
+
+type fieldNode struct {
+	path []string
+}
+
+func (n *fieldNode) eval(doc any) any {
+	v := doc
+	for _, key := range n.path {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+type literalNode struct {
+	v any
+}
+
+func (n *literalNode) eval(any) any {
+	return n.v
+}
+
+type notNode struct {
+	x node
+}
+
+func (n *notNode) eval(doc any) any {
+	return !truthy(n.x.eval(doc))
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(doc any) any {
+	return truthy(n.left.eval(doc)) && truthy(n.right.eval(doc))
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(doc any) any {
+	return truthy(n.left.eval(doc)) || truthy(n.right.eval(doc))
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+// eval 类型不同时只有 != 成立；大小比较只对两个数字或两个字符串成立
+func (n *compareNode) eval(doc any) any {
+	l, r := n.left.eval(doc), n.right.eval(doc)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	}
+
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false
+		}
+		c = cmp(lv, rv)
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false
+		}
+		c = cmp(lv, rv)
+	default:
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func cmp[T float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// equal 只比较标量，对象与数组之间总是不相等
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case float64:
+		bv, ok := b.(float64)
+		return ok && av == bv
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	}
+	return false
+}
+
+// truthy null、false、0 与空字符串为假，其余为真
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	}
+	return true
+}
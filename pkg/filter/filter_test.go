@@ -0,0 +1,292 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	toks, err := tokenize(`a.b_1 == "x\"y" && n >= -1.5 || !(ok != 'z') && v == null && t == true && f == false`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind tokenKind
+		text string
+	}{
+		{tokField, "a.b_1"}, {tokOp, "=="}, {tokString, `x"y`}, {tokOp, "&&"},
+		{tokField, "n"}, {tokOp, ">="}, {tokNumber, "-1.5"}, {tokOp, "||"},
+		{tokOp, "!"}, {tokOp, "("}, {tokField, "ok"}, {tokOp, "!="}, {tokString, "z"}, {tokOp, ")"}, {tokOp, "&&"},
+		{tokField, "v"}, {tokOp, "=="}, {tokNull, "null"}, {tokOp, "&&"},
+		{tokField, "t"}, {tokOp, "=="}, {tokTrue, "true"}, {tokOp, "&&"},
+		{tokField, "f"}, {tokOp, "=="}, {tokFalse, "false"},
+		{tokEOF, ""},
+	}
+	if len(toks) != len(want) {
+		t.Fatalf("得到 %d 个 token: %+v", len(toks), toks)
+	}
+	for i, w := range want {
+		if toks[i].kind != w.kind || toks[i].text != w.text {
+			t.Errorf("第 %d 个 token 为 %+v，期望 %+v", i, toks[i], w)
+		}
+	}
+	if toks[6].num != -1.5 {
+		t.Errorf("数字为 %v", toks[6].num)
+	}
+	// 位置从 0 开始，错误信息中从 1 开始
+	if toks[1].pos != 6 {
+		t.Errorf("== 的位置为 %d", toks[1].pos)
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct{ src, msg string }{
+		{`a == "x`, "缺少结尾的引号"},
+		{`a == 'x`, "缺少结尾的引号"},
+		{`a == 1.2.3`, "数字"},
+		{`a == -`, "数字"},
+		{`a = 1`, "无法识别"},
+		{`a == 1 & b`, "无法识别"},
+		{`a | b`, "无法识别"},
+		{`a == @`, "无法识别"},
+	}
+	for _, tt := range tests {
+		_, err := tokenize(tt.src)
+		if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("tokenize(%q) = %v，期望包含 %q", tt.src, err, tt.msg)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tests := []struct{ src, msg string }{
+		{"", "不完整"},
+		{"a ==", "不完整"},
+		{"a && ", "不完整"},
+		{"(a", "缺少 )"},
+		{"a)", "多余"},
+		{"a b", "多余"},
+		{`"x"`, "必须引用字段"},
+		{"1", "必须引用字段"},
+		{"!true", "必须引用字段"},
+		{"1 == 1", "不能都是常量"},
+		{`"a" != "b"`, "不能都是常量"},
+		{"a > true", "只能比较数字或字符串"},
+		{"a <= null", "只能比较数字或字符串"},
+		{"false < a", "只能比较数字或字符串"},
+		{"a == ==", "需要字段或常量"},
+		{"a == )", "需要字段或常量"},
+		{"&& a", "需要字段或常量"},
+		{"a == b == c", "多余"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src)
+		if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("Compile(%q) = %v，期望包含 %q", tt.src, err, tt.msg)
+		}
+	}
+}
+
+func TestCompileErrorPosition(t *testing.T) {
+	_, err := Compile("a == 1 && b = 2")
+	if err == nil || !strings.Contains(err.Error(), "第 13 个字符") {
+		t.Fatalf("错误 %v", err)
+	}
+}
+
+func TestCompileDepth(t *testing.T) {
+	nest := func(n int, open, close string) string {
+		return strings.Repeat(open, n) + "a" + strings.Repeat(close, n)
+	}
+	// 最外层的 unary 算一层，最多再嵌套 31 层
+	for _, tt := range []struct {
+		src string
+		ok  bool
+	}{
+		{nest(maxDepth-1, "(", ")"), true},
+		{nest(maxDepth, "(", ")"), false},
+		{nest(maxDepth-1, "!", ""), true},
+		{nest(maxDepth, "!", ""), false},
+		{nest(10000, "(", ")"), false},
+	} {
+		_, err := Compile(tt.src)
+		if tt.ok && err != nil {
+			t.Errorf("%d 字节的嵌套表达式: %v", len(tt.src), err)
+		}
+		if !tt.ok && (!errors.Is(err, ErrInvalid) || (len(tt.src) <= MaxLen && !strings.Contains(err.Error(), "嵌套超过 32 层"))) {
+			t.Errorf("%d 字节的嵌套表达式返回 %v", len(tt.src), err)
+		}
+	}
+}
+
+func TestCompileMaxLen(t *testing.T) {
+	prefix := `a == "`
+	exact := prefix + strings.Repeat("x", MaxLen-len(prefix)-1) + `"`
+	if len(exact) != MaxLen {
+		t.Fatalf("构造的长度 %d", len(exact))
+	}
+	if _, err := Compile(exact); err != nil {
+		t.Fatalf("%d 字节: %v", MaxLen, err)
+	}
+	long := prefix + strings.Repeat("x", MaxLen-len(prefix)) + `"`
+	if _, err := Compile(long); !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "超过 1024 字节") {
+		t.Fatalf("%d 字节返回 %v", len(long), err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	doc := `{"region":"eu","amount":150,"zero":0,"empty":"","flag":true,"off":false,"nil":null,
+		"customer":{"tier":"gold","age":30,"address":{"city":"Berlin"}},"tags":["a"],"n":"5"}`
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`region == "eu"`, true},
+		{`region == 'eu'`, true},
+		{`region != "eu"`, false},
+		{`"eu" == region`, true},
+		{`amount > 100`, true},
+		{`amount >= 150`, true},
+		{`amount < 150`, false},
+		{`amount <= 150.0`, true},
+		{`100 < amount`, true},
+		{`amount > -1`, true},
+		{`region == "eu" && amount > 100`, true},
+		{`region == "us" || amount > 100`, true},
+		{`region == "us" || amount > 200`, false},
+		{`!(region == "us")`, true},
+		{`!!flag`, true},
+		{`region == "us" || region == "eu" && amount > 200`, false},
+		{`(region == "us" || region == "eu") && amount > 100`, true},
+		{`region > "ab"`, true},
+		{`region < "ab"`, false},
+		// 嵌套字段
+		{`customer.tier == "gold"`, true},
+		{`customer.address.city == "Berlin"`, true},
+		{`customer.age >= 18 && customer.tier != "basic"`, true},
+		// 单独的字段按真值判断
+		{`flag`, true},
+		{`off`, false},
+		{`zero`, false},
+		{`empty`, false},
+		{`nil`, false},
+		{`region`, true},
+		{`customer`, true},
+		{`tags`, true},
+		{`!missing`, true},
+		// 缺失的字段视为 null
+		{`missing == null`, true},
+		{`missing != null`, false},
+		{`missing`, false},
+		{`missing == "eu"`, false},
+		{`missing != "eu"`, true},
+		{`missing > 0`, false},
+		{`missing < 0`, false},
+		{`customer.missing.deeper == null`, true},
+		{`region.sub == null`, true},
+		{`nil == null`, true},
+		// 类型不同时只有 != 成立，大小比较不成立
+		{`n == 5`, false},
+		{`n != 5`, true},
+		{`n > 1`, false},
+		{`n <= 1`, false},
+		{`amount == "150"`, false},
+		{`amount > "1"`, false},
+		{`flag == 1`, false},
+		{`flag == true`, true},
+		{`off == false`, true},
+		{`zero == false`, false},
+		{`empty == null`, false},
+		// 对象与数组之间不相等
+		{`customer == customer`, false},
+		{`tags == tags`, false},
+		{`customer != customer`, true},
+		{`amount == amount`, true},
+	}
+	var v any
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := e.Match(v); got != tt.want {
+			t.Errorf("%s = %v，期望 %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+// 消息不是 JSON 对象时字段都视为 null
+func TestMatchNonObject(t *testing.T) {
+	e, err := Compile(`a == null && !a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range []any{nil, "text", 1.0, []any{1.0}, true} {
+		if !e.Match(doc) {
+			t.Errorf("对 %v 求值应为 true", doc)
+		}
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	a, err := Compile(`cache_test == 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Compile(`cache_test == 1`)
+	if a != b {
+		t.Fatal("相同的表达式未共用编译结果")
+	}
+	if a.String() != `cache_test == 1` {
+		t.Fatalf("String() = %q", a.String())
+	}
+	// 错误的表达式不缓存
+	Compile(`cache_test ==`)
+	cache.RLock()
+	_, cached := cache.m[`cache_test ==`]
+	cache.RUnlock()
+	if cached {
+		t.Fatal("缓存了错误的表达式")
+	}
+
+	// 填满缓存后新的表达式照常编译但不再缓存
+	for i := 0; ; i++ {
+		cache.RLock()
+		full := len(cache.m) >= cacheMax
+		cache.RUnlock()
+		if full {
+			break
+		}
+		if _, err := Compile(fmt.Sprintf("fill == %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	x, err := Compile(`after_full == 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, _ := Compile(`after_full == 1`)
+	if x == y {
+		t.Fatal("缓存已满时仍缓存了新的表达式")
+	}
+	if !x.Match(map[string]any{"after_full": 1.0}) {
+		t.Fatal("未缓存的表达式求值错误")
+	}
+	cache.RLock()
+	n := len(cache.m)
+	cache.RUnlock()
+	if n != cacheMax {
+		t.Fatalf("缓存了 %d 条", n)
+	}
+	// 已缓存的仍然命中
+	if c, _ := Compile(`cache_test == 1`); c != a {
+		t.Fatal("缓存已满后原有条目失效")
+	}
+}
@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokField
+	tokString
+	tokNumber
+	tokTrue
+	tokFalse
+	tokNull
+	tokOp // == != < <= > >= && || ! ( )
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// 起始位置（字节偏移），用于错误信息
+	pos int
+	num float64
+}
+
+// lexer 把表达式切分为 token，一次性完成，出错时返回第一个错误
+type lexer struct {
+	src string
+	pos int
+}
+
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src}
+	var out []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+		if t.kind == tokEOF {
+			return out, nil
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t') {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		return l.string(c)
+	case c == '-' || isDigit(c):
+		return l.number()
+	case isIdentStart(c):
+		return l.field()
+	}
+
+	// 运算符，先尝试两个字符
+	for _, op := range [...]string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, errorf(start, "无法识别的字符 %q", c)
+}
+
+func (l *lexer) string(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{kind: tokString, text: b.String(), pos: start}, nil
+		case c == '\\' && l.pos+1 < len(l.src):
+			// 只支持转义引号与反斜杠本身
+			b.WriteByte(l.src[l.pos+1])
+			l.pos += 2
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, errorf(start, "字符串缺少结尾的引号")
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+		l.pos++
+	}
+	text := l.src[start:l.pos]
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, errorf(start, "数字 %q 不合法", text)
+	}
+	return token{kind: tokNumber, text: text, pos: start, num: n}, nil
+}
+
+// field 字段路径，按 "." 访问嵌套对象，如 customer.region
+func (l *lexer) field() (token, error) {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isIdentStart(c) || isDigit(c) {
+			l.pos++
+			continue
+		}
+		if c == '.' && l.pos+1 < len(l.src) && isIdentStart(l.src[l.pos+1]) {
+			l.pos++
+			continue
+		}
+		break
+	}
+	text := l.src[start:l.pos]
+	switch text {
+	case "true":
+		return token{kind: tokTrue, text: text, pos: start}, nil
+	case "false":
+		return token{kind: tokFalse, text: text, pos: start}, nil
+	case "null":
+		return token{kind: tokNull, text: text, pos: start}, nil
+	}
+	return token{kind: tokField, text: text, pos: start}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,182 @@
+package filter
+
+import "strings"
+
+// 语法：
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | "(" expr ")" | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand = 字段路径 | 字符串 | 数字 | true | false | null
+type parser struct {
+	toks  []token
+	i     int
+	depth int
+}
+
+// 嵌套深度上限，防止恶意输入耗尽栈
+const maxDepth = 32
+
+func parse(src string) (node, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "多余的 %q", t.text)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.i++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.i++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	t := p.peek()
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, errorf(t.pos, "嵌套超过 %d 层", maxDepth)
+	}
+
+	switch {
+	case p.isOp("!"):
+		p.i++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	case p.isOp("("):
+		p.i++
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, errorf(p.peek().pos, "缺少 )")
+		}
+		p.i++
+		return x, nil
+	}
+	return p.compare()
+}
+
+func (p *parser) compare() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp || !isCompareOp(t.text) {
+		// 单独的字段按真值判断
+		if _, ok := left.(*fieldNode); !ok {
+			return nil, errorf(t.pos, "条件必须引用字段")
+		}
+		return left, nil
+	}
+	p.i++
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return newCompare(t, left, right)
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokField:
+		p.i++
+		return &fieldNode{path: strings.Split(t.text, ".")}, nil
+	case tokString:
+		p.i++
+		return &literalNode{v: t.text}, nil
+	case tokNumber:
+		p.i++
+		return &literalNode{v: t.num}, nil
+	case tokTrue, tokFalse:
+		p.i++
+		return &literalNode{v: t.kind == tokTrue}, nil
+	case tokNull:
+		p.i++
+		return &literalNode{v: nil}, nil
+	case tokEOF:
+		return nil, errorf(t.pos, "表达式不完整")
+	}
+	return nil, errorf(t.pos, "此处需要字段或常量，得到 %q", t.text)
+}
+
+func isCompareOp(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// newCompare 编译期检查比较的两侧：至少一侧是字段，大小比较的常量只能是数字或字符串
+func newCompare(op token, left, right node) (node, error) {
+	ll, lok := left.(*literalNode)
+	rl, rok := right.(*literalNode)
+	if lok && rok {
+		return nil, errorf(op.pos, "比较的两侧不能都是常量")
+	}
+	if op.text != "==" && op.text != "!=" {
+		for _, l := range [...]*literalNode{ll, rl} {
+			if l == nil {
+				continue
+			}
+			switch l.v.(type) {
+			case float64, string:
+			default:
+				return nil, errorf(op.pos, "%s 只能比较数字或字符串", op.text)
+			}
+		}
+	}
+	return &compareNode{op: op.text, left: left, right: right}, nil
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotStarted 组件已登记但尚未就绪
+var ErrNotStarted = errors.New("未启动")
+
+// Health 汇总各依赖组件的状态，用于存活/就绪探针
+type Health struct {
+	draining atomic.Bool
+
+	mu sync.RWMutex
+	// 组件状态，nil 表示正常（如监听器已启动）
+	states map[string]error
+	// 主动检查函数（如持久化、通知适配器的连接检查）
+	checks map[string]func() error
+}
+
+// New 创建健康状态
+func New() *Health {
+	return &Health{
+		states: make(map[string]error),
+		checks: make(map[string]func() error),
+	}
+}
+
+// Expect 登记一个需要就绪的组件，初始状态为未启动
+func (h *Health) Expect(name string) {
+	h.Set(name, ErrNotStarted)
+}
+
+// Set 更新组件状态，err 为 nil 表示正常
+func (h *Health) Set(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.states[name] = err
+}
+
+// Register 注册主动检查函数，每次就绪探针时调用
+func (h *Health) Register(name string, check func() error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Drain 进入排空状态，就绪探针随即失败
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Draining 是否处于排空状态
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Status 就绪探针的结果
+type Status struct {
+	Ready      bool              `json:"ready"`
+	Draining   bool              `json:"draining"`
+	Components map[string]string `json:"components"`
+}
+
+// Ready 检查所有组件，返回是否就绪及各组件状态
+func (h *Health) Ready() Status {
+	h.mu.RLock()
+	states := make(map[string]error, len(h.states)+len(h.checks))
+	for name, err := range h.states {
+		states[name] = err
+	}
+	checks := make(map[string]func() error, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+
+	// 检查函数可能有网络调用，不持锁执行
+	for name, check := range checks {
+		states[name] = check()
+	}
+
+	status := Status{
+		Ready:      !h.Draining(),
+		Draining:   h.Draining(),
+		Components: make(map[string]string, len(states)),
+	}
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := states[name]; err != nil {
+			status.Ready = false
+			status.Components[name] = err.Error()
+		} else {
+			status.Components[name] = "ok"
+		}
+	}
+	return status
+}
+
+// LiveHandler /healthz：进程存活即返回 200，不做任何依赖检查
+func (h *Health) LiveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+// ReadyHandler /readyz：所有组件就绪且未在排空时返回 200，否则 503
+func (h *Health) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := h.Ready()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	}
+}
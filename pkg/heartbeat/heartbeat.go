@@ -1,22 +1,46 @@
 package heartbeat
 
 import (
+	"bytes"
 	"sse/internal/ports"
+	"sync/atomic"
 	"time"
 )
 
+// Payload 写入客户端通道的心跳数据，非 SSE 传输据此识别并改用各自的保活方式
+var Payload = []byte("event: ping")
+
+// IsPayload 判断通道中的消息是否为心跳
+func IsPayload(msg []byte) bool {
+	return bytes.Equal(msg, Payload)
+}
+
 // Heartbeat 控制心跳消息的发送
 type Heartbeat struct {
-	ticker *time.Ticker
-	hub    ports.Hub
+	ticker   *time.Ticker
+	tenants  ports.Tenants
+	interval atomic.Int64
 }
 
 // NewHeartbeat 创建一个新的 Heartbeat 实例
-func NewHeartbeat(interval int, hub ports.Hub) *Heartbeat {
-	return &Heartbeat{
-		ticker: time.NewTicker(time.Duration(interval) * time.Second),
-		hub:    hub,
+func NewHeartbeat(interval int, tenants ports.Tenants) *Heartbeat {
+	h := &Heartbeat{
+		ticker:  time.NewTicker(time.Duration(interval) * time.Second),
+		tenants: tenants,
 	}
+	h.interval.Store(int64(time.Duration(interval) * time.Second))
+	return h
+}
+
+// SetInterval 调整心跳间隔，下一次心跳按新间隔计时
+func (h *Heartbeat) SetInterval(interval int) {
+	h.interval.Store(int64(time.Duration(interval) * time.Second))
+	h.ticker.Reset(time.Duration(interval) * time.Second)
+}
+
+// Interval 当前心跳间隔
+func (h *Heartbeat) Interval() time.Duration {
+	return time.Duration(h.interval.Load())
 }
 
 func (h *Heartbeat) Start() {
@@ -24,8 +48,9 @@ func (h *Heartbeat) Start() {
 		for {
 			select {
 			case <-h.ticker.C:
-				bytes := []byte("event: ping")
-				h.hub.HeaderBeat(bytes)
+				h.tenants.Each(func(_ string, hub ports.Hub) {
+					hub.HeaderBeat(Payload)
+				})
 
 			}
 		}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Level 全局日志级别，可在运行时修改
+var Level = new(slog.LevelVar)
+
+// New 创建结构化日志，format 为 json 或 text，level 为 debug/info/warn/error
+func New(w io.Writer, level, format string) *slog.Logger {
+	Level.Set(ParseLevel(level))
+
+	opts := &slog.HandlerOptions{Level: Level}
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ParseLevel 解析日志级别，无法识别时返回 info
+func ParseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// Sampled 返回只输出每 n 条中第一条的日志，用于逐条投递这类高频日志；n <= 1 时不采样
+func Sampled(l *slog.Logger, n int) *slog.Logger {
+	if n <= 1 {
+		return l
+	}
+	return slog.New(&samplingHandler{next: l.Handler(), n: uint64(n), counter: new(atomic.Uint64)})
+}
+
+// samplingHandler 按计数采样的 slog.Handler
+type samplingHandler struct {
+	next    slog.Handler
+	n       uint64
+	counter *atomic.Uint64
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if (h.counter.Add(1)-1)%h.n != 0 {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), n: h.n, counter: h.counter}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), n: h.n, counter: h.counter}
+}
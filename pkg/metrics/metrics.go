@@ -0,0 +1,303 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// collector 可以按 Prometheus 文本格式输出的指标
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+}
+
+// 默认注册表，NewCounterVec 等构造函数注册到这里
+var defaultRegistry = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText 以 Prometheus 文本格式（0.0.4）输出所有指标
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.RLock()
+	cs := make([]collector, len(r.collectors))
+	copy(cs, r.collectors)
+	r.mu.RUnlock()
+
+	sort.Slice(cs, func(i, j int) bool { return cs[i].name() < cs[j].name() })
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// Handler 返回 /metrics 的 HTTP 处理函数
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		defaultRegistry.WriteText(w)
+	}
+}
+
+// 带标签的指标族公共部分
+type family struct {
+	fqName string
+	help   string
+	labels []string
+
+	mu     sync.RWMutex
+	series map[string]any // 标签值组合 -> 指标值
+	keys   map[string][]string
+}
+
+func newFamily(name, help string, labels []string) family {
+	return family{
+		fqName: name,
+		help:   help,
+		labels: labels,
+		series: make(map[string]any),
+		keys:   make(map[string][]string),
+	}
+}
+
+func (f *family) name() string { return f.fqName }
+
+// get 获取或创建标签值组合对应的指标值
+func (f *family) get(values []string, create func() any) any {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s 需要 %d 个标签值，实际 %d 个", f.fqName, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	v, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if v, ok := f.series[key]; ok {
+		return v
+	}
+	v = create()
+	f.series[key] = v
+	f.keys[key] = append([]string(nil), values...)
+	return v
+}
+
+// each 按标签值排序遍历
+func (f *family) each(fn func(values []string, v any)) {
+	f.mu.RLock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	f.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.mu.RLock()
+		v, values := f.series[k], f.keys[k]
+		f.mu.RUnlock()
+		fn(values, v)
+	}
+}
+
+func (f *family) header(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.fqName, escapeHelp(f.help), f.fqName, typ)
+}
+
+// labelString 拼接 {a="x",b="y"}，extra 用于直方图的 le 标签
+func (f *family) labelString(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range f.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l + `="` + escapeLabel(values[i]) + `"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extra[i] + `="` + escapeLabel(extra[i+1]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Counter 单调递增计数器
+type Counter struct{ bits uint64 }
+
+// Inc 加一
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add 增加 v，v 不能为负
+func (c *Counter) Add(v float64) { addFloat(&c.bits, v) }
+
+// CounterVec 带标签的计数器
+type CounterVec struct{ family }
+
+// NewCounterVec 创建并注册计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newFamily(name, help, labels)}
+	defaultRegistry.register(c)
+	return c
+}
+
+// With 按标签值获取计数器
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.get(values, func() any { return &Counter{} }).(*Counter)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.header(w, "counter")
+	c.each(func(values []string, v any) {
+		fmt.Fprintf(w, "%s%s %s\n", c.fqName, c.labelString(values), formatFloat(loadFloat(&v.(*Counter).bits)))
+	})
+}
+
+// Gauge 可增可减的瞬时值
+type Gauge struct{ bits uint64 }
+
+// Set 设置为 v
+func (g *Gauge) Set(v float64) { atomic.StoreUint64(&g.bits, math.Float64bits(v)) }
+
+// Inc 加一
+func (g *Gauge) Inc() { addFloat(&g.bits, 1) }
+
+// Dec 减一
+func (g *Gauge) Dec() { addFloat(&g.bits, -1) }
+
+// Add 增加 v
+func (g *Gauge) Add(v float64) { addFloat(&g.bits, v) }
+
+// GaugeVec 带标签的瞬时值
+type GaugeVec struct{ family }
+
+// NewGaugeVec 创建并注册瞬时值
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newFamily(name, help, labels)}
+	defaultRegistry.register(g)
+	return g
+}
+
+// With 按标签值获取瞬时值
+func (g *GaugeVec) With(values ...string) *Gauge {
+	return g.get(values, func() any { return &Gauge{} }).(*Gauge)
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.header(w, "gauge")
+	g.each(func(values []string, v any) {
+		fmt.Fprintf(w, "%s%s %s\n", g.fqName, g.labelString(values), formatFloat(loadFloat(&v.(*Gauge).bits)))
+	})
+}
+
+// Histogram 累积分桶直方图
+type Histogram struct {
+	upper  []float64
+	counts []uint64 // 每个桶（非累积）的计数，最后一个为 +Inf
+	sum    uint64
+	count  uint64
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	addFloat(&h.sum, v)
+	atomic.AddUint64(&h.count, 1)
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	family
+	buckets []float64
+}
+
+// NewHistogramVec 创建并注册直方图，buckets 为升序的桶上界
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{family: newFamily(name, help, labels), buckets: buckets}
+	defaultRegistry.register(h)
+	return h
+}
+
+// With 按标签值获取直方图
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.get(values, func() any {
+		return &Histogram{upper: h.buckets, counts: make([]uint64, len(h.buckets)+1)}
+	}).(*Histogram)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.each(func(values []string, v any) {
+		hist := v.(*Histogram)
+		var cumulative uint64
+		for i, upper := range hist.upper {
+			cumulative += atomic.LoadUint64(&hist.counts[i])
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.fqName, h.labelString(values, "le", formatFloat(upper)), cumulative)
+		}
+		count := atomic.LoadUint64(&hist.count)
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.fqName, h.labelString(values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.fqName, h.labelString(values), formatFloat(loadFloat(&hist.sum)))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.fqName, h.labelString(values), count)
+	})
+}
+
+// DefBuckets 默认的时延桶（秒）
+var DefBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func addFloat(bits *uint64, v float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		if atomic.CompareAndSwapUint64(bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func loadFloat(bits *uint64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(bits))
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
@@ -0,0 +1,143 @@
+package metrics
+
+// 投递目标类型，对应 Hub 的各个发布方法
+const (
+	TargetTopic      = "topic"
+	TargetUser       = "user"
+	TargetClientType = "client_type"
+	TargetClient     = "client"
+	TargetHeartbeat  = "heartbeat"
+	TargetSnapshot   = "snapshot"
+)
+
+// 断开原因
+const (
+	ReasonClientGone  = "client_gone"  // 客户端主动断开
+	ReasonWriteError  = "write_error"  // 写出失败
+	ReasonServerClose = "server_close" // 服务端关闭连接
+	ReasonExpired     = "expired"      // 长轮询会话超时未续期
+)
+
+// 丢弃原因
+const (
+	DropQueueFull = "queue_full"  // 客户端写通道已满
+	DropClosed    = "closed"      // 客户端已关闭
+	DropPollBuf   = "poll_buffer" // 长轮询会话缓存已满
+	DropAckWindow = "ack_window"  // 确认模式下未确认的事件已达上限
+)
+
+// Hub 与 SSE 传输层
+var (
+	ActiveConnections = NewGaugeVec("sse_active_connections",
+		"当前活跃连接数", "client_type")
+	Connects = NewCounterVec("sse_connects_total",
+		"建立的连接总数", "client_type")
+	ConnectRejects = NewCounterVec("sse_connect_rejects_total",
+		"因配额或参数错误被拒绝的连接数", "reason")
+	Disconnects = NewCounterVec("sse_disconnects_total",
+		"断开的连接总数", "reason")
+
+	MessagesPublished = NewCounterVec("sse_messages_published_total",
+		"发布调用次数", "target")
+	MessagesDelivered = NewCounterVec("sse_messages_delivered_total",
+		"成功写入客户端通道的消息数", "target")
+	MessagesDropped = NewCounterVec("sse_messages_dropped_total",
+		"被丢弃的消息数", "reason")
+	MessagesRedelivered = NewCounterVec("sse_messages_redelivered_total",
+		"确认模式下超时或重连后重发的消息数")
+	MessagesConflated = NewCounterVec("sse_messages_conflated_total",
+		"合并主题中被同一键的新值替换、未写出的消息数")
+	LaneDropped = NewCounterVec("sse_lane_dropped_total",
+		"优先级通道已满时丢弃的消息数（dropOldest 丢弃的是最旧的一条）", "priority")
+
+	QueueDepth = NewHistogramVec("sse_client_queue_depth",
+		"心跳时采样的客户端通道积压长度",
+		[]float64{0, 1, 4, 16, 64, 128, 192, 255})
+	WriteLatency = NewHistogramVec("sse_write_duration_seconds",
+		"单条事件写出并 Flush 的耗时", DefBuckets)
+	HeartbeatFailures = NewCounterVec("sse_heartbeat_failures_total",
+		"心跳写入失败次数")
+	SlowClientEvictions = NewCounterVec("sse_slow_client_evictions_total",
+		"因通道已满被断开的慢客户端数")
+	ConnectionsReplaced = NewCounterVec("sse_connections_replaced_total",
+		"被同一用户的新连接替换而断开的连接数", "reason")
+)
+
+// 连接被替换的原因
+const (
+	ReplacedSingleSession = "single_session"  // 单会话模式下同一客户端类型的新连接
+	ReplacedUserTypeLimit = "user_type_limit" // 用户的该客户端类型连接数达到上限
+	ReplacedUserLimit     = "user_limit"      // 用户连接数达到上限
+)
+
+// HTTP 与 gRPC 接口
+var (
+	HTTPRequests = NewCounterVec("sse_http_requests_total",
+		"HTTP 请求数", "path", "code")
+	HTTPDuration = NewHistogramVec("sse_http_request_duration_seconds",
+		"HTTP 请求耗时（/sse 为连接持续时间）", DefBuckets, "path")
+	GRPCRequests = NewCounterVec("sse_grpc_requests_total",
+		"gRPC 请求数", "method", "code")
+	GRPCDuration = NewHistogramVec("sse_grpc_request_duration_seconds",
+		"gRPC 请求耗时", DefBuckets, "method")
+)
+
+// webhook 回调结果
+const (
+	WebhookDelivered = "delivered" // 接收方返回 2xx
+	WebhookRetry     = "retry"     // 失败后等待重试
+	WebhookDead      = "dead"      // 放弃并转入死信
+	WebhookDropped   = "dropped"   // 队列已满直接转入死信
+)
+
+// 离线消息的 webhook 回调
+var (
+	WebhookDeliveries = NewCounterVec("sse_webhook_deliveries_total",
+		"webhook 回调尝试次数", "result")
+	WebhookQueue = NewGaugeVec("sse_webhook_queue",
+		"webhook 队列长度", "state")
+)
+
+// 在线状态
+var (
+	PresenceEvents = NewCounterVec("sse_presence_events_total",
+		"发出的在线状态事件数", "type")
+)
+
+// 发布幂等
+var (
+	PublishDuplicates = NewCounterVec("sse_publish_duplicates_total",
+		"幂等键重复而未投递的发布请求数")
+)
+
+// 定时消息的投递结果
+const (
+	ScheduledDelivered = "delivered" // 已投递到 Hub
+	ScheduledRetried   = "retried"   // 租户或收件箱暂时不可用等原因投递失败，退避后重试
+	ScheduledFailed    = "failed"    // 请求无法投递或重试次数用完，已丢弃
+	ScheduledExpired   = "expired"   // 到期前消息已过期
+)
+
+// 定时发布
+var (
+	ScheduledPending = NewGaugeVec("sse_scheduled_pending",
+		"等待投递的定时消息数")
+	ScheduledFired = NewCounterVec("sse_scheduled_fired_total",
+		"到期处理的定时消息数", "result")
+)
+
+// 过期消息被丢弃的环节
+const (
+	ExpiredQueue      = "queue"      // 在客户端通道中等待写出时过期
+	ExpiredRedelivery = "redelivery" // 确认模式下等待确认或重发时过期
+	ExpiredHistory    = "history"    // 读取持久化历史时跳过
+	ExpiredInbox      = "inbox"      // 离线收件箱中过期
+	ExpiredSchedule   = "schedule"   // 定时消息到期前已过期
+	ExpiredWebhook    = "webhook"    // 离线回调等待发出或重试时过期
+)
+
+// 消息过期
+var (
+	MessagesExpired = NewCounterVec("sse_messages_expired_total",
+		"因过期未下发的消息数", "stage")
+)
@@ -0,0 +1,117 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchOptions 批量聚合选项
+type BatchOptions struct {
+	// 攒够多少条立即发送，默认 100
+	MaxItems int
+	// 第一条消息进入后最多等待多久发送，默认 50ms
+	Linger time.Duration
+	// 后台（Linger 到期）发送失败时的回调，未接受的消息不会再次发送
+	OnError func(items []Item, err error)
+}
+
+// Batcher 把多次 Add 聚合为 PublishBatch，可并发使用
+type Batcher struct {
+	p    *Publisher
+	opts BatchOptions
+
+	mu      sync.Mutex
+	pending []Item
+	timer   *time.Timer
+	closed  bool
+	// 未结束的 Linger 发送，Close 时等待
+	lingering sync.WaitGroup
+}
+
+// NewBatcher 创建批量聚合器，用完需调用 Close 发送剩余消息
+func (p *Publisher) NewBatcher(opts BatchOptions) *Batcher {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 100
+	}
+	if opts.Linger <= 0 {
+		opts.Linger = 50 * time.Millisecond
+	}
+	return &Batcher{p: p, opts: opts}
+}
+
+// Add 加入一条消息；攒满 MaxItems 时在当前协程同步发送并返回发送结果
+func (b *Batcher) Add(ctx context.Context, target Target, ev Event) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return &Error{Kind: ErrInvalid, Message: "batcher 已关闭"}
+	}
+	b.pending = append(b.pending, Item{Target: target, Event: ev})
+	if len(b.pending) < b.opts.MaxItems {
+		if b.timer == nil {
+			b.lingering.Add(1)
+			b.timer = time.AfterFunc(b.opts.Linger, b.lingerFlush)
+		}
+		b.mu.Unlock()
+		return nil
+	}
+	items := b.takeLocked()
+	b.mu.Unlock()
+
+	_, err := b.p.PublishBatch(ctx, items)
+	return err
+}
+
+// Flush 立即发送当前积攒的消息
+func (b *Batcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	items := b.takeLocked()
+	b.mu.Unlock()
+
+	if len(items) == 0 {
+		return nil
+	}
+	_, err := b.p.PublishBatch(ctx, items)
+	return err
+}
+
+// Close 发送剩余消息并等待后台发送结束，之后的 Add 返回错误
+func (b *Batcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	err := b.Flush(ctx)
+	b.lingering.Wait()
+	return err
+}
+
+// takeLocked 取走积攒的消息并停止计时，调用方需持有 mu
+func (b *Batcher) takeLocked() []Item {
+	if b.timer != nil {
+		// 成功取消时 lingerFlush 不会再执行
+		if b.timer.Stop() {
+			b.lingering.Done()
+		}
+		b.timer = nil
+	}
+	items := b.pending
+	b.pending = nil
+	return items
+}
+
+// lingerFlush Linger 到期后在后台发送
+func (b *Batcher) lingerFlush() {
+	defer b.lingering.Done()
+	b.mu.Lock()
+	items := b.takeLocked()
+	b.mu.Unlock()
+
+	if len(items) == 0 {
+		return
+	}
+	n, err := b.p.PublishBatch(context.Background(), items)
+	if err != nil && b.opts.OnError != nil {
+		b.opts.OnError(items[n:], err)
+	}
+}
@@ -0,0 +1,51 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误类别，可用 errors.Is(err, publisher.ErrRateLimited) 判断
+var (
+	ErrRateLimited  = errors.New("publisher: 发布被限流")
+	ErrUnauthorized = errors.New("publisher: 认证失败")
+	ErrInvalid      = errors.New("publisher: 请求无效")
+	ErrNotFound     = errors.New("publisher: 租户或接口不存在")
+	ErrUnavailable  = errors.New("publisher: 服务不可用")
+)
+
+// Error 发布失败的详细信息
+type Error struct {
+	// 错误类别，上面的 Err* 之一
+	Kind error
+	// 传输方式：http 或 grpc
+	Transport string
+	// HTTP 状态码或 gRPC 状态码名称，网络错误时为空
+	Code string
+	// 服务端返回的错误信息或底层错误
+	Message string
+	// 是否值得重试：限流与服务不可用为 true
+	Temporary bool
+	// 底层错误，如网络错误
+	Cause error
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%v (%s): %s", e.Kind, e.Transport, e.Message)
+	}
+	return fmt.Sprintf("%v (%s %s): %s", e.Kind, e.Transport, e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Cause}
+}
+
+// isTemporary 判断错误是否可重试
+func isTemporary(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Temporary
+}
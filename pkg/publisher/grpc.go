@@ -0,0 +1,147 @@
+package publisher
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "sse/pkg/ssepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPC 创建基于 gRPC 发布接口的客户端；conn 的生命周期由调用方管理，HTTPClient 选项不生效
+func NewGRPC(conn grpc.ClientConnInterface, opts Options) *Publisher {
+	opts.setDefaults()
+	return &Publisher{
+		t:    &grpcTransport{client: pb.NewMessageServiceClient(conn), opts: opts},
+		opts: opts,
+	}
+}
+
+type grpcTransport struct {
+	client pb.MessageServiceClient
+	opts   Options
+}
+
+// outgoing 附加租户、token 与幂等键 metadata
+func (t *grpcTransport) outgoing(ctx context.Context, key string) context.Context {
+	kv := []string{"idempotency-key", key}
+	if t.opts.Tenant != "" {
+		kv = append(kv, strings.ToLower(t.opts.TenantHeader), t.opts.Tenant)
+	}
+	if t.opts.Token != "" {
+		kv = append(kv, "authorization", "Bearer "+t.opts.Token)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func (t *grpcTransport) publish(ctx context.Context, key string, it Item) error {
+	ctx = t.outgoing(ctx, key)
+	ev := it.Event
+	msg := ev.Message
+	deliverAt, delay := unixMs(ev.DeliverAt), ev.Delay.Milliseconds()
+	expiresAt, ttl := unixMs(ev.ExpiresAt), ev.TTL.Milliseconds()
+
+	var err error
+	switch it.Target.Kind {
+	case KindTopic:
+		_, err = t.client.PublishByTopic(ctx, &pb.PublishByTopicRequest{
+			Topic: it.Target.Topic, Message: msg,
+			DeliverAtUnixMs: deliverAt, DelayMs: delay, ExpiresAtUnixMs: expiresAt, TtlMs: ttl, Priority: ev.Priority,
+		})
+	case KindUser:
+		_, err = t.client.PublishByUserId(ctx, &pb.PublishByUserIdRequest{
+			UserId: it.Target.UserId, Message: msg, Persistent: ev.Persistent,
+			DeliverAtUnixMs: deliverAt, DelayMs: delay, ExpiresAtUnixMs: expiresAt, TtlMs: ttl, Priority: ev.Priority,
+		})
+	case KindClientType:
+		_, err = t.client.PublishByClientType(ctx, &pb.PublishByClientTypeRequest{
+			ClientType: it.Target.ClientType, Message: msg,
+			DeliverAtUnixMs: deliverAt, DelayMs: delay, ExpiresAtUnixMs: expiresAt, TtlMs: ttl, Priority: ev.Priority,
+		})
+	case KindClient:
+		_, err = t.client.PublishToClient(ctx, &pb.PublishToClientRequest{
+			ClientType: it.Target.ClientType, UserId: it.Target.UserId, Message: msg, Persistent: ev.Persistent,
+			DeliverAtUnixMs: deliverAt, DelayMs: delay, ExpiresAtUnixMs: expiresAt, TtlMs: ttl, Priority: ev.Priority,
+		})
+	default:
+		return &Error{Kind: ErrInvalid, Transport: "grpc", Message: "未知的发布目标 " + strconv.Quote(it.Target.Kind)}
+	}
+	return grpcError(err)
+}
+
+func (t *grpcTransport) batch(ctx context.Context, key string, items []Item) (int, error) {
+	req := &pb.PublishBatchRequest{Items: make([]*pb.PublishRequest, 0, len(items))}
+	for _, it := range items {
+		ev := it.Event
+		req.Items = append(req.Items, &pb.PublishRequest{
+			Kind:            it.Target.Kind,
+			Topic:           it.Target.Topic,
+			UserId:          it.Target.UserId,
+			ClientType:      it.Target.ClientType,
+			Message:         ev.Message,
+			Persistent:      ev.Persistent,
+			DeliverAtUnixMs: unixMs(ev.DeliverAt),
+			DelayMs:         ev.Delay.Milliseconds(),
+			ExpiresAtUnixMs: unixMs(ev.ExpiresAt),
+			TtlMs:           ev.TTL.Milliseconds(),
+			Priority:        ev.Priority,
+		})
+	}
+
+	resp, err := t.client.PublishBatch(t.outgoing(ctx, key), req)
+	if err != nil {
+		return 0, grpcError(err)
+	}
+	accepted := int(resp.Accepted)
+	if resp.Error != "" {
+		// 中途失败，与 HTTP 按状态码返回错误一致
+		return accepted, grpcError(status.Error(codes.Code(resp.ErrorCode), resp.Error))
+	}
+	if accepted < len(items) {
+		// 与 HTTP 的 429 保持一致
+		return accepted, &Error{
+			Kind: ErrRateLimited, Transport: "grpc", Code: codes.ResourceExhausted.String(),
+			Message: "批量发布被部分限流", Temporary: true,
+		}
+	}
+	return accepted, nil
+}
+
+// unixMs 零值为 0，表示未设置；gRPC 接口的时间与时长精确到毫秒
+func unixMs(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// grpcError 把 gRPC 状态码映射为错误类别
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	e := &Error{Transport: "grpc", Code: st.Code().String(), Message: st.Message(), Cause: err}
+	switch st.Code() {
+	case codes.ResourceExhausted:
+		e.Kind, e.Temporary = ErrRateLimited, true
+	case codes.Unauthenticated, codes.PermissionDenied:
+		e.Kind = ErrUnauthorized
+	case codes.NotFound:
+		e.Kind = ErrNotFound
+	case codes.Unavailable, codes.Aborted:
+		// Aborted：同一幂等键的首次发布仍在处理中
+		e.Kind, e.Temporary = ErrUnavailable, true
+	case codes.Canceled, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		e.Kind = ErrUnavailable
+	default:
+		e.Kind = ErrInvalid
+	}
+	return e
+}
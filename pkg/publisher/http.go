@@ -0,0 +1,173 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewHTTP 创建基于 HTTP 发布接口的客户端，baseURL 如 http://sse:8080
+func NewHTTP(baseURL string, opts Options) *Publisher {
+	opts.setDefaults()
+	return &Publisher{
+		t:    &httpTransport{base: strings.TrimRight(baseURL, "/"), opts: opts},
+		opts: opts,
+	}
+}
+
+type httpTransport struct {
+	base string
+	opts Options
+}
+
+// 与服务端 publish.Request 的 JSON 字段一致
+type httpItem struct {
+	Kind       string `json:"kind"`
+	Topic      string `json:"topic,omitempty"`
+	UserId     int64  `json:"userId,omitempty"`
+	ClientType string `json:"clientType,omitempty"`
+	Message    string `json:"message"`
+	Persistent bool   `json:"persistent,omitempty"`
+	// 时长以 "90s" 形式表示
+	DeliverAt *time.Time `json:"deliverAt,omitempty"`
+	Delay     string     `json:"delay,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	Priority  string     `json:"priority,omitempty"`
+}
+
+func toHTTPItem(it Item) httpItem {
+	ev := it.Event
+	return httpItem{
+		Kind:       it.Target.Kind,
+		Topic:      it.Target.Topic,
+		UserId:     it.Target.UserId,
+		ClientType: it.Target.ClientType,
+		Message:    ev.Message,
+		Persistent: ev.Persistent,
+		DeliverAt:  jsonTime(ev.DeliverAt),
+		Delay:      jsonDuration(ev.Delay),
+		ExpiresAt:  jsonTime(ev.ExpiresAt),
+		TTL:        jsonDuration(ev.TTL),
+		Priority:   ev.Priority,
+	}
+}
+
+// jsonTime 零值省略
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// jsonDuration 零值省略
+func jsonDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func (t *httpTransport) publish(ctx context.Context, key string, it Item) error {
+	var path string
+	switch it.Target.Kind {
+	case KindTopic:
+		path = "/publishByTopic"
+	case KindUser:
+		path = "/publishByUserId"
+	case KindClientType:
+		path = "/publishByClientType"
+	case KindClient:
+		path = "/publishToClient"
+	default:
+		return &Error{Kind: ErrInvalid, Transport: "http", Message: "未知的发布目标 " + strconv.Quote(it.Target.Kind)}
+	}
+	_, err := t.post(ctx, path, key, toHTTPItem(it))
+	return err
+}
+
+func (t *httpTransport) batch(ctx context.Context, key string, items []Item) (int, error) {
+	body := struct {
+		Items []httpItem `json:"items"`
+	}{Items: make([]httpItem, 0, len(items))}
+	for _, it := range items {
+		body.Items = append(body.Items, toHTTPItem(it))
+	}
+
+	resp, err := t.post(ctx, "/publishBatch", key, body)
+	var result struct {
+		Accepted int `json:"accepted"`
+	}
+	// 部分接受时服务端返回 429，响应体中仍带有已接受的条数
+	if resp != nil {
+		_ = json.Unmarshal(resp, &result)
+	}
+	return result.Accepted, err
+}
+
+// post 发送 JSON 请求，返回响应体；非 2xx 时同时返回 *Error
+func (t *httpTransport) post(ctx context.Context, path, key string, v any) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalid, Transport: "http", Message: err.Error(), Cause: err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.base+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalid, Transport: "http", Message: err.Error(), Cause: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	if t.opts.Tenant != "" {
+		req.Header.Set(t.opts.TenantHeader, t.opts.Tenant)
+	}
+	if t.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.opts.Token)
+	}
+
+	resp, err := t.opts.HTTPClient.Do(req)
+	if err != nil {
+		// ctx 取消不重试，其余网络错误视为服务暂时不可用
+		return nil, &Error{
+			Kind: ErrUnavailable, Transport: "http", Message: err.Error(), Cause: err,
+			Temporary: !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded),
+		}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 == 2 {
+		return body, nil
+	}
+	return body, httpError(resp.StatusCode, body)
+}
+
+// httpError 把 HTTP 状态码映射为错误类别
+func httpError(code int, body []byte) *Error {
+	e := &Error{Transport: "http", Code: strconv.Itoa(code), Message: strings.TrimSpace(string(body))}
+	switch {
+	case code == http.StatusTooManyRequests:
+		e.Kind, e.Temporary = ErrRateLimited, true
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		e.Kind = ErrUnauthorized
+	case code == http.StatusNotFound:
+		e.Kind = ErrNotFound
+	case code == http.StatusConflict:
+		// 同一幂等键的首次发布仍在处理中，稍后重试可取得其结果
+		e.Kind, e.Temporary = ErrUnavailable, true
+	case code == http.StatusNotImplemented:
+		// 服务端未启用所需功能（如收件箱），重试无意义
+		e.Kind = ErrInvalid
+	case code >= 500:
+		e.Kind, e.Temporary = ErrUnavailable, true
+	default:
+		e.Kind = ErrInvalid
+	}
+	return e
+}
@@ -0,0 +1,178 @@
+// Package publisher 是 SSE 服务发布接口的 Go 客户端，HTTP 与 gRPC 共用同一套 API：
+//
+//	p := publisher.NewHTTP("http://sse:8080", publisher.Options{Tenant: "acme"})
+//	err := p.Publish(ctx, publisher.Topic("orders"), publisher.Event{Message: "..."})
+//
+// 可重试的错误（限流、服务不可用）会按退避自动重试，同一次发布的所有重试携带同一个幂等键。
+package publisher
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// 发布目标类型，与服务端批量接口的 kind 字段一致
+const (
+	KindTopic      = "topic"
+	KindUser       = "user"
+	KindClientType = "clientType"
+	KindClient     = "client"
+)
+
+// Target 发布目标，使用 Topic / User / ClientType / Client 构造
+type Target struct {
+	Kind       string
+	Topic      string
+	UserId     int64
+	ClientType string
+}
+
+// Topic 订阅了 name 的所有连接
+func Topic(name string) Target {
+	return Target{Kind: KindTopic, Topic: name}
+}
+
+// User 指定用户的所有连接
+func User(userId int64) Target {
+	return Target{Kind: KindUser, UserId: userId}
+}
+
+// ClientType 指定客户端类型的所有连接
+func ClientType(clientType string) Target {
+	return Target{Kind: KindClientType, ClientType: clientType}
+}
+
+// Client 指定用户在某一客户端类型上的连接
+func Client(clientType string, userId int64) Target {
+	return Target{Kind: KindClient, ClientType: clientType, UserId: userId}
+}
+
+// Event 待发布的消息
+type Event struct {
+	Message string
+	// 仅 User / Client 目标：同时存入服务端的用户收件箱，用户确认前每次连接都会补发
+	Persistent bool
+	// 定时发布：在 DeliverAt 或 Delay 之后投递，只能设置一项；零值或时间已过时立即投递
+	DeliverAt time.Time
+	Delay     time.Duration
+	// 有效期：过期后尚未下发的消息不再下发，只能设置一项，零值表示不过期；TTL 从投递时开始计算
+	ExpiresAt time.Time
+	TTL       time.Duration
+	// 优先级：high / normal / low，空表示 normal
+	Priority string
+	// 幂等键，为空时自动生成；重试时保持不变
+	IdempotencyKey string
+}
+
+// Item 批量发布中的一条
+type Item struct {
+	Target Target
+	Event  Event
+}
+
+// Options 发布选项，零值可用
+type Options struct {
+	// 租户名，非空时写入 TenantHeader；路径模式的租户请直接写进 baseURL，如 http://sse:8080/t/acme
+	Tenant string
+	// 租户请求头（gRPC 为同名 metadata），默认 X-Tenant
+	TenantHeader string
+	// Bearer token，写入 Authorization
+	Token string
+
+	// HTTP 发布使用的 http.Client，默认超时 10s
+	HTTPClient *http.Client
+
+	// 可重试错误的最大重试次数，默认 3，小于 0 表示不重试
+	MaxRetries int
+	// 初始重试间隔，默认 100ms，每次翻倍
+	InitialBackoff time.Duration
+	// 最大重试间隔，默认 5s
+	MaxBackoff time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.TenantHeader == "" {
+		o.TenantHeader = "X-Tenant"
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Second
+	}
+}
+
+// transport 单次发布请求，不负责重试
+type transport interface {
+	publish(ctx context.Context, key string, item Item) error
+	// batch 返回服务端已接受的条数，部分接受时同时返回错误
+	batch(ctx context.Context, key string, items []Item) (int, error)
+}
+
+// Publisher 发布客户端，可并发使用
+type Publisher struct {
+	t    transport
+	opts Options
+}
+
+// Publish 发布一条消息，可重试的错误按退避重试
+func (p *Publisher) Publish(ctx context.Context, target Target, ev Event) error {
+	if ev.IdempotencyKey == "" {
+		ev.IdempotencyKey = newKey()
+	}
+	item := Item{Target: target, Event: ev}
+	return p.retry(ctx, func() error {
+		return p.t.publish(ctx, ev.IdempotencyKey, item)
+	})
+}
+
+// PublishBatch 按顺序批量发布，返回服务端已接受的条数。
+// 被限流时只重发未被接受的部分；每个剩余部分使用由批次键派生的幂等键。
+func (p *Publisher) PublishBatch(ctx context.Context, items []Item) (int, error) {
+	key := newKey()
+	accepted := 0
+	err := p.retry(ctx, func() error {
+		n, err := p.t.batch(ctx, fmt.Sprintf("%s/%d", key, accepted), items[accepted:])
+		accepted += n
+		return err
+	})
+	return accepted, err
+}
+
+// retry 执行 fn，遇到 Temporary 错误时退避后重试
+func (p *Publisher) retry(ctx context.Context, fn func() error) error {
+	backoff := p.opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isTemporary(err) || attempt >= p.opts.MaxRetries {
+			return err
+		}
+
+		// 抖动 ±20%，避免多个发布方同时重试
+		wait := time.Duration(float64(backoff) * (0.8 + 0.4*rand.Float64()))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, p.opts.MaxBackoff)
+	}
+}
+
+// newKey 生成随机幂等键
+func newKey() string {
+	var b [16]byte
+	_, _ = crand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
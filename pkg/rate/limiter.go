@@ -0,0 +1,56 @@
+package rate
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter 令牌桶限流器，按固定速率补充令牌，允许 1 秒的突发量
+type Limiter struct {
+	mu     sync.Mutex
+	qps    float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter 创建限流器，qps <= 0 表示不限流
+func NewLimiter(qps int) *Limiter {
+	return &Limiter{
+		qps:    float64(qps),
+		tokens: float64(qps),
+		last:   time.Now(),
+	}
+}
+
+// SetQps 调整速率，qps <= 0 表示不限流
+func (l *Limiter) SetQps(qps int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.qps = float64(qps)
+	if l.tokens > l.qps {
+		l.tokens = l.qps
+	}
+}
+
+// Allow 尝试取出一个令牌，成功返回 true
+func (l *Limiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.qps <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.qps
+	if l.tokens > l.qps {
+		l.tokens = l.qps
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,44 @@
+package topic
+
+import "sync/atomic"
+
+// 一组包含/排除规则
+type rules struct {
+	include []string
+	exclude []string
+}
+
+// Filter 按 include/exclude 规则筛选主题，规则语法同订阅（见 Match），可在运行时整体替换
+type Filter struct {
+	rules atomic.Pointer[rules]
+}
+
+// NewFilter 创建主题过滤器，include 为空表示全部不匹配
+func NewFilter(include, exclude []string) *Filter {
+	f := &Filter{}
+	f.Set(include, exclude)
+	return f
+}
+
+// Set 替换过滤规则
+func (f *Filter) Set(include, exclude []string) {
+	f.rules.Store(&rules{
+		include: append([]string(nil), include...),
+		exclude: append([]string(nil), exclude...),
+	})
+}
+
+// Allow 主题命中 include 且未命中 exclude 时返回 true
+func (f *Filter) Allow(topic string) bool {
+	r := f.rules.Load()
+	return matchAny(r.include, topic) && !matchAny(r.exclude, topic)
+}
+
+func matchAny(patterns []string, topic string) bool {
+	for _, p := range patterns {
+		if Match(p, topic) {
+			return true
+		}
+	}
+	return false
+}
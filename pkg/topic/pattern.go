@@ -0,0 +1,84 @@
+package topic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 主题按 "." 分段，订阅与过滤规则中可使用通配段：
+//   - "*" 匹配恰好一段，如 orders.*.shipped 匹配 orders.42.shipped
+//   - ">" 或 "#" 只能作为最后一段，匹配剩余的一段或多段，如 orders.> 匹配 orders.42.shipped
+//
+// 以 SystemPrefix 开头的系统主题（如 $presence.chat）不被首段的通配匹配，需要显式订阅，如 $presence.>
+const (
+	Separator = "."
+	// AnySegment 匹配一段
+	AnySegment = "*"
+	// AnyRest 匹配剩余的一段或多段，与 AnyRestAlt 等价
+	AnyRest    = ">"
+	AnyRestAlt = "#"
+	// SystemPrefix 系统主题的前缀
+	SystemPrefix = "$"
+)
+
+// ErrInvalidPattern 主题或订阅规则不合法
+var ErrInvalidPattern = errors.New("主题格式不合法")
+
+func split(s string) []string {
+	return strings.Split(s, Separator)
+}
+
+func isRest(seg string) bool {
+	return seg == AnyRest || seg == AnyRestAlt
+}
+
+// ValidatePattern 检查订阅规则：不能为空或含空段，多段通配只能出现在最后一段
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("%w: 不能为空", ErrInvalidPattern)
+	}
+	segs := split(pattern)
+	for i, seg := range segs {
+		if seg == "" {
+			return fmt.Errorf("%w: %q 含空段", ErrInvalidPattern, pattern)
+		}
+		if isRest(seg) && i != len(segs)-1 {
+			return fmt.Errorf("%w: %q 中的 %s 只能作为最后一段", ErrInvalidPattern, pattern, seg)
+		}
+	}
+	return nil
+}
+
+// ValidateTopic 检查发布的主题：不能为空或含空段（如 a..b、.a、a.），也不能包含通配段
+func ValidateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("%w: 不能为空", ErrInvalidPattern)
+	}
+	for _, seg := range split(topic) {
+		if seg == "" {
+			return fmt.Errorf("%w: %q 含空段", ErrInvalidPattern, topic)
+		}
+		if seg == AnySegment || isRest(seg) {
+			return fmt.Errorf("%w: 发布的主题 %q 不能包含通配段", ErrInvalidPattern, topic)
+		}
+	}
+	return nil
+}
+
+// Match 主题是否命中订阅规则
+func Match(pattern, topic string) bool {
+	ps, ts := split(pattern), split(topic)
+	if strings.HasPrefix(ts[0], SystemPrefix) && ps[0] != ts[0] {
+		return false
+	}
+	for i, seg := range ps {
+		if isRest(seg) {
+			return len(ts) > i
+		}
+		if i >= len(ts) || (seg != AnySegment && seg != ts[i]) {
+			return false
+		}
+	}
+	return len(ps) == len(ts)
+}
@@ -0,0 +1,81 @@
+package topic
+
+import (
+	"errors"
+	"testing"
+)
+
+// matchTests 同时用于 Match 与 Trie.Match
+var matchTests = []struct {
+	pattern, topic string
+	want           bool
+}{
+	{"orders", "orders", true},
+	{"orders", "orders.1", false},
+	{"orders.1", "orders", false},
+	// * 恰好匹配一段
+	{"orders.*", "orders.1", true},
+	{"orders.*", "orders", false},
+	{"orders.*", "orders.1.shipped", false},
+	{"orders.*.shipped", "orders.42.shipped", true},
+	{"orders.*.shipped", "orders.42.paid", false},
+	{"*", "orders", true},
+	{"*.*", "orders.1", true},
+	// > 与 # 匹配剩余的一段或多段
+	{"orders.>", "orders.1", true},
+	{"orders.>", "orders.1.shipped", true},
+	{"orders.>", "orders", false},
+	{"orders.#", "orders.1.shipped", true},
+	{"orders.#", "orders", false},
+	{">", "orders", true},
+	{"#", "orders.1", true},
+	{"*.>", "orders.1", true},
+	{"*.>", "orders", false},
+	// 系统主题不被首段的通配匹配
+	{">", "$presence.chat", false},
+	{"#", "$presence.chat", false},
+	{"*.chat", "$presence.chat", false},
+	{"$presence.>", "$presence.chat", true},
+	{"$presence.>", "$presence.chat.1", true},
+	{"$presence.*", "$presence.chat", true},
+	{"$presence.chat", "$presence.chat", true},
+	{"$other.>", "$presence.chat", false},
+}
+
+func TestMatch(t *testing.T) {
+	for _, tt := range matchTests {
+		if got := Match(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v，期望 %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	valid := []string{"a", "a.b", "a.*", "*.b", "a.>", "a.#", ">", "*.*.>", "$presence.>"}
+	for _, p := range valid {
+		if err := ValidatePattern(p); err != nil {
+			t.Errorf("ValidatePattern(%q): %v", p, err)
+		}
+	}
+	invalid := []string{"", "a.>.b", ">.a", "a.#.b", "a..b", ".a", "a.", "."}
+	for _, p := range invalid {
+		if err := ValidatePattern(p); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("ValidatePattern(%q) = %v，期望 ErrInvalidPattern", p, err)
+		}
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	valid := []string{"a", "a.b", "orders.42.shipped", "$presence.chat"}
+	for _, topic := range valid {
+		if err := ValidateTopic(topic); err != nil {
+			t.Errorf("ValidateTopic(%q): %v", topic, err)
+		}
+	}
+	invalid := []string{"", "a.*", "*", "a.>", "a.#", "a..b", ".a", "a.", ".", ".."}
+	for _, topic := range invalid {
+		if err := ValidateTopic(topic); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("ValidateTopic(%q) = %v，期望 ErrInvalidPattern", topic, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package topic
+
+import "strings"
+
+// Trie 按段索引订阅规则，查找主题的订阅者时只走命中的分支，不必遍历全部订阅。
+// 非并发安全，由调用方加锁
+type Trie[V comparable] struct {
+	root *node[V]
+}
+
+type node[V comparable] struct {
+	children map[string]*node[V]
+	// 规则在此结束的订阅者
+	values map[V]struct{}
+}
+
+func NewTrie[V comparable]() *Trie[V] {
+	return &Trie[V]{root: &node[V]{}}
+}
+
+// Add 登记 v 订阅了 pattern
+func (t *Trie[V]) Add(pattern string, v V) {
+	n := t.root
+	for _, seg := range split(pattern) {
+		child := n.children[seg]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[string]*node[V])
+			}
+			child = &node[V]{}
+			n.children[seg] = child
+		}
+		n = child
+	}
+	if n.values == nil {
+		n.values = make(map[V]struct{})
+	}
+	n.values[v] = struct{}{}
+}
+
+// Remove 取消 v 对 pattern 的订阅，并清理不再使用的分支
+func (t *Trie[V]) Remove(pattern string, v V) {
+	t.root.remove(split(pattern), v)
+}
+
+// remove 返回当前节点是否已空，可由父节点删除
+func (n *node[V]) remove(segs []string, v V) bool {
+	if len(segs) == 0 {
+		delete(n.values, v)
+	} else if child := n.children[segs[0]]; child != nil && child.remove(segs[1:], v) {
+		delete(n.children, segs[0])
+	}
+	return len(n.values) == 0 && len(n.children) == 0
+}
+
+// Exact 返回以 pattern 本身订阅的订阅者，不展开通配
+func (t *Trie[V]) Exact(pattern string) []V {
+	n := t.root
+	for _, seg := range split(pattern) {
+		if n = n.children[seg]; n == nil {
+			return nil
+		}
+	}
+	out := make([]V, 0, len(n.values))
+	for v := range n.values {
+		out = append(out, v)
+	}
+	return out
+}
+
+// Match 返回订阅规则命中 topic 的订阅者，同一订阅者只出现一次
+func (t *Trie[V]) Match(topic string) []V {
+	seen := make(map[V]struct{})
+	var out []V
+	fn := func(v V) {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	segs := split(topic)
+	if strings.HasPrefix(segs[0], SystemPrefix) {
+		// 系统主题的首段不走通配分支
+		if child := t.root.children[segs[0]]; child != nil {
+			child.match(segs[1:], fn)
+		}
+		return out
+	}
+	t.root.match(segs, fn)
+	return out
+}
+
+func (n *node[V]) match(segs []string, fn func(V)) {
+	if len(segs) == 0 {
+		for v := range n.values {
+			fn(v)
+		}
+		return
+	}
+	// 多段通配至少匹配一段
+	for _, rest := range [...]string{AnyRest, AnyRestAlt} {
+		if child := n.children[rest]; child != nil {
+			for v := range child.values {
+				fn(v)
+			}
+		}
+	}
+	if child := n.children[segs[0]]; child != nil {
+		child.match(segs[1:], fn)
+	}
+	if child := n.children[AnySegment]; child != nil {
+		child.match(segs[1:], fn)
+	}
+}
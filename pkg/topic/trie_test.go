@@ -0,0 +1,114 @@
+package topic
+
+import (
+	"slices"
+	"testing"
+)
+
+func sorted(ids []int) []int {
+	slices.Sort(ids)
+	return ids
+}
+
+// Trie.Match 与逐条调用 Match 的结果一致
+func TestTrieMatch(t *testing.T) {
+	for i, tt := range matchTests {
+		tr := NewTrie[int]()
+		tr.Add(tt.pattern, i)
+		got := len(tr.Match(tt.topic)) == 1
+		if got != tt.want {
+			t.Errorf("Trie(%q).Match(%q) = %v，期望 %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestTrieMatchMany(t *testing.T) {
+	tr := NewTrie[int]()
+	tr.Add("orders.1", 1)
+	tr.Add("orders.*", 2)
+	tr.Add("orders.>", 3)
+	tr.Add("orders.#", 4)
+	tr.Add(">", 5)
+	tr.Add("orders.1.shipped", 6)
+	tr.Add("$presence.orders.1", 7)
+	// 同一订阅者的多条规则命中时只出现一次
+	tr.Add("orders.1", 8)
+	tr.Add("orders.*", 8)
+
+	tests := []struct {
+		topic string
+		want  []int
+	}{
+		{"orders.1", []int{1, 2, 3, 4, 5, 8}},
+		{"orders.2", []int{2, 3, 4, 5, 8}},
+		{"orders.1.shipped", []int{3, 4, 5, 6}},
+		{"orders", []int{5}},
+		{"other.1", []int{5}},
+		{"$presence.orders.1", []int{7}},
+		{"$presence.orders.2", nil},
+	}
+	for _, tt := range tests {
+		if got := sorted(tr.Match(tt.topic)); !slices.Equal(got, tt.want) {
+			t.Errorf("Match(%q) = %v，期望 %v", tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestTrieExact(t *testing.T) {
+	tr := NewTrie[int]()
+	tr.Add("chat.1", 1)
+	tr.Add("chat.*", 2)
+	tr.Add("chat.>", 3)
+	tr.Add("chat.1", 4)
+
+	if got := sorted(tr.Exact("chat.1")); !slices.Equal(got, []int{1, 4}) {
+		t.Fatalf("Exact(chat.1) = %v", got)
+	}
+	if got := tr.Exact("chat.*"); !slices.Equal(got, []int{2}) {
+		t.Fatalf("Exact(chat.*) = %v", got)
+	}
+	if got := tr.Exact("chat"); len(got) != 0 {
+		t.Fatalf("Exact(chat) = %v", got)
+	}
+	if got := tr.Exact("chat.2"); len(got) != 0 {
+		t.Fatalf("Exact(chat.2) = %v", got)
+	}
+}
+
+func TestTrieRemove(t *testing.T) {
+	tr := NewTrie[int]()
+	tr.Add("a.b.c", 1)
+	tr.Add("a.b.c", 2)
+	tr.Add("a.b", 3)
+	tr.Add("a.*", 4)
+
+	tr.Remove("a.b.c", 1)
+	if got := tr.Match("a.b.c"); !slices.Equal(got, []int{2}) {
+		t.Fatalf("Match(a.b.c) = %v", got)
+	}
+	// 还有订阅者的节点保留
+	if tr.root.children["a"].children["b"].children["c"] == nil {
+		t.Fatal("a.b.c 仍有订阅者，节点不应删除")
+	}
+	tr.Remove("a.b.c", 2)
+	// c 已空被删除，b 上还有订阅者 3 保留
+	b := tr.root.children["a"].children["b"]
+	if b == nil || b.children["c"] != nil {
+		t.Fatalf("清理后 b 为 %+v", b)
+	}
+	// 不存在的规则或订阅者不影响其他订阅
+	tr.Remove("x.y", 3)
+	tr.Remove("a.b", 99)
+	if got := sorted(tr.Match("a.b")); !slices.Equal(got, []int{3, 4}) {
+		t.Fatalf("Match(a.b) = %v", got)
+	}
+
+	tr.Remove("a.b", 3)
+	tr.Remove("a.*", 4)
+	if len(tr.root.children) != 0 {
+		t.Fatalf("全部取消后仍有分支 %v", tr.root.children)
+	}
+	if got := tr.Match("a.b"); len(got) != 0 {
+		t.Fatalf("Match(a.b) = %v", got)
+	}
+}